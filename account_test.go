@@ -0,0 +1,99 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestAccountHandler() (*handlers.AccountHandler, *data.Repo) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	return handlers.NewAccountHandler(logger, configs, repository, service.NewAuthService(logger, configs)), repository
+}
+
+func TestExportAccount(t *testing.T) {
+	ach, repository := newTestAccountHandler()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	repository.Create(&data.User{Email: "export@example.com", Password: string(hash), DisplayName: "Exporter"})
+	article, _ := repository.CreateArticle(&data.Article{Title: "exported title", Content: "exported content", Author: "export@example.com"})
+	repository.CreateAPIKey(&data.APIKey{UserID: "export@example.com", Name: "ci", KeyHash: "export-hash", Scopes: []string{data.ScopeArticlesRead}})
+
+	rec := httptest.NewRecorder()
+	ach.ExportAccount(rec, asUser(httptest.NewRequest(http.MethodGet, "/Account/Export", nil), "export@example.com"))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("export failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range archive.File {
+		r, _ := f.Open()
+		content, _ := ioutil.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(content)
+	}
+
+	export := handlers.AccountExport{}
+	if err := json.Unmarshal([]byte(files["account.json"]), &export); err != nil {
+		t.Fatal(err)
+	}
+	if export.Account.Email != "export@example.com" || len(export.Articles) != 1 || len(export.Sessions.APIKeys) != 1 {
+		t.Errorf("unexpected export %+v", export)
+	}
+	if strings.Contains(files["account.json"], string(hash)) || strings.Contains(files["account.json"], "export-hash") {
+		t.Errorf("secrets exposed in the export")
+	}
+	if !strings.Contains(files["account.md"], "Exporter") {
+		t.Errorf("profile missing from account.md")
+	}
+	if !strings.Contains(files["articles/"+article.ID+".md"], "# exported title") {
+		t.Errorf("article missing from the archive")
+	}
+}
+
+func TestDeleteAccountKeepsArticlesUnderTombstoneAuthor(t *testing.T) {
+	ach, repository := newTestAccountHandler()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	repository.Create(&data.User{Email: "erase@example.com", Password: string(hash)})
+	article, _ := repository.CreateArticle(&data.Article{Title: "orphaned", Author: "erase@example.com"})
+
+	deleteAccount := func(password string) int {
+		body, _ := json.Marshal(handlers.AccountDeletionRequest{Password: password})
+		rec := httptest.NewRecorder()
+		ach.DeleteAccount(rec, asUser(httptest.NewRequest(http.MethodPost, "/Account/Delete", bytes.NewReader(body)), "erase@example.com"))
+		return rec.Code
+	}
+	if status := deleteAccount("wrong"); status != http.StatusBadRequest {
+		t.Errorf("account deleted with a wrong password: %d", status)
+	}
+	if status := deleteAccount("secret"); status != http.StatusOK {
+		t.Fatalf("account not deleted: %d", status)
+	}
+
+	if _, err := repository.GetUserByEmail("erase@example.com"); err == nil {
+		t.Errorf("user record not erased")
+	}
+	kept, err := repository.GetArticleByID(article.ID)
+	if err != nil || kept.Author != data.TombstoneAuthor || kept.AuthorProfile == nil || kept.AuthorProfile.DisplayName != "Deleted user" {
+		t.Errorf("article not reassigned to the tombstone author: %+v", kept)
+	}
+	if err := repository.Create(&data.User{Email: data.TombstoneAuthor, Password: "hash"}); err == nil {
+		t.Errorf("tombstone author registered as a user")
+	}
+}
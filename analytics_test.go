@@ -0,0 +1,79 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestViewsAreDeduplicatedAndAggregatedDaily(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	analytics := service.NewAnalyticsService(logger, configs, repository)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go analytics.Run(ctx)
+
+	article, _ := repository.CreateArticle(&data.Article{Title: "viewed", Tags: []string{"go"}, Author: "analyst@example.com"})
+	other, _ := repository.CreateArticle(&data.Article{Title: "less viewed", Tags: []string{"go", "web"}, Author: "analyst@example.com"})
+
+	var wg sync.WaitGroup
+	for _, viewer := range []string{"r1@example.com", "r2@example.com", "r3@example.com"} {
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(viewer string) {
+				defer wg.Done()
+				analytics.RecordView(article.ID, viewer)
+			}(viewer)
+		}
+	}
+	wg.Wait()
+	analytics.RecordView(other.ID, "r1@example.com")
+	analytics.Flush()
+
+	today := time.Now().UTC()
+	stats, err := repository.GetArticleViewStats(article.ID, today.AddDate(0, 0, -6), today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalViews != 3 || len(stats.Daily) != 7 || stats.Daily[6].Views != 3 {
+		t.Errorf("unexpected article stats %+v", stats)
+	}
+
+	authorStats := repository.GetAuthorViewStats("analyst@example.com", today, today)
+	if authorStats.TotalViews != 4 || len(authorStats.TopArticles) != 2 || authorStats.TopArticles[0].ArticleID != article.ID {
+		t.Errorf("unexpected author stats %+v", authorStats)
+	}
+	if len(authorStats.TopTags) != 2 || authorStats.TopTags[0].Tag != "go" || authorStats.TopTags[0].Views != 4 {
+		t.Errorf("unexpected top tags %+v", authorStats.TopTags)
+	}
+
+	configs.ViewDedupWindow = 0
+	if !analytics.RecordView(article.ID, "r1@example.com") {
+		t.Errorf("view outside the dedup window not counted")
+	}
+}
+
+func TestInvalidViewFlushIntervalFallsBackToDefault(t *testing.T) {
+	t.Setenv("VIEW_FLUSH_INTERVAL", "0")
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	if configs.ViewFlushInterval != 10 {
+		t.Fatalf("unexpected flush interval %d", configs.ViewFlushInterval)
+	}
+
+	// the worker starts its ticker without panicking
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		service.NewAnalyticsService(logger, configs, data.NewRepo(logger)).Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+}
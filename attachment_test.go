@@ -0,0 +1,201 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// s3StandIn is an in memory object storage answering the path style requests of the s3 blob store
+type s3StandIn struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *s3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.objects[r.URL.Path] = body
+	case http.MethodGet:
+		object, exists := s.objects[r.URL.Path]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(object)
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	store := service.NewLocalBlobStore(utils.NewLogger(), t.TempDir())
+	if err := store.Put("attachments/a/b", strings.NewReader("content"), 7, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	content, err := store.Get("attachments/a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(content); string(body) != "content" {
+		t.Errorf("unexpected content %q", body)
+	}
+	content.Close()
+
+	if err := store.Put("../escaped", strings.NewReader("content"), 7, "text/plain"); err == nil {
+		t.Errorf("key escaped the directory of the store")
+	}
+	if err := store.Delete("attachments/a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("attachments/a/b"); err != service.ErrBlobNotFound {
+		t.Errorf("deleted blob still found: %v", err)
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	configs.MaxUploadSize = 1 << 10
+	repository := data.NewRepo(logger)
+
+	standIn := &s3StandIn{objects: make(map[string][]byte)}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+	store := service.NewS3BlobStore(logger, server.URL, "us-east-1", "articles", "key", "secret")
+	attachments := service.NewAttachmentService(logger, configs, repository, store, service.NewImageService(logger, configs, repository, store))
+	ath := handlers.NewAttachmentHandler(logger, configs, repository, attachments)
+
+	for _, email := range []string{"owner@attach.example.com", "viewer@attach.example.com"} {
+		repository.Create(&data.User{Email: email, Password: "hash"})
+	}
+	article, _ := repository.CreateArticle(&data.Article{Title: "article with files", Author: "owner@attach.example.com"})
+	repository.SetContributor(article.ID, "viewer@attach.example.com", data.PermissionViewer)
+
+	var picture bytes.Buffer
+	png.Encode(&picture, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+
+	upload := func(userID string, filename string, content []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		// the declared type is ignored, the type is sniffed from the content
+		part, _ := form.CreateFormFile("file", filename)
+		part.Write(content)
+		form.Close()
+		req := httptest.NewRequest(http.MethodPost, "/Attachment/Upload/"+article.ID, &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req = mux.SetURLVars(asUser(req, userID), map[string]string{"articleID": article.ID})
+		rec := httptest.NewRecorder()
+		ath.UploadAttachment(rec, req)
+		return rec
+	}
+
+	if rec := upload("owner@attach.example.com", "../../picture.png", picture.Bytes()); rec.Code != http.StatusCreated {
+		t.Fatalf("upload failed: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := upload("owner@attach.example.com", "script.png", []byte("<html><script>alert(1)</script></html>")); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("html uploaded as an image: %d", rec.Code)
+	}
+	if rec := upload("owner@attach.example.com", "large.png", append(picture.Bytes(), make([]byte, 1<<10)...)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("upload over the size limit: %d", rec.Code)
+	}
+	if rec := upload("viewer@attach.example.com", "picture.png", picture.Bytes()); rec.Code != http.StatusForbidden {
+		t.Errorf("viewer uploaded a file: %d", rec.Code)
+	}
+
+	stored, _ := repository.GetAttachments(article.ID)
+	if len(stored) != 1 || stored[0].ContentType != "image/png" || stored[0].Filename != "picture.png" {
+		t.Fatalf("unexpected attachments %+v", stored)
+	}
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/Attachment/"+stored[0].ID, nil), map[string]string{"attachmentID": stored[0].ID})
+	rec := httptest.NewRecorder()
+	ath.GetAttachment(rec, req)
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), picture.Bytes()) ||
+		rec.Header().Get("Content-Type") != "image/png" || rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("unexpected download %d %v", rec.Code, rec.Header())
+	}
+
+	// the blobs of a deleted article are deleted by the cleanup worker
+	repository.DeleteArticle(article.ID)
+	if _, err := repository.GetAttachmentByID(stored[0].ID); err == nil {
+		t.Errorf("attachment of a deleted article still found")
+	}
+	if len(standIn.objects) != 1 {
+		t.Fatalf("unexpected objects %v", standIn.objects)
+	}
+	attachments.CleanupOrphans()
+	if len(standIn.objects) != 0 {
+		t.Errorf("orphaned blob not deleted")
+	}
+}
+
+func TestInvalidOrphanCleanupIntervalFallsBackToDefault(t *testing.T) {
+	t.Setenv("ORPHAN_CLEANUP_INTERVAL", "-5")
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	if configs.OrphanCleanupInterval != 60 {
+		t.Fatalf("unexpected cleanup interval %d", configs.OrphanCleanupInterval)
+	}
+
+	// the cleanup worker starts its ticker without panicking
+	repository := data.NewRepo(logger)
+	store := service.NewLocalBlobStore(logger, t.TempDir())
+	attachments := service.NewAttachmentService(logger, configs, repository, store, service.NewImageService(logger, configs, repository, store))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		attachments.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+}
+
+func TestInvalidMaxUploadSizeFallsBackToDefault(t *testing.T) {
+	t.Setenv("MAX_UPLOAD_SIZE", "0")
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	if configs.MaxUploadSize != 10<<20 {
+		t.Fatalf("unexpected upload size %d", configs.MaxUploadSize)
+	}
+
+	repository := data.NewRepo(logger)
+	store := service.NewLocalBlobStore(logger, t.TempDir())
+	attachments := service.NewAttachmentService(logger, configs, repository, store, service.NewImageService(logger, configs, repository, store))
+	article, _ := repository.CreateArticle(&data.Article{Title: "article with a small file", Author: "owner@uploadsize.example.com"})
+	var picture bytes.Buffer
+	png.Encode(&picture, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	if _, err := attachments.Upload(article.ID, "owner@uploadsize.example.com", "small.png", &picture); err != nil {
+		t.Errorf("small upload rejected: %v", err)
+	}
+}
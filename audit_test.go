@@ -0,0 +1,200 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// exportAuditEvents returns the audit events of the actor, oldest first
+func exportAuditEvents(t *testing.T, auh *handlers.AuditHandler, actor string) []data.AuditEvent {
+	rec := httptest.NewRecorder()
+	auh.ExportAuditEvents(rec, httptest.NewRequest(http.MethodGet, "/Admin/Audit/Export?actor="+actor, nil))
+	events := []data.AuditEvent{}
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		event := data.AuditEvent{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid json line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestAuditLogRecordsLoginsAndArticleChanges(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	validation := data.NewValidation()
+	uh := handlers.NewAuthHandler(logger, configs, validation, repository, service.NewAuthService(logger, configs))
+	ah := handlers.NewArticleHandler(logger, configs, validation, repository, service.NewArticleService(logger, configs), service.NewAnalyticsService(logger, configs, repository))
+	auh := handlers.NewAuditHandler(logger, configs, repository)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	repository.Create(&data.User{Email: "audited@example.com", Password: string(hash)})
+	for _, password := range []string{"wrong", "secret"} {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req = req.WithContext(context.WithValue(req.Context(), handlers.UserKey{}, data.User{Email: "audited@example.com", Password: password}))
+		uh.Login(httptest.NewRecorder(), req)
+	}
+
+	req := asUser(httptest.NewRequest(http.MethodPost, "/Article/Create", nil), "audited@example.com")
+	req = req.WithContext(context.WithValue(req.Context(), handlers.ArticleKey{}, data.Article{Title: "audited article"}))
+	ah.CreateArticle(httptest.NewRecorder(), req)
+
+	rec := httptest.NewRecorder()
+	auh.ExportAuditEvents(rec, httptest.NewRequest(http.MethodGet, "/Admin/Audit/Export?actor=audited@example.com", nil))
+	if rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected export: %d %s", rec.Code, rec.Body.String())
+	}
+	events := exportAuditEvents(t, auh, "audited@example.com")
+
+	expected := []struct{ action, outcome string }{
+		{data.AuditLogin, data.AuditFailure},
+		{data.AuditLogin, data.AuditSuccess},
+		{data.AuditArticleCreate, data.AuditSuccess},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), events)
+	}
+	for i, e := range expected {
+		if events[i].Action != e.action || events[i].Outcome != e.outcome || events[i].IP != "192.0.2.1" {
+			t.Errorf("unexpected event %d: %+v", i, events[i])
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	auh.ListAuditEvents(rec, httptest.NewRequest(http.MethodGet, "/Admin/Audit?actor=audited@example.com&outcome=failure", nil))
+	list := struct {
+		Data handlers.AuditEventListResponse `json:"data"`
+	}{}
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if list.Data.Total != 1 || list.Data.Events[0].Reason != utils.ErrIncorrectPassword {
+		t.Errorf("unexpected filtered events %+v", list.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	auh.ListAuditEvents(rec, httptest.NewRequest(http.MethodGet, "/Admin/Audit?since=yesterday", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid since accepted: %d", rec.Code)
+	}
+}
+
+func TestAuditLogRecordsUserManagement(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	validation := data.NewValidation()
+	adh := handlers.NewAdminHandler(logger, configs, validation, repository)
+	ach := handlers.NewAccountHandler(logger, configs, repository, service.NewAuthService(logger, configs))
+	auh := handlers.NewAuditHandler(logger, configs, repository)
+
+	admin := "admin@auditusers.example.com"
+	managed := "managed@auditusers.example.com"
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	repository.Create(&data.User{Email: admin, Password: string(hash), Role: data.RoleAdmin})
+	repository.Create(&data.User{Email: managed, Password: string(hash)})
+	repository.Create(&data.User{Email: "leaving@auditusers.example.com", Password: string(hash)})
+
+	manage := func(handler http.HandlerFunc, userID string) {
+		req := asUser(httptest.NewRequest(http.MethodGet, "/Admin/Users/"+userID, nil), admin)
+		handler(httptest.NewRecorder(), mux.SetURLVars(req, map[string]string{"userID": userID}))
+	}
+	post := func(handler http.HandlerFunc, actor string, body interface{}) {
+		encoded, _ := json.Marshal(body)
+		handler(httptest.NewRecorder(), asUser(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encoded)), actor))
+	}
+	manage(adh.DisableUser, managed)
+	manage(adh.DisableUser, admin)
+	manage(adh.EnableUser, managed)
+	manage(adh.ForcePasswordReset, managed)
+	post(adh.ChangeRole, admin, handlers.RoleChangeRequest{UserID: managed, Role: data.RoleAdmin})
+	post(adh.DeleteUser, admin, handlers.UserDeletionRequest{UserID: managed, Articles: "delete"})
+	post(adh.DeleteUser, admin, handlers.UserDeletionRequest{UserID: managed, Articles: "delete"})
+
+	expected := []struct{ action, target, outcome string }{
+		{data.AuditUserDisable, managed, data.AuditSuccess},
+		{data.AuditUserDisable, admin, data.AuditFailure},
+		{data.AuditUserEnable, managed, data.AuditSuccess},
+		{data.AuditUserForceReset, managed, data.AuditSuccess},
+		{data.AuditUserRoleChange, managed, data.AuditSuccess},
+		{data.AuditUserDelete, managed, data.AuditSuccess},
+		{data.AuditUserDelete, managed, data.AuditFailure},
+	}
+	events := exportAuditEvents(t, auh, admin)
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), events)
+	}
+	for i, e := range expected {
+		if events[i].Action != e.action || events[i].Target != e.target || events[i].Outcome != e.outcome {
+			t.Errorf("unexpected event %d: %+v", i, events[i])
+		}
+	}
+
+	post(ach.DeleteAccount, "leaving@auditusers.example.com", handlers.AccountDeletionRequest{Password: "wrong"})
+	post(ach.DeleteAccount, "leaving@auditusers.example.com", handlers.AccountDeletionRequest{Password: "secret"})
+	events = exportAuditEvents(t, auh, "leaving@auditusers.example.com")
+	if len(events) != 2 || events[0].Action != data.AuditAccountDelete || events[0].Reason != utils.ErrIncorrectPassword ||
+		events[1].Action != data.AuditAccountDelete || events[1].Outcome != data.AuditSuccess {
+		t.Errorf("unexpected account deletion events %+v", events)
+	}
+}
+
+func TestAuditLogRecordsCategoryManagement(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	cah := handlers.NewCategoryHandler(logger, configs, data.NewValidation(), repository)
+	auh := handlers.NewAuditHandler(logger, configs, repository)
+
+	admin := "admin@auditcategories.example.com"
+	post := func(handler http.HandlerFunc, body interface{}) *httptest.ResponseRecorder {
+		encoded, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		handler(rec, asUser(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(encoded)), admin))
+		return rec
+	}
+	remove := func(categoryID string) {
+		req := asUser(httptest.NewRequest(http.MethodGet, "/Admin/Categories/Delete/"+categoryID, nil), admin)
+		cah.DeleteCategory(httptest.NewRecorder(), mux.SetURLVars(req, map[string]string{"categoryID": categoryID}))
+	}
+
+	response := struct{ Data data.Category }{}
+	json.NewDecoder(post(cah.CreateCategory, data.Category{Name: "Audited categories"}).Body).Decode(&response)
+	category := response.Data
+	post(cah.CreateCategory, data.Category{Name: "audited categories"})
+	post(cah.RenameCategory, handlers.CategoryRenameRequest{ID: category.ID, Name: "Audited sections"})
+	post(cah.MoveCategory, handlers.CategoryMoveRequest{ID: category.ID, ParentID: "missing"})
+	remove(category.ID)
+	remove(category.ID)
+
+	expected := []struct{ action, target, outcome string }{
+		{data.AuditCategoryCreate, category.ID, data.AuditSuccess},
+		{data.AuditCategoryCreate, "audited categories", data.AuditFailure},
+		{data.AuditCategoryRename, category.ID, data.AuditSuccess},
+		{data.AuditCategoryMove, category.ID, data.AuditFailure},
+		{data.AuditCategoryDelete, category.ID, data.AuditSuccess},
+		{data.AuditCategoryDelete, category.ID, data.AuditFailure},
+	}
+	events := exportAuditEvents(t, auh, admin)
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), events)
+	}
+	for i, e := range expected {
+		if events[i].Action != e.action || events[i].Target != e.target || events[i].Outcome != e.outcome {
+			t.Errorf("unexpected event %d: %+v", i, events[i])
+		}
+	}
+}
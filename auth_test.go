@@ -0,0 +1,199 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func newTestAuthService() (*service.AuthService, *utils.Configurations) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	return service.NewAuthService(logger, configs), configs
+}
+
+func signWithFile(t *testing.T, path string, claims jwt.Claims) string {
+	signBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signKey, err := jwt.ParseRSAPrivateKeyFromPEM(signBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(signKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func validStandardClaims(configs *utils.Configurations, subject string) jwt.StandardClaims {
+	now := time.Now()
+	return jwt.StandardClaims{
+		Audience:  configs.JwtAudience,
+		ExpiresAt: now.Add(time.Hour).Unix(),
+		Id:        "test-jti",
+		IssuedAt:  now.Unix(),
+		Issuer:    configs.JwtIssuer,
+		NotBefore: now.Unix(),
+		Subject:   subject,
+	}
+}
+
+func TestGeneratedTokensAreValid(t *testing.T) {
+	auth, _ := newTestAuthService()
+	user := &data.User{Email: "writer@example.com", TokenHash: "hash"}
+
+	accessToken, err := auth.GenerateAccessToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID, err := auth.ValidateAccessToken(accessToken); err != nil || userID != user.Email {
+		t.Errorf("access token rejected: %v", err)
+	}
+
+	refreshToken, err := auth.GenerateRefreshToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, customKey, err := auth.ValidateRefreshToken(refreshToken)
+	if err != nil || userID != user.Email || customKey != auth.GenerateCustomKey(user.Email, user.TokenHash) {
+		t.Errorf("refresh token rejected: %v", err)
+	}
+}
+
+func TestForgedAndMalformedAccessTokens(t *testing.T) {
+	auth, configs := newTestAuthService()
+	const userID = "writer@example.com"
+	skew := time.Duration(configs.JwtClockSkew) * time.Second
+
+	forgedKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := ioutil.ReadFile(configs.AccessTokenPublicKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withClaims := func(modify func(c *jwt.StandardClaims)) string {
+		claims := service.AccessTokenCustomClaims{UserID: userID, KeyType: "access", StandardClaims: validStandardClaims(configs, userID)}
+		modify(&claims.StandardClaims)
+		return signWithFile(t, configs.AccessTokenPrivateKeyPath, claims)
+	}
+
+	tests := []struct {
+		name  string
+		token func() string
+		valid bool
+	}{
+		{"valid", func() string { return withClaims(func(c *jwt.StandardClaims) {}) }, true},
+		{"expired within skew", func() string {
+			return withClaims(func(c *jwt.StandardClaims) { c.ExpiresAt = time.Now().Add(-skew / 2).Unix() })
+		}, true},
+		{"not before within skew", func() string {
+			return withClaims(func(c *jwt.StandardClaims) { c.NotBefore = time.Now().Add(skew / 2).Unix() })
+		}, true},
+		{"expired", func() string {
+			return withClaims(func(c *jwt.StandardClaims) { c.ExpiresAt = time.Now().Add(-skew - time.Minute).Unix() })
+		}, false},
+		{"missing expiration", func() string { return withClaims(func(c *jwt.StandardClaims) { c.ExpiresAt = 0 }) }, false},
+		{"not yet valid", func() string {
+			return withClaims(func(c *jwt.StandardClaims) { c.NotBefore = time.Now().Add(skew + time.Minute).Unix() })
+		}, false},
+		{"missing not before", func() string { return withClaims(func(c *jwt.StandardClaims) { c.NotBefore = 0 }) }, false},
+		{"issued in the future", func() string {
+			return withClaims(func(c *jwt.StandardClaims) { c.IssuedAt = time.Now().Add(skew + time.Minute).Unix() })
+		}, false},
+		{"missing issued at", func() string { return withClaims(func(c *jwt.StandardClaims) { c.IssuedAt = 0 }) }, false},
+		{"wrong issuer", func() string { return withClaims(func(c *jwt.StandardClaims) { c.Issuer = "evil.issuer" }) }, false},
+		{"missing issuer", func() string { return withClaims(func(c *jwt.StandardClaims) { c.Issuer = "" }) }, false},
+		{"wrong audience", func() string { return withClaims(func(c *jwt.StandardClaims) { c.Audience = "other-service" }) }, false},
+		{"missing audience", func() string { return withClaims(func(c *jwt.StandardClaims) { c.Audience = "" }) }, false},
+		{"missing jti", func() string { return withClaims(func(c *jwt.StandardClaims) { c.Id = "" }) }, false},
+		{"subject mismatch", func() string { return withClaims(func(c *jwt.StandardClaims) { c.Subject = "other@example.com" }) }, false},
+		{"refresh key type", func() string {
+			claims := service.AccessTokenCustomClaims{UserID: userID, KeyType: "refresh", StandardClaims: validStandardClaims(configs, userID)}
+			return signWithFile(t, configs.AccessTokenPrivateKeyPath, claims)
+		}, false},
+		{"signed by refresh key", func() string {
+			claims := service.AccessTokenCustomClaims{UserID: userID, KeyType: "access", StandardClaims: validStandardClaims(configs, userID)}
+			return signWithFile(t, configs.RefreshTokenPrivateKeyPath, claims)
+		}, false},
+		{"signed by unknown key", func() string {
+			claims := service.AccessTokenCustomClaims{UserID: userID, KeyType: "access", StandardClaims: validStandardClaims(configs, userID)}
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(forgedKey)
+			return token
+		}, false},
+		{"RS512 instead of RS256", func() string {
+			claims := service.AccessTokenCustomClaims{UserID: userID, KeyType: "access", StandardClaims: validStandardClaims(configs, userID)}
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodRS512, claims).SignedString(forgedKey)
+			return token
+		}, false},
+		{"HMAC with public key as secret", func() string {
+			claims := service.AccessTokenCustomClaims{UserID: userID, KeyType: "access", StandardClaims: validStandardClaims(configs, userID)}
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(publicKey)
+			return token
+		}, false},
+		{"alg none", func() string {
+			claims := service.AccessTokenCustomClaims{UserID: userID, KeyType: "access", StandardClaims: validStandardClaims(configs, userID)}
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return token
+		}, false},
+		{"tampered payload", func() string {
+			parts := strings.Split(withClaims(func(c *jwt.StandardClaims) {}), ".")
+			other := strings.Split(withClaims(func(c *jwt.StandardClaims) { c.Subject = "x" }), ".")
+			return parts[0] + "." + other[1] + "." + parts[2]
+		}, false},
+		{"empty", func() string { return "" }, false},
+		{"garbage", func() string { return "not.a.token" }, false},
+		{"two segments", func() string {
+			parts := strings.Split(withClaims(func(c *jwt.StandardClaims) {}), ".")
+			return parts[0] + "." + parts[1]
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := auth.ValidateAccessToken(tt.token())
+			if tt.valid && err != nil {
+				t.Errorf("expected token to be accepted, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("expected token to be rejected")
+			}
+		})
+	}
+}
+
+func TestForgedRefreshTokens(t *testing.T) {
+	auth, configs := newTestAuthService()
+	const userID = "writer@example.com"
+
+	tests := []struct {
+		name   string
+		claims service.RefreshTokenCustomClaims
+		key    string
+	}{
+		{"access key type", service.RefreshTokenCustomClaims{UserID: userID, CustomKey: "k", KeyType: "access", StandardClaims: validStandardClaims(configs, userID)}, configs.RefreshTokenPrivateKeyPath},
+		{"signed by access key", service.RefreshTokenCustomClaims{UserID: userID, CustomKey: "k", KeyType: "refresh", StandardClaims: validStandardClaims(configs, userID)}, configs.AccessTokenPrivateKeyPath},
+		{"no registered claims", service.RefreshTokenCustomClaims{UserID: userID, CustomKey: "k", KeyType: "refresh"}, configs.RefreshTokenPrivateKeyPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := auth.ValidateRefreshToken(signWithFile(t, tt.key, tt.claims)); err == nil {
+				t.Errorf("expected token to be rejected")
+			}
+		})
+	}
+}
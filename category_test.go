@@ -0,0 +1,71 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"testing"
+)
+
+func TestCategoryTree(t *testing.T) {
+	logger := utils.NewLogger()
+	repository := data.NewRepo(logger)
+
+	engineering, err := repository.CreateCategory(&data.Category{Name: "Engineering"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend, _ := repository.CreateCategory(&data.Category{Name: "Backend", ParentID: engineering.ID})
+	databases, _ := repository.CreateCategory(&data.Category{Name: "Databases", ParentID: backend.ID})
+	if databases.Path != "Engineering > Backend > Databases" {
+		t.Errorf("unexpected path %q", databases.Path)
+	}
+	if _, err := repository.CreateCategory(&data.Category{Name: "backend", ParentID: engineering.ID}); err == nil {
+		t.Errorf("created a sibling with the same name")
+	}
+	if _, err := repository.CreateCategory(&data.Category{Name: "Orphan", ParentID: "missing"}); err == nil {
+		t.Errorf("created a category under a missing parent")
+	}
+	if _, err := repository.CreateArticle(&data.Article{Title: "uncategorizable", CategoryID: "missing"}); err == nil {
+		t.Errorf("created an article in a missing category")
+	}
+
+	top, _ := repository.CreateArticle(&data.Article{Title: "engineering article", CategoryID: engineering.ID})
+	nested, _ := repository.CreateArticle(&data.Article{Title: "database article", CategoryID: databases.ID})
+
+	if _, total, _ := repository.GetArticlesByCategory(engineering.ID, false, 1, 10, data.SortNewest); total != 1 {
+		t.Errorf("expected 1 article directly in the category, got %d", total)
+	}
+	if page, total, _ := repository.GetArticlesByCategory(engineering.ID, true, 1, 1, data.SortNewest); total != 2 || len(page) != 1 || page[0].ID != nested.ID {
+		t.Errorf("unexpected articles with subcategories %v, %d", page, total)
+	}
+
+	if _, err := repository.MoveCategory(engineering.ID, databases.ID); err == nil {
+		t.Errorf("moved a category under its own subcategory")
+	}
+	if moved, err := repository.MoveCategory(databases.ID, engineering.ID); err != nil || moved.Path != "Engineering > Databases" {
+		t.Errorf("unexpected move %+v, %v", moved, err)
+	}
+	if renamed, err := repository.RenameCategory(engineering.ID, "Tech"); err != nil || renamed.Path != "Tech" {
+		t.Errorf("unexpected rename %+v, %v", renamed, err)
+	}
+
+	if err := repository.DeleteCategory(engineering.ID); err != nil {
+		t.Fatal(err)
+	}
+	if category, _ := repository.GetCategoryByID(backend.ID); category.ParentID != "" || category.Path != "Backend" {
+		t.Errorf("subcategory not reparented: %+v", category)
+	}
+	if article, _ := repository.GetArticleByID(top.ID); article.CategoryID != "" {
+		t.Errorf("article not moved to the parent: %q", article.CategoryID)
+	}
+
+	found := false
+	for _, node := range repository.GetCategoryTree() {
+		if node.ID == databases.ID {
+			found = node.Articles == 1
+		}
+	}
+	if !found {
+		t.Errorf("reparented category not at the top of the tree")
+	}
+}
@@ -0,0 +1,86 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCommentThreadsAndCounts(t *testing.T) {
+	logger := utils.NewLogger()
+	repository := data.NewRepo(logger)
+	article, _ := repository.CreateArticle(&data.Article{Title: "discussed", Author: "writer@example.com"})
+	other, _ := repository.CreateArticle(&data.Article{Title: "other", Author: "writer@example.com"})
+
+	first, _ := repository.CreateComment(&data.Comment{ArticleID: article.ID, Content: "first", Author: "reader@example.com"})
+	reply, _ := repository.CreateComment(&data.Comment{ArticleID: article.ID, ParentID: first.ID, Content: "reply", Author: "writer@example.com"})
+	repository.CreateComment(&data.Comment{ArticleID: article.ID, ParentID: reply.ID, Content: "nested reply", Author: "reader@example.com"})
+	repository.CreateComment(&data.Comment{ArticleID: article.ID, Content: "second", Author: "reader@example.com"})
+	repository.CreateComment(&data.Comment{ArticleID: article.ID, Content: "third", Author: "reader@example.com"})
+	if _, err := repository.CreateComment(&data.Comment{ArticleID: other.ID, ParentID: first.ID, Content: "misplaced"}); err == nil {
+		t.Errorf("reply to a comment of another article accepted")
+	}
+
+	threads, total, err := repository.GetComments(article.ID, 1, 2)
+	if err != nil || total != 3 || len(threads) != 2 {
+		t.Fatalf("unexpected first page: %d threads of %d, %v", len(threads), total, err)
+	}
+	if threads[0].Content != "first" || len(threads[0].Replies) != 1 || len(threads[0].Replies[0].Replies) != 1 {
+		t.Errorf("replies not nested %+v", threads[0])
+	}
+	if threads, _, _ := repository.GetComments(article.ID, 2, 2); len(threads) != 1 || threads[0].Content != "third" {
+		t.Errorf("unexpected second page %+v", threads)
+	}
+	if fetched, _ := repository.GetArticleByID(article.ID); fetched.CommentCount != 5 {
+		t.Errorf("expected 5 comments, got %d", fetched.CommentCount)
+	}
+
+	// a deleted comment with replies stays in the thread without its content
+	repository.DeleteComment(first.ID)
+	threads, _, _ = repository.GetComments(article.ID, 1, 2)
+	if !threads[0].Deleted || threads[0].Content != "" || threads[0].AuthorProfile != nil || len(threads[0].Replies) != 1 {
+		t.Errorf("deleted comment not kept as placeholder %+v", threads[0])
+	}
+	if fetched, _ := repository.GetArticleByID(article.ID); fetched.CommentCount != 4 {
+		t.Errorf("expected 4 comments, got %d", fetched.CommentCount)
+	}
+
+	repository.DeleteArticle(article.ID)
+	if _, err := repository.GetCommentByID(reply.ID); err == nil {
+		t.Errorf("comments of a deleted article kept")
+	}
+}
+
+func TestCommentModeration(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	ch := handlers.NewCommentHandler(logger, configs, data.NewValidation(), repository)
+	article, _ := repository.CreateArticle(&data.Article{Title: "moderated", Author: "moderator@example.com"})
+
+	tests := []struct {
+		name   string
+		userID string
+		status int
+	}{
+		{"another reader", "bystander@example.com", http.StatusBadRequest},
+		{"author of the article", "moderator@example.com", http.StatusOK},
+		{"author of the comment", "commenter@example.com", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comment, _ := repository.CreateComment(&data.Comment{ArticleID: article.ID, Content: "spam", Author: "commenter@example.com"})
+			req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/Comment/Delete/"+comment.ID, nil), map[string]string{"commentID": comment.ID})
+			rec := httptest.NewRecorder()
+			ch.DeleteComment(rec, asUser(req, tt.userID))
+			if rec.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
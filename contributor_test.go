@@ -0,0 +1,73 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestContributorPermissions(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	ah := handlers.NewArticleHandler(logger, configs, data.NewValidation(), repository, service.NewArticleService(logger, configs), service.NewAnalyticsService(logger, configs, repository))
+	for _, email := range []string{"owner@contrib.example.com", "editor@contrib.example.com", "viewer@contrib.example.com"} {
+		repository.Create(&data.User{Email: email, Password: "hash"})
+	}
+	article, _ := repository.CreateArticle(&data.Article{Title: "shared article", Author: "owner@contrib.example.com"})
+
+	if _, err := repository.SetContributor(article.ID, "owner@contrib.example.com", data.PermissionEditor); err == nil {
+		t.Errorf("owner added as contributor")
+	}
+	repository.SetContributor(article.ID, "editor@contrib.example.com", data.PermissionEditor)
+	repository.SetContributor(article.ID, "viewer@contrib.example.com", data.PermissionViewer)
+	if contributors, _ := repository.GetContributors(article.ID); len(contributors) != 3 || contributors[0].Permission != data.PermissionOwner {
+		t.Errorf("unexpected contributors %+v", contributors)
+	}
+
+	update := func(userID string) int {
+		req := asUser(httptest.NewRequest(http.MethodPost, "/Article/Update", nil), userID)
+		req = req.WithContext(context.WithValue(req.Context(), handlers.ArticleKey{}, data.Article{ID: article.ID, Title: "edited by " + userID}))
+		rec := httptest.NewRecorder()
+		ah.UpdateArticle(rec, req)
+		return rec.Code
+	}
+	remove := func(userID string) int {
+		req := mux.SetURLVars(asUser(httptest.NewRequest(http.MethodGet, "/Article/Delete/"+article.ID, nil), userID), map[string]string{"articleID": article.ID})
+		rec := httptest.NewRecorder()
+		ah.DeleteArticle(rec, req)
+		return rec.Code
+	}
+	if code := update("editor@contrib.example.com"); code != http.StatusCreated {
+		t.Errorf("editor could not update: %d", code)
+	}
+	if code := update("viewer@contrib.example.com"); code != http.StatusBadRequest {
+		t.Errorf("viewer updated the article: %d", code)
+	}
+	if code := remove("editor@contrib.example.com"); code != http.StatusBadRequest {
+		t.Errorf("editor deleted the article: %d", code)
+	}
+
+	if _, err := repository.TransferOwnership(article.ID, "editor@contrib.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if repository.GetArticlePermission(article.ID, "editor@contrib.example.com") != data.PermissionOwner ||
+		repository.GetArticlePermission(article.ID, "owner@contrib.example.com") != data.PermissionEditor {
+		t.Errorf("ownership not transferred")
+	}
+
+	repository.DeleteUser("viewer@contrib.example.com", "")
+	if repository.GetArticlePermission(article.ID, "viewer@contrib.example.com") != "" {
+		t.Errorf("deleted user still a contributor")
+	}
+	if code := remove("editor@contrib.example.com"); code != http.StatusCreated {
+		t.Errorf("new owner could not delete the article: %d", code)
+	}
+}
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var apiKeys = make(map[string]APIKey)
+
+// creates a new API key for the user
+func (repo *Repo) CreateAPIKey(key *APIKey) (*APIKey, error) {
+	repo.logger.Info("creating api key", "user", key.UserID, "name", key.Name)
+	key.ID = uuid.NewV4().String()
+	key.CreatedAt = time.Now()
+	apiKeys[key.ID] = *key
+	return key, nil
+}
+
+// lists all the API keys of a user
+func (repo *Repo) GetAPIKeysByUser(userID string) []APIKey {
+	repo.logger.Info("fetching api keys")
+	result := []APIKey{}
+	for _, key := range apiKeys {
+		if key.UserID == userID {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// get an API key by the hash of the key
+func (repo *Repo) GetAPIKeyByHash(keyHash string) (*APIKey, error) {
+	for _, key := range apiKeys {
+		if key.KeyHash == keyHash {
+			return &key, nil
+		}
+	}
+	return nil, errors.New(utils.ErrAPIKeyNotFound)
+}
+
+// records the last time an API key was used
+func (repo *Repo) TouchAPIKey(keyID string, usedAt time.Time) {
+	if key, exists := apiKeys[keyID]; exists {
+		key.LastUsedAt = &usedAt
+		apiKeys[keyID] = key
+	}
+}
+
+// revokes (deletes) an API key owned by the user
+func (repo *Repo) RevokeAPIKey(userID string, keyID string) error {
+	repo.logger.Info("revoking api key", "id", keyID)
+	if key, exists := apiKeys[keyID]; exists && key.UserID == userID {
+		delete(apiKeys, keyID)
+		return nil
+	}
+	return errors.New(utils.ErrAPIKeyNotFound)
+}
@@ -0,0 +1,116 @@
+package data
+
+import (
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// attachments are updated by the image workers and orphanedBlobs, the blob keys of the deleted attachments,
+// is drained by the cleanup worker. Unlike the other stores they are used in the background, so they are
+// guarded by attachmentsMu
+var attachments = make(map[string]Attachment)
+var orphanedBlobs []string
+var attachmentsMu sync.Mutex
+
+// creates an attachment of an article whose content is already in the blob store
+func (repo *Repo) CreateAttachment(attachment *Attachment) (*Attachment, error) {
+	if _, exists := articles[attachment.ArticleID]; !exists {
+		return nil, errors.New(utils.ErrArticleNotFound)
+	}
+	repo.logger.Info("creating attachment", "articleID", attachment.ArticleID, "contentType", attachment.ContentType, "size", attachment.Size)
+	attachment.ID = uuid.NewV4().String()
+	attachment.CreatedAt = time.Now()
+	attachmentsMu.Lock()
+	defer attachmentsMu.Unlock()
+	attachments[attachment.ID] = *attachment
+	return attachment, nil
+}
+
+// get an attachment by ID
+func (repo *Repo) GetAttachmentByID(attachmentID string) (*Attachment, error) {
+	attachmentsMu.Lock()
+	defer attachmentsMu.Unlock()
+	attachment, exists := attachments[attachmentID]
+	if !exists {
+		return nil, errors.New(utils.ErrAttachmentNotFound)
+	}
+	return &attachment, nil
+}
+
+// lists the attachments of an article, oldest first
+func (repo *Repo) GetAttachments(articleID string) ([]Attachment, error) {
+	if _, exists := articles[articleID]; !exists {
+		return nil, errors.New(utils.ErrArticleNotFound)
+	}
+	attachmentsMu.Lock()
+	defer attachmentsMu.Unlock()
+	result := []Attachment{}
+	for _, attachment := range attachments {
+		if attachment.ArticleID == articleID {
+			result = append(result, attachment)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// sets the resized variants of an image attachment once they are in the blob store
+func (repo *Repo) SetAttachmentVariants(attachmentID string, variants []AttachmentVariant) error {
+	attachmentsMu.Lock()
+	defer attachmentsMu.Unlock()
+	attachment, exists := attachments[attachmentID]
+	if !exists {
+		return errors.New(utils.ErrAttachmentNotFound)
+	}
+	attachment.Variants = variants
+	attachments[attachmentID] = attachment
+	return nil
+}
+
+// deletes an attachment and returns it as it was deleted, the caller deletes its content from the blob store
+func (repo *Repo) DeleteAttachment(attachmentID string) (*Attachment, error) {
+	attachmentsMu.Lock()
+	defer attachmentsMu.Unlock()
+	attachment, exists := attachments[attachmentID]
+	if !exists {
+		return nil, errors.New(utils.ErrAttachmentNotFound)
+	}
+	repo.logger.Info("deleting attachment", "id", attachmentID)
+	delete(attachments, attachmentID)
+	return &attachment, nil
+}
+
+// adds blob keys whose content is no longer referenced, the cleanup worker deletes them from the blob store
+func (repo *Repo) AddOrphanedBlobs(keys ...string) {
+	attachmentsMu.Lock()
+	defer attachmentsMu.Unlock()
+	orphanedBlobs = append(orphanedBlobs, keys...)
+}
+
+// takes the blob keys waiting to be deleted from the blob store
+func (repo *Repo) TakeOrphanedBlobs() []string {
+	attachmentsMu.Lock()
+	defer attachmentsMu.Unlock()
+	keys := orphanedBlobs
+	orphanedBlobs = nil
+	return keys
+}
+
+// deleteAttachments deletes the attachments of a deleted article and orphans their blobs
+func deleteAttachments(articleID string) {
+	attachmentsMu.Lock()
+	defer attachmentsMu.Unlock()
+	for id, attachment := range attachments {
+		if attachment.ArticleID == articleID {
+			orphanedBlobs = append(orphanedBlobs, attachment.BlobKeys()...)
+			delete(attachments, id)
+		}
+	}
+}
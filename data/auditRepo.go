@@ -0,0 +1,29 @@
+package data
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// auditLog is only ever appended to, nothing updates or removes its events
+var auditLog []AuditEvent
+
+// appends an event to the audit log
+func (repo *Repo) AppendAuditEvent(event *AuditEvent) {
+	event.ID = uuid.NewV4().String()
+	event.Time = time.Now().UTC()
+	auditLog = append(auditLog, *event)
+}
+
+// get the audit events selected by the filter, oldest first
+func (repo *Repo) GetAuditEvents(filter AuditFilter) []AuditEvent {
+	repo.logger.Info("fetching audit events")
+	result := []AuditEvent{}
+	for i := range auditLog {
+		if filter.Matches(&auditLog[i]) {
+			result = append(result, auditLog[i])
+		}
+	}
+	return result
+}
@@ -0,0 +1,206 @@
+package data
+
+import (
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var categories = make(map[string]Category)
+
+// separator of the names in the path of a category
+const categoryPathSeparator = " > "
+
+// creates a new category under its parent, the names of sibling categories are unique
+func (repo *Repo) CreateCategory(category *Category) (*Category, error) {
+	if err := checkCategoryPlacement("", category.Name, category.ParentID); err != nil {
+		return nil, err
+	}
+
+	repo.logger.Info("creating category", "name", category.Name, "parentID", category.ParentID)
+	category.ID = uuid.NewV4().String()
+	category.CreatedAt = time.Now()
+	category.Path = ""
+	categories[category.ID] = *category
+	*category = withPath(*category)
+	return category, nil
+}
+
+// get a category by ID
+func (repo *Repo) GetCategoryByID(categoryID string) (*Category, error) {
+	category, exists := categories[categoryID]
+	if !exists {
+		return nil, errors.New(utils.ErrCategoryNotFound)
+	}
+	category = withPath(category)
+	return &category, nil
+}
+
+// get the category tree, siblings are ordered by name
+func (repo *Repo) GetCategoryTree() []CategoryNode {
+	counts := make(map[string]int)
+	for _, article := range articles {
+		counts[article.CategoryID]++
+	}
+	children := make(map[string][]Category)
+	for _, category := range categories {
+		children[category.ParentID] = append(children[category.ParentID], category)
+	}
+
+	var build func(parentID string) []CategoryNode
+	build = func(parentID string) []CategoryNode {
+		nodes := []CategoryNode{}
+		for _, category := range children[parentID] {
+			nodes = append(nodes, CategoryNode{Category: withPath(category), Articles: counts[category.ID], Subcategories: build(category.ID)})
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			return strings.ToLower(nodes[i].Name) < strings.ToLower(nodes[j].Name)
+		})
+		return nodes
+	}
+	return build("")
+}
+
+// renames a category
+func (repo *Repo) RenameCategory(categoryID string, name string) (*Category, error) {
+	category, exists := categories[categoryID]
+	if !exists {
+		return nil, errors.New(utils.ErrCategoryNotFound)
+	}
+	if err := checkCategoryPlacement(categoryID, name, category.ParentID); err != nil {
+		return nil, err
+	}
+
+	repo.logger.Info("renaming category", "id", categoryID, "name", name)
+	category.Name = name
+	categories[categoryID] = category
+	category = withPath(category)
+	return &category, nil
+}
+
+// moves a category with its subcategories under a new parent, or to the top of the tree without parent.
+// A category can not be moved under itself or one of its subcategories
+func (repo *Repo) MoveCategory(categoryID string, parentID string) (*Category, error) {
+	category, exists := categories[categoryID]
+	if !exists {
+		return nil, errors.New(utils.ErrCategoryNotFound)
+	}
+	for ancestorID := parentID; ancestorID != ""; ancestorID = categories[ancestorID].ParentID {
+		if ancestorID == categoryID {
+			return nil, errors.New(utils.ErrCategoryCycle)
+		}
+	}
+	if err := checkCategoryPlacement(categoryID, category.Name, parentID); err != nil {
+		return nil, err
+	}
+
+	repo.logger.Info("moving category", "id", categoryID, "parentID", parentID)
+	category.ParentID = parentID
+	categories[categoryID] = category
+	category = withPath(category)
+	return &category, nil
+}
+
+// deletes a category, its subcategories and articles move to its parent
+func (repo *Repo) DeleteCategory(categoryID string) error {
+	category, exists := categories[categoryID]
+	if !exists {
+		return errors.New(utils.ErrCategoryNotFound)
+	}
+	for id, child := range categories {
+		if child.ParentID == categoryID && categoryNameTaken(id, child.Name, category.ParentID) {
+			return errors.New(utils.ErrCategoryNameTaken)
+		}
+	}
+
+	repo.logger.Info("deleting category", "id", categoryID)
+	for id, child := range categories {
+		if child.ParentID == categoryID {
+			child.ParentID = category.ParentID
+			categories[id] = child
+		}
+	}
+	for id, article := range articles {
+		if article.CategoryID == categoryID {
+			article.CategoryID = category.ParentID
+			articles[id] = article
+			articlesChanged()
+		}
+	}
+	delete(categories, categoryID)
+	return nil
+}
+
+// fetchs one page of the published articles of a category, with includeSubcategories the articles of all its subcategories too
+func (repo *Repo) GetArticlesByCategory(categoryID string, includeSubcategories bool, pageNumber int, pageSize int, sortBy string) ([]Article, int, error) {
+	if _, exists := categories[categoryID]; !exists {
+		return nil, 0, errors.New(utils.ErrCategoryNotFound)
+	}
+
+	selected := map[string]bool{categoryID: true}
+	if includeSubcategories {
+		for id := range categories {
+			for ancestorID := categories[id].ParentID; ancestorID != ""; ancestorID = categories[ancestorID].ParentID {
+				if ancestorID == categoryID {
+					selected[id] = true
+					break
+				}
+			}
+		}
+	}
+
+	result := []Article{}
+	for _, article := range articles {
+		if selected[article.CategoryID] && article.Published() {
+			result = append(result, withDetails(article))
+		}
+	}
+	sortArticles(result, sortBy)
+
+	start := (pageNumber - 1) * pageSize
+	if pageNumber < 1 || (start >= len(result) && pageNumber > 1) {
+		return nil, 0, errors.New(utils.ErrInvalidPageNumber)
+	}
+	stop := start + pageSize
+	if stop > len(result) {
+		stop = len(result)
+	}
+	return result[start:stop], len(result), nil
+}
+
+// checkCategoryPlacement checks that the parent exists and that no sibling has the name
+func checkCategoryPlacement(categoryID string, name string, parentID string) error {
+	if parentID != "" {
+		if _, exists := categories[parentID]; !exists {
+			return errors.New(utils.ErrParentCategoryNotFound)
+		}
+	}
+	if categoryNameTaken(categoryID, name, parentID) {
+		return errors.New(utils.ErrCategoryNameTaken)
+	}
+	return nil
+}
+
+// categoryNameTaken reports whether a category other than categoryID under the parent has the name
+func categoryNameTaken(categoryID string, name string, parentID string) bool {
+	for id, category := range categories {
+		if id != categoryID && category.ParentID == parentID && strings.EqualFold(category.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// withPath sets the path of the category from the names of its parents
+func withPath(category Category) Category {
+	names := []string{category.Name}
+	for parentID := category.ParentID; parentID != ""; parentID = categories[parentID].ParentID {
+		names = append([]string{categories[parentID].Name}, names...)
+	}
+	category.Path = strings.Join(names, categoryPathSeparator)
+	return category
+}
@@ -0,0 +1,157 @@
+package data
+
+import (
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"sort"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var comments = make(map[string]Comment)
+
+// commentCounts is the number of comments that are not deleted per article ID
+var commentCounts = make(map[string]int)
+
+// creates a new comment, replies have to be on the same article as their parent
+func (repo *Repo) CreateComment(comment *Comment) (*Comment, error) {
+	if _, exists := articles[comment.ArticleID]; !exists {
+		return nil, errors.New(utils.ErrArticleNotFound)
+	}
+	if comment.ParentID != "" {
+		if parent, exists := comments[comment.ParentID]; !exists || parent.ArticleID != comment.ArticleID || parent.Deleted {
+			return nil, errors.New(utils.ErrCommentNotFound)
+		}
+	}
+
+	repo.logger.Info("creating comment", "article", comment.ArticleID)
+	comment.ID = uuid.NewV4().String()
+	comment.CreatedAt = time.Now()
+	comment.UpdatedAt = comment.CreatedAt
+	comment.Deleted = false
+	comment.Replies = nil
+	comments[comment.ID] = *comment
+	commentCounts[comment.ArticleID]++
+	*comment = withCommentDetails(*comment)
+	return comment, nil
+}
+
+// get a comment by ID
+func (repo *Repo) GetCommentByID(commentID string) (*Comment, error) {
+	comment, exists := comments[commentID]
+	if !exists || comment.Deleted {
+		return nil, errors.New(utils.ErrCommentNotFound)
+	}
+	comment = withCommentDetails(comment)
+	return &comment, nil
+}
+
+// updates only the content of a comment
+func (repo *Repo) UpdateComment(newComment *Comment) (*Comment, error) {
+	comment, exists := comments[newComment.ID]
+	if !exists || comment.Deleted {
+		return nil, errors.New(utils.ErrCommentNotFound)
+	}
+	repo.logger.Info("updating comment", "id", comment.ID)
+	comment.Content = newComment.Content
+	comment.UpdatedAt = time.Now()
+	comments[comment.ID] = comment
+	comment = withCommentDetails(comment)
+	return &comment, nil
+}
+
+// deletes a comment. A comment with replies is only emptied so the replies keep their place in the thread,
+// deleted comments without replies left are removed
+func (repo *Repo) DeleteComment(commentID string) error {
+	comment, exists := comments[commentID]
+	if !exists || comment.Deleted {
+		return errors.New(utils.ErrCommentNotFound)
+	}
+	repo.logger.Info("deleting comment", "id", commentID)
+	commentCounts[comment.ArticleID]--
+
+	if hasReplies(commentID) {
+		comment.Deleted = true
+		comment.Content = ""
+		comment.Author = ""
+		comments[commentID] = comment
+		return nil
+	}
+	delete(comments, commentID)
+	for parent, exists := comments[comment.ParentID]; exists && parent.Deleted && !hasReplies(parent.ID); parent, exists = comments[parent.ParentID] {
+		delete(comments, parent.ID)
+	}
+	return nil
+}
+
+// fetches one page of the threads of an article, oldest first. Each top level comment comes with all its replies
+func (repo *Repo) GetComments(articleID string, pageNumber int, pageSize int) ([]Comment, int, error) {
+	if _, exists := articles[articleID]; !exists {
+		return nil, 0, errors.New(utils.ErrArticleNotFound)
+	}
+	repo.logger.Info("fetching comments", "article", articleID)
+
+	children := make(map[string][]Comment)
+	for _, comment := range comments {
+		if comment.ArticleID == articleID {
+			children[comment.ParentID] = append(children[comment.ParentID], withCommentDetails(comment))
+		}
+	}
+	for _, siblings := range children {
+		sort.Slice(siblings, func(i, j int) bool {
+			return siblings[i].CreatedAt.Before(siblings[j].CreatedAt)
+		})
+	}
+
+	threads := children[""]
+	start := (pageNumber - 1) * pageSize
+	if pageNumber < 1 || (start >= len(threads) && pageNumber != 1) {
+		return nil, 0, errors.New(utils.ErrInvalidPageNumber)
+	}
+	stop := start + pageSize
+	if stop > len(threads) {
+		stop = len(threads)
+	}
+	result := []Comment{}
+	for _, thread := range threads[start:stop] {
+		result = append(result, withReplies(thread, children))
+	}
+	return result, len(threads), nil
+}
+
+// nests the replies of a comment, children maps the ID of a comment to its replies
+func withReplies(comment Comment, children map[string][]Comment) Comment {
+	for _, reply := range children[comment.ID] {
+		comment.Replies = append(comment.Replies, withReplies(reply, children))
+	}
+	return comment
+}
+
+// sets the public author information on a comment read from the store
+func withCommentDetails(comment Comment) Comment {
+	comment.AuthorProfile = nil
+	if !comment.Deleted {
+		comment.AuthorProfile = authorProfile(comment.Author)
+	}
+	return comment
+}
+
+func hasReplies(commentID string) bool {
+	for _, comment := range comments {
+		if comment.ParentID == commentID {
+			return true
+		}
+	}
+	return false
+}
+
+// deletes all comments of an article
+func deleteComments(articleID string) {
+	for id, comment := range comments {
+		if comment.ArticleID == articleID {
+			delete(comments, id)
+		}
+	}
+	delete(commentCounts, articleID)
+}
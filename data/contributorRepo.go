@@ -0,0 +1,120 @@
+package data
+
+import (
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"sort"
+	"time"
+)
+
+// contributors has the editors and viewers of the articles: articleID -> userID -> contributor,
+// the owner of an article is its Author
+var contributors = make(map[string]map[string]Contributor)
+
+// get the permission of a user on an article, nothing when the user is not a contributor
+func (repo *Repo) GetArticlePermission(articleID string, userID string) string {
+	article, exists := articles[articleID]
+	if !exists {
+		return ""
+	}
+	if article.Author == userID {
+		return PermissionOwner
+	}
+	return contributors[articleID][userID].Permission
+}
+
+// lists the contributors of an article, the owner first and then in the order they were added
+func (repo *Repo) GetContributors(articleID string) ([]Contributor, error) {
+	article, exists := articles[articleID]
+	if !exists {
+		return nil, errors.New(utils.ErrArticleNotFound)
+	}
+
+	result := []Contributor{}
+	for _, contributor := range contributors[articleID] {
+		contributor.User = authorProfile(contributor.UserID)
+		result = append(result, contributor)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].AddedAt.Before(result[j].AddedAt)
+	})
+	owner := Contributor{UserID: article.Author, User: authorProfile(article.Author), Permission: PermissionOwner, AddedAt: article.CreatedAt}
+	return append([]Contributor{owner}, result...), nil
+}
+
+// gives a user the editor or viewer permission on an article, or changes the permission of a contributor
+func (repo *Repo) SetContributor(articleID string, userID string, permission string) (*Contributor, error) {
+	article, exists := articles[articleID]
+	if !exists {
+		return nil, errors.New(utils.ErrArticleNotFound)
+	}
+	if _, exists := users[userID]; !exists {
+		return nil, errors.New(utils.ErrUserNotFound)
+	}
+	if permission != PermissionEditor && permission != PermissionViewer {
+		return nil, errors.New(utils.ErrInvalidPermission)
+	}
+	if article.Author == userID {
+		return nil, errors.New(utils.ErrContributorIsOwner)
+	}
+
+	repo.logger.Info("setting contributor", "articleID", articleID, "userID", userID, "permission", permission)
+	if contributors[articleID] == nil {
+		contributors[articleID] = make(map[string]Contributor)
+	}
+	contributor, exists := contributors[articleID][userID]
+	if !exists {
+		contributor = Contributor{UserID: userID, AddedAt: time.Now()}
+	}
+	contributor.Permission = permission
+	contributors[articleID][userID] = contributor
+	contributor.User = authorProfile(userID)
+	return &contributor, nil
+}
+
+// removes a contributor from an article, the owner can not be removed
+func (repo *Repo) RemoveContributor(articleID string, userID string) error {
+	if _, exists := contributors[articleID][userID]; !exists {
+		return errors.New(utils.ErrContributorNotFound)
+	}
+	repo.logger.Info("removing contributor", "articleID", articleID, "userID", userID)
+	delete(contributors[articleID], userID)
+	return nil
+}
+
+// makes another user the owner of an article, the previous owner becomes an editor. The article leaves
+// the series of the previous owner
+func (repo *Repo) TransferOwnership(articleID string, userID string) (*Article, error) {
+	article, exists := articles[articleID]
+	if !exists {
+		return nil, errors.New(utils.ErrArticleNotFound)
+	}
+	if _, exists := users[userID]; !exists {
+		return nil, errors.New(utils.ErrUserNotFound)
+	}
+	if article.Author == userID {
+		return nil, errors.New(utils.ErrContributorIsOwner)
+	}
+
+	repo.logger.Info("transferring article", "articleID", articleID, "from", article.Author, "to", userID)
+	if contributors[articleID] == nil {
+		contributors[articleID] = make(map[string]Contributor)
+	}
+	delete(contributors[articleID], userID)
+	if article.Author != TombstoneAuthor {
+		contributors[articleID][article.Author] = Contributor{UserID: article.Author, Permission: PermissionEditor, AddedAt: time.Now()}
+	}
+	removeFromSeries(articleID)
+	article.Author = userID
+	articles[articleID] = article
+	articlesChanged()
+	article = withDetails(article)
+	return &article, nil
+}
+
+// removeContributions removes a deleted user from the contributors of all articles
+func removeContributions(userID string) {
+	for _, articleContributors := range contributors {
+		delete(articleContributors, userID)
+	}
+}
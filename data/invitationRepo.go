@@ -0,0 +1,74 @@
+package data
+
+import (
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var invitations = make(map[string]Invitation)
+
+// creates a new invitation
+func (repo *Repo) CreateInvitation(invitation *Invitation) (*Invitation, error) {
+	repo.logger.Info("creating invitation", "createdBy", invitation.CreatedBy, "role", invitation.Role)
+	invitation.ID = uuid.NewV4().String()
+	invitation.CreatedAt = time.Now()
+	invitations[invitation.ID] = *invitation
+	return invitation, nil
+}
+
+// lists all invitations, newest first
+func (repo *Repo) GetInvitations() []Invitation {
+	result := []Invitation{}
+	for _, invitation := range invitations {
+		result = append(result, invitation)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result
+}
+
+// get an unused and unexpired invitation by the hash of its code, invitations issued
+// for a specific email can only be used by that email
+func (repo *Repo) GetValidInvitation(codeHash string, email string) (*Invitation, error) {
+	for _, invitation := range invitations {
+		if invitation.CodeHash != codeHash {
+			continue
+		}
+		if invitation.UsedAt != nil || time.Now().After(invitation.ExpiresAt) ||
+			(invitation.Email != "" && !strings.EqualFold(invitation.Email, email)) {
+			break
+		}
+		return &invitation, nil
+	}
+	return nil, errors.New(utils.ErrInvalidInvitation)
+}
+
+// marks an invitation as used by the given user
+func (repo *Repo) ConsumeInvitation(invitationID string, usedBy string) error {
+	invitation, exists := invitations[invitationID]
+	if !exists || invitation.UsedAt != nil {
+		return errors.New(utils.ErrInvalidInvitation)
+	}
+	repo.logger.Info("consuming invitation", "id", invitationID, "usedBy", usedBy)
+	now := time.Now()
+	invitation.UsedBy = usedBy
+	invitation.UsedAt = &now
+	invitations[invitationID] = invitation
+	return nil
+}
+
+// deletes an invitation
+func (repo *Repo) DeleteInvitation(invitationID string) error {
+	if _, exists := invitations[invitationID]; !exists {
+		return errors.New(utils.ErrInvitationNotFound)
+	}
+	repo.logger.Info("deleting invitation", "id", invitationID)
+	delete(invitations, invitationID)
+	return nil
+}
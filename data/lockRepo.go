@@ -0,0 +1,66 @@
+package data
+
+import (
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"time"
+)
+
+// editLocks has the edit lock of each article being edited, expired locks are ignored and replaced
+var editLocks = make(map[string]EditLock)
+
+// acquires the edit lock of an article for ttl, or renews it when the user already holds it. It fails with
+// the current lock when another user holds it
+func (repo *Repo) AcquireEditLock(articleID string, userID string, ttl time.Duration) (*EditLock, error) {
+	if _, exists := articles[articleID]; !exists {
+		return nil, errors.New(utils.ErrArticleNotFound)
+	}
+
+	now := time.Now()
+	lock, exists := editLocks[articleID]
+	if exists && lock.Holder != userID && lock.ExpiresAt.After(now) {
+		lock.HolderUser = authorProfile(lock.Holder)
+		return &lock, errors.New(utils.ErrArticleLocked)
+	}
+	if !exists || lock.Holder != userID || !lock.ExpiresAt.After(now) {
+		repo.logger.Info("acquiring edit lock", "articleID", articleID, "userID", userID)
+		lock = EditLock{ArticleID: articleID, Holder: userID, AcquiredAt: now}
+	}
+	lock.ExpiresAt = now.Add(ttl)
+	editLocks[articleID] = lock
+	lock.HolderUser = authorProfile(userID)
+	return &lock, nil
+}
+
+// get the unexpired edit lock of an article, nil when nobody is editing it
+func (repo *Repo) GetEditLock(articleID string) *EditLock {
+	lock, exists := editLocks[articleID]
+	if !exists || !lock.ExpiresAt.After(time.Now()) {
+		return nil
+	}
+	lock.HolderUser = authorProfile(lock.Holder)
+	return &lock
+}
+
+// releases the edit lock of an article held by the user, force releases the lock of any user
+func (repo *Repo) ReleaseEditLock(articleID string, userID string, force bool) error {
+	lock := repo.GetEditLock(articleID)
+	if lock == nil {
+		return errors.New(utils.ErrLockNotFound)
+	}
+	if lock.Holder != userID && !force {
+		return errors.New(utils.ErrArticleLocked)
+	}
+	repo.logger.Info("releasing edit lock", "articleID", articleID, "holder", lock.Holder, "force", force)
+	delete(editLocks, articleID)
+	return nil
+}
+
+// releaseEditLocks releases the edit locks held by a deleted user
+func releaseEditLocks(userID string) {
+	for articleID, lock := range editLocks {
+		if lock.Holder == userID {
+			delete(editLocks, articleID)
+		}
+	}
+}
@@ -2,20 +2,507 @@ package data
 
 import "time"
 
-// User is the data type for user object
+// User is the data type for user object.
+// OIDCSubject links the user to an account of the external identity provider.
+// Role, Disabled and MustResetPassword are managed by admins and can not be set at sign up.
+// InvitationCode is only read at sign up and never stored
 type User struct {
-	Email     string `json:"email" validate:"required" `
-	Password  string `json:"password" validate:"required"`
-	TokenHash string `json:"tokenhash"`
+	Email             string    `json:"email" validate:"required" `
+	Password          string    `json:"password" validate:"required"`
+	TokenHash         string    `json:"tokenhash"`
+	Username          string    `json:"username" validate:"omitempty,alphanum,min=3,max=30"`
+	DisplayName       string    `json:"displayName" validate:"max=100"`
+	Bio               string    `json:"bio" validate:"max=500"`
+	AvatarURL         string    `json:"avatarURL" validate:"omitempty,url"`
+	OIDCSubject       string    `json:"-"`
+	Role              string    `json:"-"`
+	Disabled          bool      `json:"-"`
+	MustResetPassword bool      `json:"-"`
+	CreatedAt         time.Time `json:"-"`
+	InvitationCode    string    `json:"invitationCode,omitempty"`
 }
 
-//Article is the data type for article object
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// IsAdmin reports whether the user has the admin role
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// Profile is the part of a user that is shown to other users
+type Profile struct {
+	Username    string `json:"username" validate:"required,alphanum,min=3,max=30"`
+	DisplayName string `json:"displayName" validate:"max=100"`
+	Bio         string `json:"bio" validate:"max=500"`
+	AvatarURL   string `json:"avatarURL" validate:"omitempty,url"`
+}
+
+// Profile returns the public profile of the user
+func (u *User) Profile() Profile {
+	return Profile{Username: u.Username, DisplayName: u.DisplayName, Bio: u.Bio, AvatarURL: u.AvatarURL}
+}
+
+// ArticleAuthor is how the author is shown on an article, readers never see the email of the author
+type ArticleAuthor struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	AvatarURL   string `json:"avatarURL"`
+}
+
+// TombstoneAuthor is the author of the articles of users who erased their account
+const TombstoneAuthor = "deleted-user"
+
+// Article is the data type for article object, Slug is derived from the title and unique.
+// Author is the ID (email) of the user who wrote the article, readers only see the AuthorProfile.
+// Content is written in Format, RenderedContent is its sanitized HTML. Excerpt, WordCount and ReadingTime(in minutes)
+// are derived from the content when the article is written, unless the author wrote the excerpt.
+// Drafts are left out of the public feeds, articles without a Status are published
 type Article struct {
+	ID              string            `json:"ID"`
+	Title           string            `json:"title" validate:"required" `
+	Slug            string            `json:"slug"`
+	Content         string            `json:"content" `
+	Format          string            `json:"format" validate:"omitempty,oneof=plain markdown html"`
+	Status          string            `json:"status" validate:"omitempty,oneof=draft published"`
+	RenderedContent string            `json:"-"`
+	Excerpt         string            `json:"excerpt" validate:"max=500"`
+	WordCount       int               `json:"wordCount"`
+	ReadingTime     int               `json:"readingTime"`
+	Tags            []string          `json:"tags" `
+	CategoryID      string            `json:"categoryID,omitempty"`
+	Series          *SeriesNavigation `json:"series,omitempty"`
+	EditLock        *EditLock         `json:"editLock,omitempty"`
+	SEO             *ArticleSEO       `json:"seo,omitempty"`
+	Author          string            `json:"-"`
+	AuthorProfile   *ArticleAuthor    `json:"author"`
+	CommentCount    int               `json:"commentCount"`
+	LikeCount       int               `json:"likeCount"`
+	BookmarkCount   int               `json:"bookmarkCount"`
+	CreatedAt       time.Time         `json:"createdAt"`
+	UpdatedAt       time.Time         `json:"updatedAt"`
+	PublishedAt     *time.Time        `json:"publishedAt,omitempty"`
+}
+
+// Published reports whether the article is public, articles written before drafts existed have no status
+func (a *Article) Published() bool {
+	return a.Status != StatusDraft
+}
+
+// PublicationTime returns when the article was published, articles written before the publication date
+// existed were published when they were created
+func (a *Article) PublicationTime() time.Time {
+	if a.PublishedAt != nil {
+		return *a.PublishedAt
+	}
+	return a.CreatedAt
+}
+
+// ArticleSummary is the lightweight projection of an article used by the listings, without the content
+type ArticleSummary struct {
+	ID            string         `json:"ID"`
+	Title         string         `json:"title"`
+	Slug          string         `json:"slug"`
+	Excerpt       string         `json:"excerpt"`
+	Format        string         `json:"format"`
+	Status        string         `json:"status"`
+	WordCount     int            `json:"wordCount"`
+	ReadingTime   int            `json:"readingTime"`
+	Tags          []string       `json:"tags"`
+	CategoryID    string         `json:"categoryID,omitempty"`
+	AuthorProfile *ArticleAuthor `json:"author"`
+	CommentCount  int            `json:"commentCount"`
+	LikeCount     int            `json:"likeCount"`
+	BookmarkCount int            `json:"bookmarkCount"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+	PublishedAt   *time.Time     `json:"publishedAt,omitempty"`
+}
+
+// Summary returns the summary projection of the article
+func (a *Article) Summary() ArticleSummary {
+	return ArticleSummary{
+		ID:            a.ID,
+		Title:         a.Title,
+		Slug:          a.Slug,
+		Excerpt:       a.Excerpt,
+		Format:        a.Format,
+		Status:        a.Status,
+		WordCount:     a.WordCount,
+		ReadingTime:   a.ReadingTime,
+		Tags:          a.Tags,
+		CategoryID:    a.CategoryID,
+		AuthorProfile: a.AuthorProfile,
+		CommentCount:  a.CommentCount,
+		LikeCount:     a.LikeCount,
+		BookmarkCount: a.BookmarkCount,
+		CreatedAt:     a.CreatedAt,
+		UpdatedAt:     a.UpdatedAt,
+		PublishedAt:   a.PublishedAt,
+	}
+}
+
+// Tag is a normalized tag in use with the number of its articles
+type Tag struct {
+	Name      string    `json:"name"`
+	Articles  int       `json:"articles"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Category is a section of the publication, categories without ParentID are at the top of the tree.
+// Path is the names of the category and its parents, like Engineering > Backend
+type Category struct {
 	ID        string    `json:"ID"`
-	Title     string    `json:"title" validate:"required" `
-	Content   string    `json:"content" `
-	Tags      []string  `json:"tags" `
-	Author    string    `json:"author"`
+	Name      string    `json:"name" validate:"required,max=100"`
+	ParentID  string    `json:"parentID,omitempty"`
+	Path      string    `json:"path"`
 	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CategoryNode is a category with its subcategories and the number of articles directly in it
+type CategoryNode struct {
+	Category
+	Articles      int            `json:"articles"`
+	Subcategories []CategoryNode `json:"subcategories"`
+}
+
+// Series is an ordered collection of articles of its owner, like the parts of a tutorial.
+// An article is part of at most one series
+type Series struct {
+	ID           string         `json:"ID"`
+	Title        string         `json:"title" validate:"required,max=200"`
+	Description  string         `json:"description" validate:"max=2000"`
+	ArticleIDs   []string       `json:"articleIDs"`
+	Owner        string         `json:"-"`
+	OwnerProfile *ArticleAuthor `json:"owner"`
+	CreatedAt    time.Time      `json:"createdAt"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+}
+
+// SeriesEntry is how an article is shown in the navigation of a series
+type SeriesEntry struct {
+	ID    string `json:"ID"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+// SeriesNavigation places an article in its series, Position starts at 1
+type SeriesNavigation struct {
+	SeriesID string       `json:"seriesID"`
+	Title    string       `json:"title"`
+	Position int          `json:"position"`
+	Total    int          `json:"total"`
+	Previous *SeriesEntry `json:"previous"`
+	Next     *SeriesEntry `json:"next"`
+}
+
+// Article permissions. The owner is the Author of the article and can do everything, editors update the article
+// and viewers read its statistics
+const (
+	PermissionOwner  = "owner"
+	PermissionEditor = "editor"
+	PermissionViewer = "viewer"
+)
+
+// CanUpdateArticle reports whether the permission allows to update the article
+func CanUpdateArticle(permission string) bool {
+	return permission == PermissionOwner || permission == PermissionEditor
+}
+
+// CanDeleteArticle reports whether the permission allows to delete the article and to manage its contributors
+func CanDeleteArticle(permission string) bool {
+	return permission == PermissionOwner
+}
+
+// Contributor is a user with a permission on an article, other users only see their public information
+type Contributor struct {
+	UserID     string         `json:"-"`
+	User       *ArticleAuthor `json:"user"`
+	Permission string         `json:"permission"`
+	AddedAt    time.Time      `json:"addedAt"`
+}
+
+// EditLock tells other contributors that a user is editing an article, the holder renews it with heartbeats
+// before it expires
+type EditLock struct {
+	ArticleID  string         `json:"articleID"`
+	Holder     string         `json:"-"`
+	HolderUser *ArticleAuthor `json:"holder"`
+	AcquiredAt time.Time      `json:"acquiredAt"`
+	ExpiresAt  time.Time      `json:"expiresAt"`
+}
+
+// ArticleSEO is the metadata search engines and social networks read from the public page of a published article,
+// the Open Graph tags are properties and the Twitter card tags are names of meta elements
+type ArticleSEO struct {
+	CanonicalURL string    `json:"canonicalURL"`
+	OpenGraph    []MetaTag `json:"openGraph"`
+	TwitterCard  []MetaTag `json:"twitterCard"`
+}
+
+// MetaTag is a meta element of a page, tags like article:tag can repeat
+type MetaTag struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// Attachment is a file uploaded for an article, the content is kept in the blob store under Key.
+// ContentType is sniffed from the content, the type declared by the client is ignored. Images also have
+// their dimensions and the resized variants generated in the background
+type Attachment struct {
+	ID          string              `json:"ID"`
+	ArticleID   string              `json:"articleID"`
+	Filename    string              `json:"filename"`
+	ContentType string              `json:"contentType"`
+	Size        int64               `json:"size"`
+	Width       int                 `json:"width,omitempty"`
+	Height      int                 `json:"height,omitempty"`
+	Variants    []AttachmentVariant `json:"variants,omitempty"`
+	Key         string              `json:"-"`
+	UploadedBy  string              `json:"-"`
+	CreatedAt   time.Time           `json:"createdAt"`
+}
+
+// AttachmentVariant is a resized copy of an image attachment, Name is the size it was generated for
+type AttachmentVariant struct {
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Key         string `json:"-"`
+}
+
+// Variant returns the variant generated for the size name, nil when there is none
+func (a *Attachment) Variant(name string) *AttachmentVariant {
+	for i := range a.Variants {
+		if a.Variants[i].Name == name {
+			return &a.Variants[i]
+		}
+	}
+	return nil
+}
+
+// BlobKeys returns the keys of the content of the attachment and of its variants
+func (a *Attachment) BlobKeys() []string {
+	keys := []string{a.Key}
+	for _, variant := range a.Variants {
+		keys = append(keys, variant.Key)
+	}
+	return keys
+}
+
+// Content formats of an article
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Publication statuses of an article
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+)
+
+// Orders of the articles listing
+const (
+	SortNewest    = "newest"
+	SortMostLiked = "likes"
+)
+
+// Comment is the data type for a comment on an article, replies to a comment have its ID as ParentID.
+// Deleted comments that have replies keep their place in the thread without author and content
+type Comment struct {
+	ID            string         `json:"ID"`
+	ArticleID     string         `json:"articleID" validate:"required"`
+	ParentID      string         `json:"parentID,omitempty"`
+	Content       string         `json:"content" validate:"required,max=5000"`
+	Author        string         `json:"-"`
+	AuthorProfile *ArticleAuthor `json:"author"`
+	Deleted       bool           `json:"deleted,omitempty"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+	Replies       []Comment      `json:"replies,omitempty"`
+}
+
+// API key scopes that can be granted to an APIKey
+const (
+	ScopeArticlesRead  = "articles:read"
+	ScopeArticlesWrite = "articles:write"
+)
+
+// APIKey is the data type for a named API key used by service accounts and automation.
+// Only the hash of the key is stored, the key itself is returned once on creation
+type APIKey struct {
+	ID         string     `json:"ID"`
+	UserID     string     `json:"userID"`
+	Name       string     `json:"name" validate:"required"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes" validate:"required,min=1,dive,oneof=articles:read articles:write"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// HasScope reports whether the key was granted the given scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthClient is the data type for a third-party application allowed to act on behalf of users.
+// Public clients (single page and mobile apps) have no secret and can only use the authorization code grant
+type OAuthClient struct {
+	ID           string    `json:"clientID"`
+	SecretHash   string    `json:"-"`
+	Name         string    `json:"name" validate:"required"`
+	Owner        string    `json:"owner"`
+	RedirectURIs []string  `json:"redirectURIs" validate:"required,min=1,dive,url"`
+	Scopes       []string  `json:"scopes" validate:"required,min=1,dive,oneof=articles:read articles:write"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// OAuthConsent records the scopes a user granted to a client
+type OAuthConsent struct {
+	UserID    string    `json:"userID"`
+	ClientID  string    `json:"clientID"`
+	Scopes    []string  `json:"scopes"`
+	GrantedAt time.Time `json:"grantedAt"`
+}
+
+// Invitation is the data type for an admin issued code that allows to sign up when registration is invite-only.
+// Only the hash of the code is stored, the code itself is returned once on creation
+type Invitation struct {
+	ID             string     `json:"ID"`
+	CodeHash       string     `json:"-"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role" validate:"omitempty,oneof=user admin"`
+	ExpiresInHours int        `json:"expiresInHours,omitempty" validate:"min=0"`
+	CreatedBy      string     `json:"createdBy"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	ExpiresAt      time.Time  `json:"expiresAt"`
+	UsedBy         string     `json:"usedBy,omitempty"`
+	UsedAt         *time.Time `json:"usedAt,omitempty"`
+}
+
+// UserData is everything stored about a user, it is what users get when they export their account
+type UserData struct {
+	User          User
+	APIKeys       []APIKey
+	OAuthConsents []OAuthConsent
+	OAuthClients  []OAuthClient
+	Articles      []Article
+	Comments      []Comment
+	LikedArticles []string
+	Bookmarks     []string
+}
+
+// Audit log actions
+const (
+	AuditSignup           = "signup"
+	AuditLogin            = "login"
+	AuditTokenRefresh     = "token.refresh"
+	AuditPasswordReset    = "password.reset"
+	AuditArticleCreate    = "article.create"
+	AuditArticleUpdate    = "article.update"
+	AuditArticleDelete    = "article.delete"
+	AuditCommentCreate    = "comment.create"
+	AuditCommentUpdate    = "comment.update"
+	AuditCommentDelete    = "comment.delete"
+	AuditArticleShare     = "article.share"
+	AuditArticleUnshare   = "article.unshare"
+	AuditArticleTransfer  = "article.transfer"
+	AuditArticleUnlock    = "article.unlock"
+	AuditTagRename        = "tag.rename"
+	AuditTagMerge         = "tag.merge"
+	AuditTagDelete        = "tag.delete"
+	AuditCategoryCreate   = "category.create"
+	AuditCategoryRename   = "category.rename"
+	AuditCategoryMove     = "category.move"
+	AuditCategoryDelete   = "category.delete"
+	AuditAttachmentUpload = "attachment.upload"
+	AuditAttachmentDelete = "attachment.delete"
+	AuditUserDisable      = "user.disable"
+	AuditUserEnable       = "user.enable"
+	AuditUserRoleChange   = "user.role_change"
+	AuditUserForceReset   = "user.force_reset"
+	AuditUserDelete       = "user.delete"
+	AuditAccountDelete    = "account.delete"
+)
+
+// Audit log outcomes
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEvent is an entry of the append-only audit log, Reason tells why an action failed
+type AuditEvent struct {
+	ID      string    `json:"ID"`
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`
+	Action  string    `json:"action"`
+	Target  string    `json:"target"`
+	IP      string    `json:"ip"`
+	Outcome string    `json:"outcome"`
+	Reason  string    `json:"reason,omitempty"`
+}
+
+// AuditFilter selects audit events, empty fields match every event
+type AuditFilter struct {
+	Actor   string
+	Action  string
+	Target  string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+}
+
+// Matches reports whether the event is selected by the filter
+func (f *AuditFilter) Matches(event *AuditEvent) bool {
+	return (f.Actor == "" || f.Actor == event.Actor) &&
+		(f.Action == "" || f.Action == event.Action) &&
+		(f.Target == "" || f.Target == event.Target) &&
+		(f.Outcome == "" || f.Outcome == event.Outcome) &&
+		(f.Since.IsZero() || !event.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || event.Time.Before(f.Until))
+}
+
+// DailyViews is the number of views of an article or an author on a day(UTC, formatted as 2006-01-02)
+type DailyViews struct {
+	Day   string `json:"day"`
+	Views int    `json:"views"`
+}
+
+// ArticleViews is the number of views of an article in a period
+type ArticleViews struct {
+	ArticleID string `json:"articleID"`
+	Title     string `json:"title"`
+	Views     int    `json:"views"`
+}
+
+// TagViews is the number of views of the articles with a tag in a period
+type TagViews struct {
+	Tag   string `json:"tag"`
+	Views int    `json:"views"`
+}
+
+// ViewStats are the views of an article or of all the articles of an author in a period, TopArticles
+// and TopTags are only set for authors
+type ViewStats struct {
+	From        string         `json:"from"`
+	To          string         `json:"to"`
+	TotalViews  int            `json:"totalViews"`
+	Daily       []DailyViews   `json:"daily"`
+	TopArticles []ArticleViews `json:"topArticles,omitempty"`
+	TopTags     []TagViews     `json:"topTags,omitempty"`
 }
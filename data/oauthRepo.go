@@ -0,0 +1,122 @@
+package data
+
+import (
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var oauthClients = make(map[string]OAuthClient)
+var oauthConsents = make(map[string]OAuthConsent)
+
+// consentTokens maps the consents, keyed like oauthConsents, to the jti and expiry of the access tokens issued
+// under them, so withdrawing a consent revokes the tokens of the client
+var consentTokens = make(map[string]map[string]time.Time)
+
+// revokedTokens maps the jti of revoked access tokens to their expiry, after which they no longer need to be kept
+var revokedTokens = make(map[string]time.Time)
+
+// registers a new oauth client
+func (repo *Repo) CreateOAuthClient(client *OAuthClient) (*OAuthClient, error) {
+	repo.logger.Info("creating oauth client", "owner", client.Owner, "name", client.Name)
+	client.ID = uuid.NewV4().String()
+	client.CreatedAt = time.Now()
+	oauthClients[client.ID] = *client
+	return client, nil
+}
+
+// get an oauth client by ID
+func (repo *Repo) GetOAuthClient(clientID string) (*OAuthClient, error) {
+	if client, exists := oauthClients[clientID]; exists {
+		return &client, nil
+	}
+	return nil, errors.New(utils.ErrOAuthClientNotFound)
+}
+
+// lists the oauth clients registered by a user
+func (repo *Repo) GetOAuthClientsByOwner(owner string) []OAuthClient {
+	repo.logger.Info("fetching oauth clients")
+	result := []OAuthClient{}
+	for _, client := range oauthClients {
+		if client.Owner == owner {
+			result = append(result, client)
+		}
+	}
+	return result
+}
+
+// creates or replaces the consent of a user for a client
+func (repo *Repo) SaveOAuthConsent(consent *OAuthConsent) error {
+	repo.logger.Info("saving oauth consent", "user", consent.UserID, "client", consent.ClientID)
+	consent.GrantedAt = time.Now()
+	oauthConsents[consent.UserID+"|"+consent.ClientID] = *consent
+	return nil
+}
+
+// get the consent of a user for a client
+func (repo *Repo) GetOAuthConsent(userID string, clientID string) (*OAuthConsent, error) {
+	if consent, exists := oauthConsents[userID+"|"+clientID]; exists {
+		return &consent, nil
+	}
+	return nil, errors.New(utils.ErrOAuthConsentNotFound)
+}
+
+// lists the consents given by a user
+func (repo *Repo) GetOAuthConsentsByUser(userID string) []OAuthConsent {
+	result := []OAuthConsent{}
+	for _, consent := range oauthConsents {
+		if consent.UserID == userID {
+			result = append(result, consent)
+		}
+	}
+	return result
+}
+
+// records an access token issued to a client under the consent of a user
+func (repo *Repo) AddConsentToken(userID string, clientID string, jti string, expiresAt time.Time) {
+	key := userID + "|" + clientID
+	now := time.Now()
+	for id, exp := range consentTokens[key] {
+		if now.After(exp) {
+			delete(consentTokens[key], id)
+		}
+	}
+	if consentTokens[key] == nil {
+		consentTokens[key] = make(map[string]time.Time)
+	}
+	consentTokens[key][jti] = expiresAt
+}
+
+// deletes the consent of a user for a client and revokes the access tokens issued under it
+func (repo *Repo) DeleteOAuthConsent(userID string, clientID string) error {
+	repo.logger.Info("deleting oauth consent", "user", userID, "client", clientID)
+	if _, exists := oauthConsents[userID+"|"+clientID]; exists {
+		delete(oauthConsents, userID+"|"+clientID)
+		for jti, expiresAt := range consentTokens[userID+"|"+clientID] {
+			repo.RevokeToken(jti, expiresAt)
+		}
+		delete(consentTokens, userID+"|"+clientID)
+		return nil
+	}
+	return errors.New(utils.ErrOAuthConsentNotFound)
+}
+
+// marks the token with the given jti as revoked until it expires
+func (repo *Repo) RevokeToken(jti string, expiresAt time.Time) {
+	repo.logger.Info("revoking token", "jti", jti)
+	now := time.Now()
+	for id, exp := range revokedTokens {
+		if now.After(exp) {
+			delete(revokedTokens, id)
+		}
+	}
+	revokedTokens[jti] = expiresAt
+}
+
+// checks whether the token with the given jti was revoked
+func (repo *Repo) IsTokenRevoked(jti string) bool {
+	_, revoked := revokedTokens[jti]
+	return revoked
+}
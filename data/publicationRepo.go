@@ -0,0 +1,90 @@
+package data
+
+import (
+	"sort"
+	"time"
+)
+
+// articlesChangedAt is the last time an article was written or deleted, it only moves forward
+var articlesChangedAt = time.Now()
+
+// articlesChanged records that an article was written or deleted
+func articlesChanged() {
+	if now := time.Now(); now.After(articlesChangedAt) {
+		articlesChangedAt = now
+	}
+}
+
+// LastArticleChange returns the last time an article was written or deleted, or when the repository was created.
+// Unlike the UpdatedAt of the articles it never moves back when the latest article is deleted or unpublished
+func (repo *Repo) LastArticleChange() time.Time {
+	return articlesChangedAt
+}
+
+// PublishedFilter narrows the published articles down to the ones with Tag and by the author with the email
+// Author, empty fields match every article
+type PublishedFilter struct {
+	Tag    string
+	Author string
+}
+
+// publish sets the status of an article, an empty status keeps the current one. PublishedAt is the first time
+// the article was published, unpublishing and publishing again keeps it
+func publish(article *Article, status string) {
+	if status != "" {
+		article.Status = status
+	} else if article.Status == "" {
+		article.Status = StatusPublished
+	}
+	if article.Published() && article.PublishedAt == nil {
+		now := time.Now()
+		article.PublishedAt = &now
+	}
+}
+
+// fetchs a page of the published articles matching the filter, the most recently published first, with the
+// number of matching articles. A pageSize of 0 fetchs all of them
+func (repo *Repo) GetPublishedArticles(filter PublishedFilter, pageNumber int, pageSize int) ([]Article, int) {
+	repo.logger.Info("fetching published articles", "tag", filter.Tag, "author", filter.Author)
+	// old names of renamed and merged tags find the articles of the tag they became
+	tag := ""
+	if tags := normalizeTags([]string{filter.Tag}); len(tags) > 0 {
+		tag = tags[0]
+	}
+	result := []Article{}
+	for _, article := range articles {
+		if !article.Published() || (filter.Author != "" && article.Author != filter.Author) {
+			continue
+		}
+		if filter.Tag != "" && !hasTag(article.Tags, tag) {
+			continue
+		}
+		result = append(result, withDetails(article))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].PublicationTime().After(result[j].PublicationTime())
+	})
+
+	total := len(result)
+	if pageSize == 0 {
+		return result, total
+	}
+	start := (pageNumber - 1) * pageSize
+	if pageNumber < 1 || start >= total {
+		return []Article{}, total
+	}
+	stop := start + pageSize
+	if stop > total {
+		stop = total
+	}
+	return result[start:stop], total
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
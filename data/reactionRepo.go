@@ -0,0 +1,93 @@
+package data
+
+import (
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"sort"
+	"time"
+)
+
+// likes and bookmarks map an article ID to the users who liked or bookmarked it and when
+var likes = make(map[string]map[string]time.Time)
+var bookmarks = make(map[string]map[string]time.Time)
+
+// likes an article, liking it again keeps the first like
+func (repo *Repo) LikeArticle(userID string, articleID string) (*Article, error) {
+	return repo.react(likes, userID, articleID, true)
+}
+
+// removes the like of the user from an article, if any
+func (repo *Repo) UnlikeArticle(userID string, articleID string) (*Article, error) {
+	return repo.react(likes, userID, articleID, false)
+}
+
+// saves an article to the bookmarks of the user
+func (repo *Repo) BookmarkArticle(userID string, articleID string) (*Article, error) {
+	return repo.react(bookmarks, userID, articleID, true)
+}
+
+// removes an article from the bookmarks of the user, if it is there
+func (repo *Repo) RemoveBookmark(userID string, articleID string) (*Article, error) {
+	return repo.react(bookmarks, userID, articleID, false)
+}
+
+// adds or removes the reaction of a user to an article, both are idempotent
+func (repo *Repo) react(reactions map[string]map[string]time.Time, userID string, articleID string, add bool) (*Article, error) {
+	article, exists := articles[articleID]
+	if !exists {
+		return nil, errors.New(utils.ErrArticleNotFound)
+	}
+	repo.logger.Info("updating reaction", "article", articleID, "user", userID, "add", add)
+	if add {
+		if reactions[articleID] == nil {
+			reactions[articleID] = make(map[string]time.Time)
+		}
+		if _, exists := reactions[articleID][userID]; !exists {
+			reactions[articleID][userID] = time.Now()
+		}
+	} else {
+		delete(reactions[articleID], userID)
+	}
+	article = withDetails(article)
+	return &article, nil
+}
+
+// fetches one page of the published articles bookmarked by the user, most recently bookmarked first
+func (repo *Repo) GetBookmarks(userID string, pageNumber int, pageSize int) ([]Article, int, error) {
+	repo.logger.Info("fetching bookmarks", "user", userID)
+	bookmarkedAt := make(map[string]time.Time)
+	result := []Article{}
+	for articleID, users := range bookmarks {
+		if at, exists := users[userID]; exists {
+			if article := articles[articleID]; article.Published() {
+				bookmarkedAt[articleID] = at
+				result = append(result, withDetails(article))
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return bookmarkedAt[result[i].ID].After(bookmarkedAt[result[j].ID])
+	})
+
+	start := (pageNumber - 1) * pageSize
+	if pageNumber < 1 || (start >= len(result) && pageNumber != 1) {
+		return nil, 0, errors.New(utils.ErrInvalidPageNumber)
+	}
+	stop := start + pageSize
+	if stop > len(result) {
+		stop = len(result)
+	}
+	return result[start:stop], len(result), nil
+}
+
+// lists the IDs of the articles the user reacted to
+func reactedArticles(reactions map[string]map[string]time.Time, userID string) []string {
+	result := []string{}
+	for articleID, users := range reactions {
+		if _, exists := users[userID]; exists {
+			result = append(result, articleID)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
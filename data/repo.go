@@ -3,7 +3,11 @@ package data
 import (
 	"MohsenArabi/ArticleManagementSystem/utils"
 	"errors"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/hashicorp/go-hclog"
 	uuid "github.com/satori/go.uuid"
@@ -11,7 +15,6 @@ import (
 
 var users = make(map[string]User)
 var articles = make(map[string]Article)
-var tags []string
 
 // Repo has the implementation of the in memory repository.
 type Repo struct {
@@ -23,16 +26,106 @@ func NewRepo(logger hclog.Logger) *Repo {
 	return &Repo{logger}
 }
 
-//creates a new user
+//creates a new user, users without a username get one derived from their email
 func (repo *Repo) Create(user *User) error {
-	if _, exists := users[user.Email]; exists {
+	if _, exists := users[user.Email]; exists || user.Email == TombstoneAuthor {
 		repo.logger.Info(utils.ErrUserAlreadyExists)
 		return errors.New(utils.ErrUserAlreadyExists)
-	} else {
-		repo.logger.Info("creating user", hclog.Fmt("%#v", user))
-		users[user.Email] = *user
-		return nil
 	}
+	if user.Username == "" {
+		user.Username = uniqueUsername(user.Email)
+	} else if usernameTaken(user.Username, user.Email) {
+		repo.logger.Info(utils.ErrUsernameTaken)
+		return errors.New(utils.ErrUsernameTaken)
+	}
+	if user.Role == "" {
+		user.Role = RoleUser
+	}
+	user.CreatedAt = time.Now()
+	repo.logger.Info("creating user", hclog.Fmt("%#v", user))
+	users[user.Email] = *user
+	return nil
+}
+
+//get user struct by username, usernames are case insensitive
+func (repo *Repo) GetUserByUsername(username string) (*User, error) {
+	repo.logger.Debug("searching for user with username", username)
+	for _, u := range users {
+		if strings.EqualFold(u.Username, username) {
+			return &u, nil
+		}
+	}
+	return nil, errors.New(utils.ErrAuthorNotFound)
+}
+
+//updates the public profile of a user
+func (repo *Repo) UpdateProfile(email string, profile *Profile) (*User, error) {
+	user, exists := users[email]
+	if !exists {
+		return nil, errors.New(utils.ErrUserNotFound)
+	}
+	if usernameTaken(profile.Username, email) {
+		return nil, errors.New(utils.ErrUsernameTaken)
+	}
+	repo.logger.Info("updating profile", "email", email)
+	user.Username = profile.Username
+	user.DisplayName = profile.DisplayName
+	user.Bio = profile.Bio
+	user.AvatarURL = profile.AvatarURL
+	users[email] = user
+	return &user, nil
+}
+
+//checks whether another user already has the username
+func usernameTaken(username string, email string) bool {
+	for _, u := range users {
+		if u.Email != email && strings.EqualFold(u.Username, username) {
+			return true
+		}
+	}
+	return false
+}
+
+//derives a free username from the local part of the email
+func uniqueUsername(email string) string {
+	base := strings.Builder{}
+	for _, r := range strings.Split(email, "@")[0] {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			base.WriteRune(unicode.ToLower(r))
+		}
+	}
+	username := base.String()
+	for len(username) < 3 {
+		username += "user"
+	}
+	if len(username) > 24 {
+		username = username[:24]
+	}
+	candidate := username
+	for i := 2; usernameTaken(candidate, email); i++ {
+		candidate = username + strconv.Itoa(i)
+	}
+	return candidate
+}
+
+//sets the fields of an article read from the store that are derived from other data,
+//the public author information and the number of comments, likes and bookmarks
+func withDetails(article Article) Article {
+	article.AuthorProfile = authorProfile(article.Author)
+	article.CommentCount = commentCounts[article.ID]
+	article.LikeCount = len(likes[article.ID])
+	article.BookmarkCount = len(bookmarks[article.ID])
+	return article
+}
+
+//returns the public information of the author with the given email
+func authorProfile(email string) *ArticleAuthor {
+	if u, exists := users[email]; exists {
+		return &ArticleAuthor{Username: u.Username, DisplayName: u.DisplayName, AvatarURL: u.AvatarURL}
+	} else if email == TombstoneAuthor {
+		return &ArticleAuthor{DisplayName: "Deleted user"}
+	}
+	return nil
 }
 
 //get user struct by email
@@ -47,25 +140,178 @@ func (repo *Repo) GetUserByEmail(email string) (*User, error) {
 	}
 }
 
+//updates an existing user
+func (repo *Repo) UpdateUser(user *User) error {
+	if _, exists := users[user.Email]; exists {
+		repo.logger.Info("updating user", "email", user.Email)
+		users[user.Email] = *user
+		return nil
+	} else {
+		return errors.New(utils.ErrUserNotFound)
+	}
+}
+
+//fetchs one page of the users matching the query (email, username or display name), ordered by email.
+//It also returns the total number of matching users
+func (repo *Repo) ListUsers(query string, pageNumber int, pageSize int) ([]User, int, error) {
+	repo.logger.Info("listing users")
+	query = strings.ToLower(query)
+	result := []User{}
+	for _, u := range users {
+		if query == "" || strings.Contains(strings.ToLower(u.Email), query) ||
+			strings.Contains(strings.ToLower(u.Username), query) || strings.Contains(strings.ToLower(u.DisplayName), query) {
+			result = append(result, u)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Email < result[j].Email
+	})
+
+	start := (pageNumber - 1) * pageSize
+	if pageNumber < 1 || (start >= len(result) && pageNumber != 1) {
+		return nil, 0, errors.New(utils.ErrInvalidPageNumber)
+	}
+	stop := start + pageSize
+	if stop > len(result) {
+		stop = len(result)
+	}
+	return result[start:stop], len(result), nil
+}
+
+//deletes a user with everything that authenticates as them. The articles of the user are
+//reassigned to another user or to the TombstoneAuthor when reassignArticlesTo is set, otherwise they are deleted
+func (repo *Repo) DeleteUser(email string, reassignArticlesTo string) error {
+	if _, exists := users[email]; !exists {
+		return errors.New(utils.ErrUserNotFound)
+	}
+	if reassignArticlesTo != "" && reassignArticlesTo != TombstoneAuthor {
+		if _, exists := users[reassignArticlesTo]; !exists || reassignArticlesTo == email {
+			return errors.New(utils.ErrReassignUserNotFound)
+		}
+	}
+
+	repo.logger.Info("deleting user", "email", email, "reassignArticlesTo", reassignArticlesTo)
+	for id, article := range articles {
+		if article.Author != email {
+			continue
+		}
+		if reassignArticlesTo != "" {
+			article.Author = reassignArticlesTo
+			articles[id] = article
+			articlesChanged()
+			delete(contributors[id], reassignArticlesTo)
+		} else {
+			deleteArticleData(id)
+		}
+	}
+	removeContributions(email)
+	releaseEditLocks(email)
+	// series follow the articles of the user
+	for id, found := range series {
+		if found.Owner != email {
+			continue
+		}
+		if reassignArticlesTo != "" {
+			found.Owner = reassignArticlesTo
+			series[id] = found
+		} else {
+			delete(series, id)
+		}
+	}
+	for _, reactions := range []map[string]map[string]time.Time{likes, bookmarks} {
+		for _, users := range reactions {
+			delete(users, email)
+		}
+	}
+	// comments are part of the threads of other users, they are kept under the tombstone author
+	for id, comment := range comments {
+		if comment.Author == email {
+			comment.Author = TombstoneAuthor
+			comments[id] = comment
+		}
+	}
+	for id, key := range apiKeys {
+		if key.UserID == email {
+			delete(apiKeys, id)
+		}
+	}
+	for id, consent := range oauthConsents {
+		if consent.UserID == email {
+			delete(oauthConsents, id)
+		}
+	}
+	for id, client := range oauthClients {
+		if client.Owner == email {
+			delete(oauthClients, id)
+		}
+	}
+	// invitations are kept for the other admins, without the email of the deleted user
+	for id, invitation := range invitations {
+		if invitation.CreatedBy == email {
+			invitation.CreatedBy = TombstoneAuthor
+		}
+		if invitation.UsedBy == email {
+			invitation.UsedBy = TombstoneAuthor
+		}
+		if strings.EqualFold(invitation.Email, email) {
+			invitation.Email = ""
+		}
+		invitations[id] = invitation
+	}
+	delete(users, email)
+	return nil
+}
+
 // creates new article
 func (repo *Repo) CreateArticle(article *Article) (*Article, error) {
 	repo.logger.Info("creating article")
+	if _, exists := categories[article.CategoryID]; article.CategoryID != "" && !exists {
+		return nil, errors.New(utils.ErrCategoryNotFound)
+	}
 	article.ID = uuid.NewV4().String()
 	article.CreatedAt = time.Now()
 	article.UpdatedAt = time.Now()
+	article.AuthorProfile = nil
+	article.Series = nil
+	article.EditLock = nil
+	article.SEO = nil
+	article.Tags = normalizeTags(article.Tags)
+	countTags(article.Tags, 1)
+	assignSlug(article)
+	article.PublishedAt = nil
+	publish(article, article.Status)
 	articles[article.ID] = *article
+	articlesChanged()
+	*article = withDetails(*article)
 	return article, nil
 }
 
-//updates only title, content and tags of article
+//updates only title, content, tags and category of article, a new title gives the article a new slug
 func (repo *Repo) UpdateArticle(newArticle *Article) (*Article, error) {
 	repo.logger.Info("updating article")
 	if oldArticle, exists := articles[newArticle.ID]; exists {
+		if _, exists := categories[newArticle.CategoryID]; newArticle.CategoryID != "" && !exists {
+			return nil, errors.New(utils.ErrCategoryNotFound)
+		}
 		oldArticle.UpdatedAt = time.Now()
-		oldArticle.Title = newArticle.Title
+		if oldArticle.Title != newArticle.Title {
+			oldArticle.Title = newArticle.Title
+			assignSlug(&oldArticle)
+		}
 		oldArticle.Content = newArticle.Content
-		oldArticle.Tags = newArticle.Tags
+		oldArticle.Format = newArticle.Format
+		oldArticle.RenderedContent = newArticle.RenderedContent
+		oldArticle.Excerpt = newArticle.Excerpt
+		oldArticle.WordCount = newArticle.WordCount
+		oldArticle.ReadingTime = newArticle.ReadingTime
+		oldArticle.CategoryID = newArticle.CategoryID
+		publish(&oldArticle, newArticle.Status)
+		countTags(oldArticle.Tags, -1)
+		oldArticle.Tags = normalizeTags(newArticle.Tags)
+		countTags(oldArticle.Tags, 1)
 		articles[newArticle.ID] = oldArticle
+		articlesChanged()
+		oldArticle = withDetails(oldArticle)
 		return &oldArticle, nil
 
 	} else {
@@ -78,7 +324,7 @@ func (repo *Repo) UpdateArticle(newArticle *Article) (*Article, error) {
 func (repo *Repo) DeleteArticle(articleID string) error {
 	repo.logger.Info("deleting article")
 	if _, exists := articles[articleID]; exists {
-		delete(articles, articleID)
+		deleteArticleData(articleID)
 		return nil
 
 	} else {
@@ -87,60 +333,132 @@ func (repo *Repo) DeleteArticle(articleID string) error {
 
 }
 
-//fetchs only one page of articles
-func (repo *Repo) GetArticles(pageNumber int, pageSize int) ([]Article, error) {
-	repo.logger.Info(("fetching articles"))
+//deletes the article with everything stored for it, new data kept per article has to be deleted here
+func deleteArticleData(articleID string) {
+	article := articles[articleID]
+	delete(articles, articleID)
+	articlesChanged()
+	countTags(article.Tags, -1)
+	deleteSlugs(articleID)
+	removeFromSeries(articleID)
+	delete(contributors, articleID)
+	delete(editLocks, articleID)
+	deleteAttachments(articleID)
+	deleteComments(articleID)
+	delete(likes, articleID)
+	delete(bookmarks, articleID)
+	deleteViews(articleID)
+}
+
+//fetchs only one page of published articles, newest first or most liked first
+func (repo *Repo) GetArticles(pageNumber int, pageSize int, sortBy string) ([]Article, error) {
+	repo.logger.Info("fetching articles", "sort", sortBy)
+	//drafts are not listed
+	result := make([]Article, 0, len(articles))
+	for _, article := range articles {
+		if article.Published() {
+			result = append(result, withDetails(article))
+		}
+	}
+
 	start := (pageNumber - 1) * pageSize
 	stop := start + pageSize
 
-	if start >= len(articles) {
+	if pageNumber < 1 || start >= len(result) {
 		return nil, errors.New(utils.ErrInvalidPageNumber)
 	}
 
-	if stop > len(articles) {
-		stop = len(articles)
+	if stop > len(result) {
+		stop = len(result)
 	}
 	repo.logger.Debug("fetching articles from %v to %v", start, stop)
-	i := 0
-	var result []Article
-	for _, article := range articles {
-		if i >= start && i < stop {
-			result = append(result, article)
-		}
-		i++
-		if i >= stop {
-			break
-		}
-	}
-	return result, nil
+	sortArticles(result, sortBy)
+	return result[start:stop], nil
 
 }
 
+//sorts articles newest first or most liked first
+func sortArticles(result []Article, sortBy string) {
+	sort.Slice(result, func(i, j int) bool {
+		if sortBy == SortMostLiked && result[i].LikeCount != result[j].LikeCount {
+			return result[i].LikeCount > result[j].LikeCount
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+}
+
 //get an article by ID
 func (repo *Repo) GetArticleByID(articleID string) (Article, error) {
 	repo.logger.Info(("fetching article"))
 	article, exists := articles[articleID]
 	if exists {
-		return article, nil
+		return withDetails(article), nil
 	} else {
 		return article, errors.New(utils.ErrArticleNotFound)
 	}
 }
 
-//get all unique article tags
+//get the names of all tags in use, the most used first
 func (repo *Repo) GetArticlesTags() []string {
 	repo.logger.Info(("fetching article tags"))
-	tagsMap := make(map[string]struct{})
 	tags := []string{}
-	var empty struct{}
+	for _, tag := range repo.GetTags() {
+		tags = append(tags, tag.Name)
+	}
+	return tags
+
+}
+
+//fetchs one page of the published articles of an author, newest first
+func (repo *Repo) GetArticlesByAuthor(userID string, pageNumber int, pageSize int) ([]Article, error) {
+	repo.logger.Info("fetching articles of author")
+	result := []Article{}
 	for _, article := range articles {
-		for _, tag := range article.Tags {
-			if _, exists := tagsMap[tag]; !exists {
-				tagsMap[tag] = empty
-				tags = append(tags, tag)
-			}
+		if article.Author == userID && article.Published() {
+			result = append(result, withDetails(article))
 		}
 	}
-	return tags
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
 
+	start := (pageNumber - 1) * pageSize
+	if pageNumber < 1 || (start >= len(result) && pageNumber != 1) {
+		return nil, errors.New(utils.ErrInvalidPageNumber)
+	}
+	stop := start + pageSize
+	if stop > len(result) {
+		stop = len(result)
+	}
+	return result[start:stop], nil
+}
+
+//get everything stored about a user
+func (repo *Repo) GetUserData(email string) (*UserData, error) {
+	user, exists := users[email]
+	if !exists {
+		return nil, errors.New(utils.ErrUserNotFound)
+	}
+	repo.logger.Info("collecting user data", "email", email)
+	userData := &UserData{User: user, APIKeys: repo.GetAPIKeysByUser(email), OAuthConsents: repo.GetOAuthConsentsByUser(email), OAuthClients: repo.GetOAuthClientsByOwner(email), Articles: []Article{}}
+	for _, article := range articles {
+		if article.Author == email {
+			userData.Articles = append(userData.Articles, withDetails(article))
+		}
+	}
+	sort.Slice(userData.Articles, func(i, j int) bool {
+		return userData.Articles[i].CreatedAt.Before(userData.Articles[j].CreatedAt)
+	})
+	userData.LikedArticles = reactedArticles(likes, email)
+	userData.Bookmarks = reactedArticles(bookmarks, email)
+	userData.Comments = []Comment{}
+	for _, comment := range comments {
+		if comment.Author == email {
+			userData.Comments = append(userData.Comments, withCommentDetails(comment))
+		}
+	}
+	sort.Slice(userData.Comments, func(i, j int) bool {
+		return userData.Comments[i].CreatedAt.Before(userData.Comments[j].CreatedAt)
+	})
+	return userData, nil
 }
@@ -1,13 +1,95 @@
 package data
 
+import "time"
+
 // Repository is an interface for the storage implementation of services
 type Repository interface {
 	Create(user *User) error
 	GetUserByEmail(email string) (*User, error)
+	UpdateUser(user *User) error
+	GetUserByUsername(username string) (*User, error)
+	UpdateProfile(email string, profile *Profile) (*User, error)
+	ListUsers(query string, pageNumber int, pageSize int) ([]User, int, error)
+	DeleteUser(email string, reassignArticlesTo string) error
+	GetUserData(email string) (*UserData, error)
 	CreateArticle(article *Article) (*Article, error)
 	UpdateArticle(article *Article) (*Article, error)
 	DeleteArticle(articleID string) error
-	GetArticles(pageNumber int, pagesize int) ([]Article, error)
+	GetArticles(pageNumber int, pagesize int, sortBy string) ([]Article, error)
 	GetArticleByID(articleID string) (Article, error)
+	GetArticleBySlug(slug string) (Article, error)
 	GetArticlesTags() []string
+	GetTags() []Tag
+	SearchTags(prefix string, limit int) []Tag
+	RenameTag(name string, newName string) (*Tag, error)
+	MergeTags(sources []string, target string) (*Tag, error)
+	DeleteTag(name string) error
+	CreateCategory(category *Category) (*Category, error)
+	GetCategoryByID(categoryID string) (*Category, error)
+	GetCategoryTree() []CategoryNode
+	RenameCategory(categoryID string, name string) (*Category, error)
+	MoveCategory(categoryID string, parentID string) (*Category, error)
+	DeleteCategory(categoryID string) error
+	CreateSeries(series *Series) (*Series, error)
+	GetSeriesByID(seriesID string) (*Series, error)
+	UpdateSeries(series *Series) (*Series, error)
+	DeleteSeries(seriesID string) error
+	AddArticleToSeries(seriesID string, articleID string, position int) (*Series, error)
+	RemoveArticleFromSeries(seriesID string, articleID string) (*Series, error)
+	ReorderSeries(seriesID string, articleIDs []string) (*Series, error)
+	GetSeriesNavigation(articleID string) *SeriesNavigation
+	GetArticlePermission(articleID string, userID string) string
+	GetContributors(articleID string) ([]Contributor, error)
+	SetContributor(articleID string, userID string, permission string) (*Contributor, error)
+	RemoveContributor(articleID string, userID string) error
+	TransferOwnership(articleID string, userID string) (*Article, error)
+	AcquireEditLock(articleID string, userID string, ttl time.Duration) (*EditLock, error)
+	GetEditLock(articleID string) *EditLock
+	ReleaseEditLock(articleID string, userID string, force bool) error
+	CreateAttachment(attachment *Attachment) (*Attachment, error)
+	GetAttachmentByID(attachmentID string) (*Attachment, error)
+	GetAttachments(articleID string) ([]Attachment, error)
+	SetAttachmentVariants(attachmentID string, variants []AttachmentVariant) error
+	DeleteAttachment(attachmentID string) (*Attachment, error)
+	AddOrphanedBlobs(keys ...string)
+	TakeOrphanedBlobs() []string
+	GetArticlesByCategory(categoryID string, includeSubcategories bool, pageNumber int, pageSize int, sortBy string) ([]Article, int, error)
+	GetArticlesByAuthor(userID string, pageNumber int, pageSize int) ([]Article, error)
+	GetPublishedArticles(filter PublishedFilter, pageNumber int, pageSize int) ([]Article, int)
+	LastArticleChange() time.Time
+	CreateAPIKey(key *APIKey) (*APIKey, error)
+	GetAPIKeysByUser(userID string) []APIKey
+	GetAPIKeyByHash(keyHash string) (*APIKey, error)
+	TouchAPIKey(keyID string, usedAt time.Time)
+	RevokeAPIKey(userID string, keyID string) error
+	CreateOAuthClient(client *OAuthClient) (*OAuthClient, error)
+	GetOAuthClient(clientID string) (*OAuthClient, error)
+	GetOAuthClientsByOwner(owner string) []OAuthClient
+	SaveOAuthConsent(consent *OAuthConsent) error
+	GetOAuthConsent(userID string, clientID string) (*OAuthConsent, error)
+	GetOAuthConsentsByUser(userID string) []OAuthConsent
+	DeleteOAuthConsent(userID string, clientID string) error
+	AddConsentToken(userID string, clientID string, jti string, expiresAt time.Time)
+	RevokeToken(jti string, expiresAt time.Time)
+	IsTokenRevoked(jti string) bool
+	CreateInvitation(invitation *Invitation) (*Invitation, error)
+	GetInvitations() []Invitation
+	GetValidInvitation(codeHash string, email string) (*Invitation, error)
+	ConsumeInvitation(invitationID string, usedBy string) error
+	DeleteInvitation(invitationID string) error
+	LikeArticle(userID string, articleID string) (*Article, error)
+	UnlikeArticle(userID string, articleID string) (*Article, error)
+	BookmarkArticle(userID string, articleID string) (*Article, error)
+	RemoveBookmark(userID string, articleID string) (*Article, error)
+	GetBookmarks(userID string, pageNumber int, pageSize int) ([]Article, int, error)
+	AddViews(articleID string, day string, views int)
+	GetArticleViewStats(articleID string, from time.Time, to time.Time) (*ViewStats, error)
+	GetAuthorViewStats(author string, from time.Time, to time.Time) *ViewStats
+	CreateComment(comment *Comment) (*Comment, error)
+	GetCommentByID(commentID string) (*Comment, error)
+	UpdateComment(comment *Comment) (*Comment, error)
+	DeleteComment(commentID string) error
+	GetComments(articleID string, pageNumber int, pageSize int) ([]Comment, int, error)
+	AppendAuditEvent(event *AuditEvent)
+	GetAuditEvents(filter AuditFilter) []AuditEvent
 }
@@ -0,0 +1,224 @@
+package data
+
+import (
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var series = make(map[string]Series)
+
+// creates a new series with the given articles in order
+func (repo *Repo) CreateSeries(newSeries *Series) (*Series, error) {
+	if err := checkSeriesArticles(newSeries.Owner, newSeries.ArticleIDs); err != nil {
+		return nil, err
+	}
+
+	repo.logger.Info("creating series", "owner", newSeries.Owner)
+	newSeries.ID = uuid.NewV4().String()
+	newSeries.CreatedAt = time.Now()
+	newSeries.UpdatedAt = newSeries.CreatedAt
+	newSeries.OwnerProfile = nil
+	if newSeries.ArticleIDs == nil {
+		newSeries.ArticleIDs = []string{}
+	}
+	series[newSeries.ID] = *newSeries
+	*newSeries = withOwnerProfile(*newSeries)
+	return newSeries, nil
+}
+
+// get a series by ID
+func (repo *Repo) GetSeriesByID(seriesID string) (*Series, error) {
+	found, exists := series[seriesID]
+	if !exists {
+		return nil, errors.New(utils.ErrSeriesNotFound)
+	}
+	found = withOwnerProfile(found)
+	return &found, nil
+}
+
+// updates only title and description of a series
+func (repo *Repo) UpdateSeries(newSeries *Series) (*Series, error) {
+	oldSeries, exists := series[newSeries.ID]
+	if !exists {
+		return nil, errors.New(utils.ErrSeriesNotFound)
+	}
+
+	repo.logger.Info("updating series", "id", newSeries.ID)
+	oldSeries.Title = newSeries.Title
+	oldSeries.Description = newSeries.Description
+	oldSeries.UpdatedAt = time.Now()
+	series[oldSeries.ID] = oldSeries
+	oldSeries = withOwnerProfile(oldSeries)
+	return &oldSeries, nil
+}
+
+// deletes a series, its articles are kept
+func (repo *Repo) DeleteSeries(seriesID string) error {
+	if _, exists := series[seriesID]; !exists {
+		return errors.New(utils.ErrSeriesNotFound)
+	}
+	repo.logger.Info("deleting series", "id", seriesID)
+	delete(series, seriesID)
+	return nil
+}
+
+// adds an article of the owner of the series at a position starting at 1, positions out of range add it at the end
+func (repo *Repo) AddArticleToSeries(seriesID string, articleID string, position int) (*Series, error) {
+	found, exists := series[seriesID]
+	if !exists {
+		return nil, errors.New(utils.ErrSeriesNotFound)
+	}
+	if err := checkSeriesArticles(found.Owner, []string{articleID}); err != nil {
+		return nil, err
+	}
+
+	articleIDs := make([]string, 0, len(found.ArticleIDs)+1)
+	if position < 1 || position > len(found.ArticleIDs) {
+		position = len(found.ArticleIDs) + 1
+	}
+	articleIDs = append(articleIDs, found.ArticleIDs[:position-1]...)
+	articleIDs = append(articleIDs, articleID)
+	articleIDs = append(articleIDs, found.ArticleIDs[position-1:]...)
+	return saveSeriesOrder(found, articleIDs), nil
+}
+
+// removes an article from a series
+func (repo *Repo) RemoveArticleFromSeries(seriesID string, articleID string) (*Series, error) {
+	found, exists := series[seriesID]
+	if !exists {
+		return nil, errors.New(utils.ErrSeriesNotFound)
+	}
+	articleIDs := withoutArticle(found.ArticleIDs, articleID)
+	if len(articleIDs) == len(found.ArticleIDs) {
+		return nil, errors.New(utils.ErrArticleNotInSeries)
+	}
+	return saveSeriesOrder(found, articleIDs), nil
+}
+
+// reorders the articles of a series, the new order must have exactly the articles of the series
+func (repo *Repo) ReorderSeries(seriesID string, articleIDs []string) (*Series, error) {
+	found, exists := series[seriesID]
+	if !exists {
+		return nil, errors.New(utils.ErrSeriesNotFound)
+	}
+	inSeries := make(map[string]bool)
+	for _, id := range found.ArticleIDs {
+		inSeries[id] = true
+	}
+	for _, id := range articleIDs {
+		if !inSeries[id] {
+			return nil, errors.New(utils.ErrInvalidSeriesOrder)
+		}
+		delete(inSeries, id)
+	}
+	if len(inSeries) != 0 || len(articleIDs) != len(found.ArticleIDs) {
+		return nil, errors.New(utils.ErrInvalidSeriesOrder)
+	}
+	return saveSeriesOrder(found, articleIDs), nil
+}
+
+// get the place of an article in its series with the previous and next articles, nil when it is not part of a series.
+// The other drafts of the series are left out as the navigation is shown to every reader of the article
+func (repo *Repo) GetSeriesNavigation(articleID string) *SeriesNavigation {
+	seriesID := seriesOf(articleID)
+	if seriesID == "" {
+		return nil
+	}
+	found := series[seriesID]
+	listed := []string{}
+	for _, id := range found.ArticleIDs {
+		if article := articles[id]; id == articleID || article.Published() {
+			listed = append(listed, id)
+		}
+	}
+	for i, id := range listed {
+		if id != articleID {
+			continue
+		}
+		navigation := &SeriesNavigation{SeriesID: found.ID, Title: found.Title, Position: i + 1, Total: len(listed)}
+		if i > 0 {
+			navigation.Previous = seriesEntry(listed[i-1])
+		}
+		if i < len(listed)-1 {
+			navigation.Next = seriesEntry(listed[i+1])
+		}
+		return navigation
+	}
+	return nil
+}
+
+// checkSeriesArticles checks that the articles exist, belong to the owner of the series and are not part of
+// another series or listed twice
+func checkSeriesArticles(owner string, articleIDs []string) error {
+	listed := make(map[string]bool)
+	for _, articleID := range articleIDs {
+		article, exists := articles[articleID]
+		if !exists {
+			return errors.New(utils.ErrArticleNotFound)
+		}
+		if article.Author != owner {
+			return errors.New(utils.ErrCantAddOthersArticle)
+		}
+		if listed[articleID] || seriesOf(articleID) != "" {
+			return errors.New(utils.ErrArticleInSeries)
+		}
+		listed[articleID] = true
+	}
+	return nil
+}
+
+// seriesOf returns the ID of the series of an article, or nothing
+func seriesOf(articleID string) string {
+	for id, found := range series {
+		for _, listedID := range found.ArticleIDs {
+			if listedID == articleID {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// saveSeriesOrder stores the new order of the articles of a series
+func saveSeriesOrder(found Series, articleIDs []string) *Series {
+	found.ArticleIDs = articleIDs
+	found.UpdatedAt = time.Now()
+	series[found.ID] = found
+	found = withOwnerProfile(found)
+	return &found
+}
+
+// seriesEntry returns the navigation entry of an article
+func seriesEntry(articleID string) *SeriesEntry {
+	article := articles[articleID]
+	return &SeriesEntry{ID: article.ID, Title: article.Title, Slug: article.Slug}
+}
+
+// withOwnerProfile sets the public information of the owner of a series
+func withOwnerProfile(found Series) Series {
+	found.OwnerProfile = authorProfile(found.Owner)
+	return found
+}
+
+// withoutArticle returns the article IDs without the article
+func withoutArticle(articleIDs []string, articleID string) []string {
+	result := make([]string, 0, len(articleIDs))
+	for _, id := range articleIDs {
+		if id != articleID {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// removeFromSeries removes a deleted article from its series
+func removeFromSeries(articleID string) {
+	if seriesID := seriesOf(articleID); seriesID != "" {
+		found := series[seriesID]
+		found.ArticleIDs = withoutArticle(found.ArticleIDs, articleID)
+		series[seriesID] = found
+	}
+}
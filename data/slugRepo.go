@@ -0,0 +1,108 @@
+package data
+
+import (
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// slugs maps the current and the previous slugs of the articles to their IDs, previous slugs are never
+// given to another article so shared links keep pointing to the same article
+var slugs = make(map[string]string)
+
+// maximum length of a slug before its collision suffix
+const maxSlugLength = 80
+
+// letters that do not decompose into an ASCII letter and a combining mark
+var transliterations = map[rune]string{
+	'\'': "", '’': "", 'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l", 'þ': "th", 'ı': "i",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i",
+	'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i", 'κ': "k",
+	'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t",
+	'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// get an article by its current or one of its previous slugs, the caller redirects when the slug of
+// the article is not the requested one
+func (repo *Repo) GetArticleBySlug(slug string) (Article, error) {
+	repo.logger.Info("fetching article", "slug", slug)
+	articleID, exists := slugs[strings.ToLower(slug)]
+	if !exists {
+		return Article{}, errors.New(utils.ErrArticleNotFound)
+	}
+	return repo.GetArticleByID(articleID)
+}
+
+// slugify turns a title into lowercase ASCII words separated by hyphens, accents are removed and
+// Cyrillic and Greek letters are transliterated
+func slugify(title string) string {
+	slug := strings.Builder{}
+	hyphen := false
+	for _, r := range strings.ToLower(title) {
+		word, exists := transliterations[r]
+		if !exists {
+			word = asciiBase(r)
+		}
+		if word == "" {
+			// separators end a word, dropped letters like apostrophes do not
+			hyphen = hyphen || (!exists && slug.Len() > 0)
+			continue
+		}
+		if hyphen {
+			slug.WriteByte('-')
+			hyphen = false
+		}
+		slug.WriteString(word)
+	}
+
+	result := slug.String()
+	if len(result) > maxSlugLength {
+		result = result[:maxSlugLength]
+		if i := strings.LastIndexByte(result, '-'); i > 0 {
+			result = result[:i]
+		}
+	}
+	if result == "" {
+		result = "article"
+	}
+	return result
+}
+
+// asciiBase returns the ASCII letter or digit left once the accents of the rune are removed, or nothing
+func asciiBase(r rune) string {
+	base := strings.Builder{}
+	for _, d := range norm.NFD.String(string(r)) {
+		if d < unicode.MaxASCII && (unicode.IsLetter(d) || unicode.IsDigit(d)) {
+			base.WriteRune(d)
+		}
+	}
+	return base.String()
+}
+
+// assignSlug gives the article a slug derived from its title, suffixed with a number when the slug
+// belongs to another article. The previous slug of the article keeps pointing to it
+func assignSlug(article *Article) {
+	base := slugify(article.Title)
+	candidate := base
+	for i := 2; slugs[candidate] != "" && slugs[candidate] != article.ID; i++ {
+		candidate = base + "-" + strconv.Itoa(i)
+	}
+	article.Slug = candidate
+	slugs[candidate] = article.ID
+}
+
+// deleteSlugs frees all the slugs of a deleted article
+func deleteSlugs(articleID string) {
+	for slug, id := range slugs {
+		if id == articleID {
+			delete(slugs, slug)
+		}
+	}
+}
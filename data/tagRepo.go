@@ -0,0 +1,204 @@
+package data
+
+import (
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// tagIndex has the tags in use with the number of their articles, it is updated whenever the tags of
+// an article change so listing tags does not scan the articles
+var tagIndex = make(map[string]Tag)
+
+// tagAliases maps the names of renamed and merged tags to the tag they became, so articles written
+// with an old name get the new one
+var tagAliases = make(map[string]string)
+
+// maximum length of a tag in characters
+const maxTagLength = 50
+
+// NormalizeTag returns the canonical form of a tag: lowercase words separated by hyphens. Letters, digits
+// and the symbols of names like c++, c# or node.js are kept, anything else separates words
+func NormalizeTag(tag string) string {
+	normalized := strings.Builder{}
+	hyphen := false
+	for _, r := range strings.ToLower(tag) {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '#' || r == '.') {
+			hyphen = normalized.Len() > 0
+			continue
+		}
+		if hyphen {
+			normalized.WriteByte('-')
+			hyphen = false
+		}
+		normalized.WriteRune(r)
+	}
+
+	runes := []rune(normalized.String())
+	if len(runes) > maxTagLength {
+		runes = runes[:maxTagLength]
+	}
+	return strings.Trim(string(runes), "-.")
+}
+
+// normalizeTags normalizes the tags of an article and resolves their aliases, empty and repeated tags are dropped
+func normalizeTags(articleTags []string) []string {
+	result := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range articleTags {
+		tag = NormalizeTag(tag)
+		if alias, exists := tagAliases[tag]; exists {
+			tag = alias
+		}
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// countTags adds delta to the article counts of the tags, tags without articles are removed
+func countTags(articleTags []string, delta int) {
+	for _, name := range articleTags {
+		tag, exists := tagIndex[name]
+		if !exists {
+			tag = Tag{Name: name, CreatedAt: time.Now()}
+		}
+		tag.Articles += delta
+		if tag.Articles > 0 {
+			tagIndex[name] = tag
+		} else {
+			delete(tagIndex, name)
+		}
+	}
+}
+
+// lists all tags in use with their article counts, the most used first
+func (repo *Repo) GetTags() []Tag {
+	result := make([]Tag, 0, len(tagIndex))
+	for _, tag := range tagIndex {
+		result = append(result, tag)
+	}
+	sortTags(result)
+	return result
+}
+
+// lists at most limit tags starting with the normalized prefix, the most used first
+func (repo *Repo) SearchTags(prefix string, limit int) []Tag {
+	prefix = NormalizeTag(prefix)
+	result := []Tag{}
+	for name, tag := range tagIndex {
+		if strings.HasPrefix(name, prefix) {
+			result = append(result, tag)
+		}
+	}
+	sortTags(result)
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// renames a tag on all its articles, renaming to a tag in use is a merge
+func (repo *Repo) RenameTag(name string, newName string) (*Tag, error) {
+	name, newName = NormalizeTag(name), NormalizeTag(newName)
+	if _, exists := tagIndex[name]; !exists {
+		return nil, errors.New(utils.ErrTagNotFound)
+	}
+	if newName == "" {
+		return nil, errors.New(utils.ErrInvalidTag)
+	}
+	if _, exists := tagIndex[newName]; exists && newName != name {
+		return nil, errors.New(utils.ErrTagExists)
+	}
+	return repo.MergeTags([]string{name}, newName)
+}
+
+// merges the source tags into the target tag on all their articles, the source names become aliases of the target
+func (repo *Repo) MergeTags(sources []string, target string) (*Tag, error) {
+	target = NormalizeTag(target)
+	if target == "" {
+		return nil, errors.New(utils.ErrInvalidTag)
+	}
+	renames := make(map[string]string)
+	for _, source := range sources {
+		source = NormalizeTag(source)
+		if _, exists := tagIndex[source]; !exists {
+			return nil, errors.New(utils.ErrTagNotFound)
+		}
+		if source != target {
+			renames[source] = target
+		}
+	}
+
+	repo.logger.Info("merging tags", "sources", sources, "target", target)
+	delete(tagAliases, target)
+	replaceTags(renames)
+	for alias, name := range tagAliases {
+		if _, renamed := renames[name]; renamed {
+			tagAliases[alias] = target
+		}
+	}
+	for source := range renames {
+		tagAliases[source] = target
+	}
+	tag := tagIndex[target]
+	return &tag, nil
+}
+
+// deletes a tag from all its articles
+func (repo *Repo) DeleteTag(name string) error {
+	name = NormalizeTag(name)
+	if _, exists := tagIndex[name]; !exists {
+		return errors.New(utils.ErrTagNotFound)
+	}
+
+	repo.logger.Info("deleting tag", "tag", name)
+	replaceTags(map[string]string{name: ""})
+	for alias, target := range tagAliases {
+		if target == name {
+			delete(tagAliases, alias)
+		}
+	}
+	return nil
+}
+
+// replaceTags replaces the tags of all the articles by their new names, an empty name removes the tag. The changed
+// articles count as updated, so feeds and sitemaps pick up their new tags
+func replaceTags(renames map[string]string) {
+	now := time.Now()
+	for id, article := range articles {
+		changed := false
+		articleTags := make([]string, 0, len(article.Tags))
+		for _, tag := range article.Tags {
+			if newName, renamed := renames[tag]; renamed {
+				tag = newName
+				changed = true
+			}
+			articleTags = append(articleTags, tag)
+		}
+		if !changed {
+			continue
+		}
+		countTags(article.Tags, -1)
+		article.Tags = normalizeTags(articleTags)
+		countTags(article.Tags, 1)
+		article.UpdatedAt = now
+		articles[id] = article
+		articlesChanged()
+	}
+}
+
+// sortTags orders tags by their article counts, then by name
+func sortTags(result []Tag) {
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Articles != result[j].Articles {
+			return result[i].Articles > result[j].Articles
+		}
+		return result[i].Name < result[j].Name
+	})
+}
@@ -0,0 +1,133 @@
+package data
+
+import (
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DayFormat is the format of the days of the daily view buckets
+const DayFormat = "2006-01-02"
+
+// topViewsLimit is the number of top articles and tags in the statistics of an author
+const topViewsLimit = 10
+
+// dailyViews maps an article ID to its views per day. Unlike the other stores it is written by the
+// analytics worker in the background, so it is guarded by viewsMu
+var dailyViews = make(map[string]map[string]int)
+var viewsMu sync.RWMutex
+
+// adds views to the daily bucket of an article
+func (repo *Repo) AddViews(articleID string, day string, views int) {
+	viewsMu.Lock()
+	defer viewsMu.Unlock()
+	if dailyViews[articleID] == nil {
+		dailyViews[articleID] = make(map[string]int)
+	}
+	dailyViews[articleID][day] += views
+}
+
+// get the views of an article per day from the day of from to the day of to, both included
+func (repo *Repo) GetArticleViewStats(articleID string, from time.Time, to time.Time) (*ViewStats, error) {
+	if _, exists := articles[articleID]; !exists {
+		return nil, errors.New(utils.ErrArticleNotFound)
+	}
+	repo.logger.Info("fetching article views", "article", articleID)
+	views := articleViews([]string{articleID}, from, to)
+	return newViewStats(from, to, views[articleID]), nil
+}
+
+// get the views of all the articles of an author per day from the day of from to the day of to,
+// both included, with the most viewed articles and tags
+func (repo *Repo) GetAuthorViewStats(author string, from time.Time, to time.Time) *ViewStats {
+	repo.logger.Info("fetching author views", "author", author)
+	ids := []string{}
+	for id, article := range articles {
+		if article.Author == author {
+			ids = append(ids, id)
+		}
+	}
+	views := articleViews(ids, from, to)
+
+	total := make(map[string]int)
+	topArticles := []ArticleViews{}
+	tagViews := make(map[string]int)
+	for _, id := range ids {
+		articleTotal := 0
+		for day, count := range views[id] {
+			total[day] += count
+			articleTotal += count
+		}
+		if articleTotal == 0 {
+			continue
+		}
+		topArticles = append(topArticles, ArticleViews{ArticleID: id, Title: articles[id].Title, Views: articleTotal})
+		for _, tag := range articles[id].Tags {
+			tagViews[tag] += articleTotal
+		}
+	}
+
+	stats := newViewStats(from, to, total)
+	sort.Slice(topArticles, func(i, j int) bool {
+		if topArticles[i].Views != topArticles[j].Views {
+			return topArticles[i].Views > topArticles[j].Views
+		}
+		return topArticles[i].Title < topArticles[j].Title
+	})
+	if len(topArticles) > topViewsLimit {
+		topArticles = topArticles[:topViewsLimit]
+	}
+	stats.TopArticles = topArticles
+
+	stats.TopTags = []TagViews{}
+	for tag, count := range tagViews {
+		stats.TopTags = append(stats.TopTags, TagViews{Tag: tag, Views: count})
+	}
+	sort.Slice(stats.TopTags, func(i, j int) bool {
+		if stats.TopTags[i].Views != stats.TopTags[j].Views {
+			return stats.TopTags[i].Views > stats.TopTags[j].Views
+		}
+		return stats.TopTags[i].Tag < stats.TopTags[j].Tag
+	})
+	if len(stats.TopTags) > topViewsLimit {
+		stats.TopTags = stats.TopTags[:topViewsLimit]
+	}
+	return stats
+}
+
+// copies the daily views of the articles in the period
+func articleViews(articleIDs []string, from time.Time, to time.Time) map[string]map[string]int {
+	first, last := from.UTC().Format(DayFormat), to.UTC().Format(DayFormat)
+	viewsMu.RLock()
+	defer viewsMu.RUnlock()
+	result := make(map[string]map[string]int)
+	for _, id := range articleIDs {
+		result[id] = make(map[string]int)
+		for day, count := range dailyViews[id] {
+			if day >= first && day <= last {
+				result[id][day] = count
+			}
+		}
+	}
+	return result
+}
+
+// lists every day of the period with its views, also the days without views
+func newViewStats(from time.Time, to time.Time, views map[string]int) *ViewStats {
+	stats := &ViewStats{From: from.UTC().Format(DayFormat), To: to.UTC().Format(DayFormat), Daily: []DailyViews{}}
+	for day := from.UTC(); day.Format(DayFormat) <= stats.To; day = day.AddDate(0, 0, 1) {
+		count := views[day.Format(DayFormat)]
+		stats.Daily = append(stats.Daily, DailyViews{Day: day.Format(DayFormat), Views: count})
+		stats.TotalViews += count
+	}
+	return stats
+}
+
+// deletes the views of an article
+func deleteViews(articleID string) {
+	viewsMu.Lock()
+	defer viewsMu.Unlock()
+	delete(dailyViews, articleID)
+}
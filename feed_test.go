@@ -0,0 +1,310 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// the elements the feeds are validated against, with the namespaces readers expect
+type parsedRSS struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel struct {
+		// the namespaced link comes first, the link of the channel would match it as well
+		Self struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"http://www.w3.org/2005/Atom link"`
+		Title         string `xml:"title"`
+		Link          string `xml:"link"`
+		Description   string `xml:"description"`
+		LastBuildDate string `xml:"lastBuildDate"`
+		Items         []struct {
+			Title   string `xml:"title"`
+			Link    string `xml:"link"`
+			GUID    string `xml:"guid"`
+			Creator string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+			PubDate string `xml:"pubDate"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type parsedAtom struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Links   []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Entries []struct {
+		ID        string `xml:"id"`
+		Title     string `xml:"title"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+		Author    string `xml:"author>name"`
+		Link      struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+func TestFeeds(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	fh := handlers.NewFeedHandler(logger, configs, repository)
+
+	repository.Create(&data.User{Email: "writer@feed.example.com", Username: "feedwriter", DisplayName: "Feed Writer", Password: "hash"})
+	published, _ := repository.CreateArticle(&data.Article{Title: "Feeds & <things>", Excerpt: "about feeds", Tags: []string{"feedtest"}, Author: "writer@feed.example.com"})
+	draft, _ := repository.CreateArticle(&data.Article{Title: "unfinished feed article", Tags: []string{"feedtest"}, Status: data.StatusDraft, Author: "writer@feed.example.com"})
+	if published.Status != data.StatusPublished || published.PublishedAt == nil || draft.PublishedAt != nil {
+		t.Fatalf("unexpected statuses %q %q", published.Status, draft.Status)
+	}
+
+	get := func(handler http.HandlerFunc, target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec := get(fh.RSSFeed, "/feed.rss?tag=FeedTest", nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/rss+xml") {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	rss := &parsedRSS{}
+	if err := xml.Unmarshal(rec.Body.Bytes(), rss); err != nil {
+		t.Fatal(err)
+	}
+	if rss.Version != "2.0" || rss.Channel.Title == "" || rss.Channel.Link == "" || rss.Channel.Description == "" ||
+		rss.Channel.Self.Rel != "self" || !strings.HasSuffix(rss.Channel.Self.Href, "/feed.rss?tag=FeedTest") {
+		t.Errorf("invalid channel %+v", rss.Channel)
+	}
+	if len(rss.Channel.Items) != 1 || rss.Channel.Items[0].Title != "Feeds & <things>" || rss.Channel.Items[0].Creator != "Feed Writer" ||
+		rss.Channel.Items[0].GUID != "urn:uuid:"+published.ID || !strings.HasSuffix(rss.Channel.Items[0].Link, "/Article/Slug/"+published.Slug) {
+		t.Fatalf("unexpected items %+v", rss.Channel.Items)
+	}
+	for _, date := range []string{rss.Channel.LastBuildDate, rss.Channel.Items[0].PubDate} {
+		if _, err := time.Parse(time.RFC1123Z, date); err != nil {
+			t.Errorf("invalid RSS date %q", date)
+		}
+	}
+
+	rec = get(fh.AtomFeed, "/feed.atom?author=feedwriter", nil)
+	atom := &parsedAtom{}
+	if err := xml.Unmarshal(rec.Body.Bytes(), atom); err != nil {
+		t.Fatal(err)
+	}
+	if atom.ID == "" || atom.Title != configs.SiteTitle+" - Feed Writer" || len(atom.Links) != 2 || atom.Links[0].Rel != "self" {
+		t.Errorf("invalid feed %+v", atom)
+	}
+	if len(atom.Entries) != 1 || atom.Entries[0].ID != "urn:uuid:"+published.ID || atom.Entries[0].Author != "Feed Writer" || atom.Entries[0].Link.Href == "" {
+		t.Fatalf("unexpected entries %+v", atom.Entries)
+	}
+	if updated, err := time.Parse(time.RFC3339, atom.Updated); err != nil || !updated.Equal(published.UpdatedAt.Truncate(time.Second)) {
+		t.Errorf("feed updated %q instead of the update of the article %v", atom.Updated, published.UpdatedAt)
+	}
+	for _, date := range []string{atom.Entries[0].Published, atom.Entries[0].Updated} {
+		if _, err := time.Parse(time.RFC3339, date); err != nil {
+			t.Errorf("invalid Atom date %q", date)
+		}
+	}
+	if rec := get(fh.AtomFeed, "/feed.atom?author=nobody-writes-this", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("feed of an unknown author: %d", rec.Code)
+	}
+
+	// readers polling the feed get 304 until an article of the feed changes
+	rec = get(fh.AtomFeed, "/feed.atom?tag=feedtest", nil)
+	etag, lastModified := rec.Header().Get("ETag"), rec.Header().Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("no validators %v", rec.Header())
+	}
+	if rec := get(fh.AtomFeed, "/feed.atom?tag=feedtest", http.Header{"If-None-Match": {etag}}); rec.Code != http.StatusNotModified {
+		t.Errorf("unchanged feed sent again: %d", rec.Code)
+	}
+	if rec := get(fh.AtomFeed, "/feed.atom?tag=feedtest", http.Header{"If-Modified-Since": {lastModified}}); rec.Code != http.StatusNotModified {
+		t.Errorf("unchanged feed sent again: %d", rec.Code)
+	}
+
+	// publishing the draft changes the feed, updating without a status keeps it published
+	time.Sleep(time.Second)
+	draft.Status = data.StatusPublished
+	repository.UpdateArticle(draft)
+	draft.Status = ""
+	if updated, _ := repository.UpdateArticle(draft); updated.Status != data.StatusPublished || updated.PublishedAt == nil {
+		t.Errorf("unexpected status %q", updated.Status)
+	}
+	rec = get(fh.AtomFeed, "/feed.atom?tag=feedtest", http.Header{"If-None-Match": {etag}, "If-Modified-Since": {lastModified}})
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), draft.ID) {
+		t.Errorf("published draft not in the feed: %d", rec.Code)
+	}
+
+	// deleting the newest article of the feed does not make its last modification go back
+	lastModified = rec.Header().Get("Last-Modified")
+	time.Sleep(time.Second)
+	repository.DeleteArticle(draft.ID)
+	rec = get(fh.AtomFeed, "/feed.atom?tag=feedtest", http.Header{"If-Modified-Since": {lastModified}})
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), draft.ID) {
+		t.Errorf("deleted article still in the feed: %d", rec.Code)
+	}
+}
+
+func TestDraftsOnlyReadableByContributors(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	ah := handlers.NewArticleHandler(logger, configs, data.NewValidation(), repository, service.NewArticleService(logger, configs), service.NewAnalyticsService(logger, configs, repository))
+
+	draft, _ := repository.CreateArticle(&data.Article{Title: "Secret draft of the API", Status: data.StatusDraft, Author: "writer@draft.example.com"})
+
+	byID := func(userID string) int {
+		req := asUser(httptest.NewRequest(http.MethodGet, "/Article/"+draft.ID, nil), userID)
+		rec := httptest.NewRecorder()
+		ah.GetArticle(rec, mux.SetURLVars(req, map[string]string{"articleID": draft.ID}))
+		return rec.Code
+	}
+	bySlug := func(userID string) int {
+		req := asUser(httptest.NewRequest(http.MethodGet, "/Article/Slug/"+draft.Slug, nil), userID)
+		rec := httptest.NewRecorder()
+		ah.GetArticleBySlug(rec, mux.SetURLVars(req, map[string]string{"slug": draft.Slug}))
+		return rec.Code
+	}
+	if code := byID("reader@draft.example.com"); code != http.StatusNotFound {
+		t.Errorf("draft fetched by ID by another user: %d", code)
+	}
+	if code := bySlug("reader@draft.example.com"); code != http.StatusNotFound {
+		t.Errorf("draft fetched by slug by another user: %d", code)
+	}
+	if code := byID("writer@draft.example.com"); code != http.StatusCreated {
+		t.Errorf("author can not fetch the draft: %d", code)
+	}
+	if code := bySlug("writer@draft.example.com"); code != http.StatusCreated {
+		t.Errorf("author can not fetch the draft by slug: %d", code)
+	}
+
+	for page := 1; ; page++ {
+		req := asUser(httptest.NewRequest(http.MethodGet, "/Article?pageid="+strconv.Itoa(page), nil), "reader@draft.example.com")
+		rec := httptest.NewRecorder()
+		ah.GetArticles(rec, req)
+		if rec.Code != http.StatusCreated {
+			break
+		}
+		response := struct{ Data []data.ArticleSummary }{}
+		json.NewDecoder(rec.Body).Decode(&response)
+		for _, summary := range response.Data {
+			if summary.ID == draft.ID {
+				t.Fatalf("draft listed on page %d", page)
+			}
+		}
+	}
+}
+
+func TestDraftsHiddenFromOtherRoutes(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	validator := data.NewValidation()
+	ah := handlers.NewArticleHandler(logger, configs, validator, repository, service.NewArticleService(logger, configs), service.NewAnalyticsService(logger, configs, repository))
+	cah := handlers.NewCategoryHandler(logger, configs, validator, repository)
+	sh := handlers.NewSeriesHandler(logger, configs, validator, repository)
+	ch := handlers.NewCommentHandler(logger, configs, validator, repository)
+	store := service.NewLocalBlobStore(logger, t.TempDir())
+	ath := handlers.NewAttachmentHandler(logger, configs, repository, service.NewAttachmentService(logger, configs, repository, store, service.NewImageService(logger, configs, repository, store)))
+
+	const writer, stranger = "writer@hidden.example.com", "stranger@hidden.example.com"
+	category, _ := repository.CreateCategory(&data.Category{Name: "Hidden drafts"})
+	published, _ := repository.CreateArticle(&data.Article{Title: "Public part", Content: "public words", CategoryID: category.ID, Author: writer})
+	draft, _ := repository.CreateArticle(&data.Article{Title: "Secret part", Content: "secret words", CategoryID: category.ID, Status: data.StatusDraft, Author: writer})
+	tutorial, _ := repository.CreateSeries(&data.Series{Title: "Hidden drafts series", Owner: writer, ArticleIDs: []string{published.ID, draft.ID}})
+	repository.CreateComment(&data.Comment{ArticleID: draft.ID, Content: "secret comment", Author: writer})
+	var picture bytes.Buffer
+	png.Encode(&picture, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	attachment, err := ath.AttachmentService.Upload(draft.ID, writer, "secret.png", &picture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bookmarked before the article went back to draft
+	repository.BookmarkArticle(stranger, draft.ID)
+
+	call := func(handler http.HandlerFunc, method string, target string, body string, userID string, vars map[string]string) *httptest.ResponseRecorder {
+		req := asUser(httptest.NewRequest(method, target, strings.NewReader(body)), userID)
+		rec := httptest.NewRecorder()
+		handler(rec, mux.SetURLVars(req, vars))
+		return rec
+	}
+	routes := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		body    string
+		vars    map[string]string
+	}{
+		{"category", cah.GetCategoryArticles, http.MethodGet, "/Categories/" + category.ID + "/Articles", "", map[string]string{"categoryID": category.ID}},
+		{"series", sh.GetSeries, http.MethodGet, "/Series/" + tutorial.ID, "", map[string]string{"seriesID": tutorial.ID}},
+		{"series navigation", ah.GetArticle, http.MethodGet, "/Article/" + published.ID, "", map[string]string{"articleID": published.ID}},
+		{"like", ah.LikeArticle, http.MethodGet, "/Article/Like/" + draft.ID, "", map[string]string{"articleID": draft.ID}},
+		{"bookmark", ah.BookmarkArticle, http.MethodGet, "/Article/Bookmark/" + draft.ID, "", map[string]string{"articleID": draft.ID}},
+		{"bookmarks", ah.GetBookmarks, http.MethodGet, "/Bookmarks?full=true", "", nil},
+		{"comments", ch.GetComments, http.MethodGet, "/Article/" + draft.ID + "/Comments", "", map[string]string{"articleID": draft.ID}},
+		{"comment", ch.CreateComment, http.MethodPost, "/Comment/Create", `{"articleID": "` + draft.ID + `", "content": "found it"}`, nil},
+		{"attachments", ath.GetAttachments, http.MethodGet, "/Article/" + draft.ID + "/Attachments", "", map[string]string{"articleID": draft.ID}},
+		{"attachment", ath.GetAttachment, http.MethodGet, "/Attachment/" + attachment.ID, "", map[string]string{"attachmentID": attachment.ID}},
+	}
+	for _, route := range routes {
+		rec := call(route.handler, route.method, route.target, route.body, stranger, route.vars)
+		if body := rec.Body.String(); strings.Contains(body, "Secret part") || strings.Contains(body, "secret") || strings.Contains(body, draft.ID) || strings.Contains(body, "found it") {
+			t.Errorf("%s: draft reached by a stranger: %d %s", route.name, rec.Code, body)
+		}
+	}
+
+	// the contributors still reach the draft
+	if rec := call(sh.GetSeries, http.MethodGet, "/Series/"+tutorial.ID, "", writer, map[string]string{"seriesID": tutorial.ID}); !strings.Contains(rec.Body.String(), "Secret part") {
+		t.Errorf("draft not listed in the series of its author")
+	}
+	if rec := call(ath.GetAttachment, http.MethodGet, "/Attachment/"+attachment.ID, "", writer, map[string]string{"attachmentID": attachment.ID}); rec.Code != http.StatusOK {
+		t.Errorf("author can not fetch the attachment of the draft: %d", rec.Code)
+	}
+
+	// reactions answer with the summary of the article, not its content
+	if rec := call(ah.LikeArticle, http.MethodGet, "/Article/Like/"+published.ID, "", stranger, map[string]string{"articleID": published.ID}); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "public words") {
+		t.Errorf("unexpected like response %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestInvalidFeedSizeFallsBackToDefault(t *testing.T) {
+	t.Setenv("FEED_SIZE", "-1")
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	if configs.FeedSize != 20 {
+		t.Fatalf("unexpected feed size %d", configs.FeedSize)
+	}
+
+	// the feed is written without panicking
+	fh := handlers.NewFeedHandler(logger, configs, data.NewRepo(logger))
+	rec := httptest.NewRecorder()
+	fh.RSSFeed(rec, httptest.NewRequest(http.MethodGet, "/feed.rss", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("unexpected response %d", rec.Code)
+	}
+}
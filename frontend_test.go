@@ -0,0 +1,131 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestFrontend(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	configs.FrontendEnabled = true
+	configs.FrontendPageSize = 2
+	repository := data.NewRepo(logger)
+	articleService := service.NewArticleService(logger, configs)
+	feh := handlers.NewFrontendHandler(logger, configs, repository)
+
+	create := func(article *data.Article) *data.Article {
+		article.Author = "writer@frontend.example.com"
+		articleService.RenderContent(article)
+		created, err := repository.CreateArticle(article)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return created
+	}
+	repository.Create(&data.User{Email: "writer@frontend.example.com", Username: "frontendwriter", DisplayName: "Front <End> Writer", Bio: "writes pages", Password: "hash"})
+	article := create(&data.Article{Title: "Rendered <script>alert(1)</script> page", Format: "markdown", Content: "Some **bold** text<script>alert(2)</script>", Tags: []string{"frontendtest"}})
+	draft := create(&data.Article{Title: "Frontend draft", Content: "not yet", Tags: []string{"frontendtest"}, Status: data.StatusDraft})
+	create(&data.Article{Title: "Second frontend page", Content: "more", Tags: []string{"frontendtest"}})
+	create(&data.Article{Title: "Third frontend page", Content: "even more", Tags: []string{"frontendtest"}})
+
+	get := func(handler http.HandlerFunc, target string, vars map[string]string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, target, nil), vars)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec := get(feh.Article, "/articles/"+article.Slug, map[string]string{"slug": article.Slug})
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	for _, expected := range []string{
+		"<strong>bold</strong>",
+		"Rendered &lt;script&gt;alert(1)&lt;/script&gt; page",
+		`<link rel="canonical" href="` + configs.PublicURL + "/articles/" + article.Slug + `">`,
+		`<meta property="og:type" content="article">`,
+		`<meta name="twitter:card" content="summary">`,
+		`<a href="/authors/frontendwriter">Front &lt;End&gt; Writer</a>`,
+		`<a href="/tags/frontendtest">`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("article page without %s", expected)
+		}
+	}
+	if strings.Contains(body, "<script>") {
+		t.Errorf("script in the article page")
+	}
+
+	if rec := get(feh.Article, "/articles/"+draft.Slug, map[string]string{"slug": draft.Slug}); rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "not yet") {
+		t.Errorf("draft rendered: %d", rec.Code)
+	}
+	if rec := get(feh.Article, "/articles/no-such-article", map[string]string{"slug": "no-such-article"}); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown article: %d", rec.Code)
+	}
+
+	// the three published articles are on two pages, the newest first
+	rec = get(feh.Tag, "/tags/FrontendTest", map[string]string{"tag": "FrontendTest"})
+	body = rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "Third frontend page") || strings.Contains(body, "Frontend draft") || strings.Contains(body, "Rendered") {
+		t.Errorf("unexpected first page %d", rec.Code)
+	}
+	if !strings.Contains(body, `<a rel="next" href="/tags/FrontendTest?page=2">`) {
+		t.Errorf("no link to the next page")
+	}
+	rec = get(feh.Tag, "/tags/frontendtest?page=2", map[string]string{"tag": "frontendtest"})
+	if body = rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, "Rendered") || !strings.Contains(body, `<a rel="prev" href="/tags/frontendtest">`) || strings.Contains(body, `rel="next"`) {
+		t.Errorf("unexpected second page %d", rec.Code)
+	}
+	for _, target := range []string{"/tags/frontendtest?page=3", "/tags/frontendtest?page=first"} {
+		if rec := get(feh.Tag, target, map[string]string{"tag": "frontendtest"}); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: %d", target, rec.Code)
+		}
+	}
+
+	rec = get(feh.Author, "/authors/frontendwriter", map[string]string{"username": "frontendwriter"})
+	if body = rec.Body.String(); rec.Code != http.StatusOK || !strings.Contains(body, "writes pages") || strings.Contains(body, "Frontend draft") {
+		t.Errorf("unexpected author page %d", rec.Code)
+	}
+	if rec := get(feh.Author, "/authors/nobody-writes-here", map[string]string{"username": "nobody-writes-here"}); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown author: %d", rec.Code)
+	}
+
+	if rec := get(feh.Home, "/", nil); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Third frontend page") {
+		t.Errorf("latest article not on the home page: %d", rec.Code)
+	}
+
+	// renamed articles are redirected to their current page
+	oldSlug := article.Slug
+	article.Title = "Renamed frontend page"
+	renamed, _ := repository.UpdateArticle(article)
+	if rec := get(feh.Article, "/articles/"+oldSlug, map[string]string{"slug": oldSlug}); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/articles/"+renamed.Slug {
+		t.Errorf("old slug not redirected: %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestInvalidFrontendPageSizeFallsBackToDefault(t *testing.T) {
+	t.Setenv("FRONTEND_PAGE_SIZE", "-1")
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	if configs.FrontendPageSize != 10 {
+		t.Fatalf("unexpected page size %d", configs.FrontendPageSize)
+	}
+
+	// the home page is rendered without panicking
+	feh := handlers.NewFrontendHandler(logger, configs, data.NewRepo(logger))
+	rec := httptest.NewRecorder()
+	feh.Home(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("unexpected response %d", rec.Code)
+	}
+}
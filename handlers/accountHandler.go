@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// AccountHandler wraps instances needed to answer the data requests of a user, exporting and erasing their account
+type AccountHandler struct {
+	logger      hclog.Logger
+	configs     *utils.Configurations
+	repo        data.Repository
+	authService service.Authentication
+}
+
+// NewAccountHandler returns a new AccountHandler instance
+func NewAccountHandler(l hclog.Logger, c *utils.Configurations, r data.Repository, auth service.Authentication) *AccountHandler {
+	return &AccountHandler{
+		logger:      l,
+		configs:     c,
+		repo:        r,
+		authService: auth,
+	}
+}
+
+// AccountExport is the account.json file of the export archive
+type AccountExport struct {
+	ExportedAt time.Time       `json:"exportedAt"`
+	Account    ExportedAccount `json:"account"`
+	Sessions   ExportedSession `json:"sessions"`
+	Articles   []data.Article  `json:"articles"`
+	Comments   []data.Comment  `json:"comments"`
+	Likes      []string        `json:"likedArticles"`
+	Bookmarks  []string        `json:"bookmarkedArticles"`
+}
+
+// ExportedAccount is the account of the user without the password hash
+type ExportedAccount struct {
+	Email        string    `json:"email"`
+	Role         string    `json:"role"`
+	SingleSignOn bool      `json:"singleSignOn"`
+	CreatedAt    time.Time `json:"createdAt"`
+	data.Profile
+}
+
+// ExportedSession lists the credentials that keep the user signed in to apps, access and refresh tokens are not stored
+type ExportedSession struct {
+	APIKeys       []data.APIKey       `json:"apiKeys"`
+	OAuthConsents []data.OAuthConsent `json:"oauthConsents"`
+	OAuthClients  []data.OAuthClient  `json:"oauthClients"`
+}
+
+// AccountDeletionRequest is the data type for erasing the account, users with a password have to confirm it
+type AccountDeletionRequest struct {
+	Password string `json:"password"`
+}
+
+// ExportAccount handles ExportAccount request and downloads a zip archive with the profile, sessions and
+// articles of the user as account.json, a readable account.md and one Markdown file per article
+func (ach *AccountHandler) ExportAccount(w http.ResponseWriter, r *http.Request) {
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	userData, err := ach.repo.GetUserData(userID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrUserNotFound}, w)
+		return
+	}
+
+	archive, err := newAccountArchive(newAccountExport(userData))
+	if err != nil {
+		ach.logger.Error("unable to create account archive", "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.AccountExportFailed}, w)
+		return
+	}
+
+	ach.logger.Debug("Account exported successfully", "user", userID)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="account-%s.zip"`, userData.User.Username))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+// DeleteAccount handles DeleteAccount request. The user and everything that authenticates as them is erased,
+// their articles stay published under the tombstone author
+func (ach *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	deletion := &AccountDeletionRequest{}
+	if err := data.FromJSON(deletion, r.Body); err != nil {
+		ach.logger.Error("deserialization of account deletion json failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	user, err := ach.repo.GetUserByEmail(userID)
+	if err != nil {
+		recordAudit(ach.repo, r, userID, data.AuditAccountDelete, userID, utils.ErrUserNotFound)
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrUserNotFound}, w)
+		return
+	}
+	// single sign-on users have no password, their access token is the confirmation
+	if user.Password != "" && !ach.authService.Authenticate(&data.User{Password: deletion.Password}, user) {
+		ach.logger.Debug("Authetication of user failed")
+		recordAudit(ach.repo, r, userID, data.AuditAccountDelete, userID, utils.ErrIncorrectPassword)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrIncorrectPassword}, w)
+		return
+	}
+
+	if err := ach.repo.DeleteUser(userID, data.TombstoneAuthor); err != nil {
+		ach.logger.Error("unable to delete account", "error", err)
+		recordAudit(ach.repo, r, userID, data.AuditAccountDelete, userID, err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	recordAudit(ach.repo, r, userID, data.AuditAccountDelete, userID, "")
+	ach.logger.Debug("Account deleted successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Account deleted successfully"}, w)
+}
+
+func newAccountExport(userData *data.UserData) *AccountExport {
+	user := userData.User
+	return &AccountExport{
+		ExportedAt: time.Now().UTC(),
+		Account: ExportedAccount{
+			Email:        user.Email,
+			Role:         user.Role,
+			SingleSignOn: user.OIDCSubject != "",
+			CreatedAt:    user.CreatedAt,
+			Profile:      user.Profile(),
+		},
+		Sessions: ExportedSession{
+			APIKeys:       userData.APIKeys,
+			OAuthConsents: userData.OAuthConsents,
+			OAuthClients:  userData.OAuthClients,
+		},
+		Articles:  userData.Articles,
+		Comments:  userData.Comments,
+		Likes:     userData.LikedArticles,
+		Bookmarks: userData.Bookmarks,
+	}
+}
+
+// newAccountArchive zips the export as JSON and Markdown
+func newAccountArchive(export *AccountExport) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	archive := zip.NewWriter(buf)
+
+	encoded, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{
+		"account.json": encoded,
+		"account.md":   accountMarkdown(export),
+	}
+	for i := range export.Articles {
+		files["articles/"+export.Articles[i].ID+".md"] = articleMarkdown(&export.Articles[i])
+	}
+
+	for name, content := range files {
+		f, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func accountMarkdown(export *AccountExport) []byte {
+	md := &strings.Builder{}
+	account := export.Account
+	fmt.Fprintf(md, "# Account of %s\n\nExported at %s\n\n", account.Email, export.ExportedAt.Format(time.RFC3339))
+	fmt.Fprintf(md, "## Profile\n\n- Email: %s\n- Username: %s\n- Display name: %s\n- Bio: %s\n- Avatar URL: %s\n- Role: %s\n- Single sign-on: %t\n- Created at: %s\n\n",
+		account.Email, account.Username, account.DisplayName, account.Bio, account.AvatarURL, account.Role, account.SingleSignOn, account.CreatedAt.Format(time.RFC3339))
+
+	md.WriteString("## Sessions\n\nAccess and refresh tokens are not stored, they expire on their own.\n\n### API keys\n\n")
+	for _, key := range export.Sessions.APIKeys {
+		lastUsed := "never"
+		if key.LastUsedAt != nil {
+			lastUsed = key.LastUsedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(md, "- %s (%s...), scopes: %s, created at %s, last used %s\n", key.Name, key.Prefix, strings.Join(key.Scopes, " "), key.CreatedAt.Format(time.RFC3339), lastUsed)
+	}
+	md.WriteString("\n### Apps with access\n\n")
+	for _, consent := range export.Sessions.OAuthConsents {
+		fmt.Fprintf(md, "- %s, scopes: %s, granted at %s\n", consent.ClientID, strings.Join(consent.Scopes, " "), consent.GrantedAt.Format(time.RFC3339))
+	}
+	md.WriteString("\n### Registered apps\n\n")
+	for _, client := range export.Sessions.OAuthClients {
+		fmt.Fprintf(md, "- %s (%s), redirect URIs: %s\n", client.Name, client.ID, strings.Join(client.RedirectURIs, " "))
+	}
+
+	md.WriteString("\n## Articles\n\n")
+	for _, article := range export.Articles {
+		fmt.Fprintf(md, "- [%s](articles/%s.md)\n", article.Title, article.ID)
+	}
+	fmt.Fprintf(md, "\n## Liked articles\n\n%s\n\n## Bookmarked articles\n\n%s\n", markdownList(export.Likes), markdownList(export.Bookmarks))
+	md.WriteString("\n## Comments\n\n")
+	for _, comment := range export.Comments {
+		fmt.Fprintf(md, "- On article %s at %s: %s\n", comment.ArticleID, comment.CreatedAt.Format(time.RFC3339), comment.Content)
+	}
+	return []byte(md.String())
+}
+
+func markdownList(items []string) string {
+	list := &strings.Builder{}
+	for _, item := range items {
+		fmt.Fprintf(list, "- %s\n", item)
+	}
+	return list.String()
+}
+
+func articleMarkdown(article *data.Article) []byte {
+	md := &strings.Builder{}
+	fmt.Fprintf(md, "# %s\n\n- ID: %s\n- Tags: %s\n- Created at: %s\n- Updated at: %s\n\n%s\n",
+		article.Title, article.ID, strings.Join(article.Tags, ", "), article.CreatedAt.Format(time.RFC3339), article.UpdatedAt.Format(time.RFC3339), article.Content)
+	return []byte(md.String())
+}
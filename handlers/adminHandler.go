@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// AdminHandler wraps instances needed to manage users
+type AdminHandler struct {
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *data.Validation
+	repo      data.Repository
+}
+
+// NewAdminHandler returns a new AdminHandler instance
+func NewAdminHandler(l hclog.Logger, c *utils.Configurations, v *data.Validation, r data.Repository) *AdminHandler {
+	return &AdminHandler{
+		logger:    l,
+		configs:   c,
+		validator: v,
+		repo:      r,
+	}
+}
+
+// AdminUserView is how a user is shown to admins
+type AdminUserView struct {
+	Email                 string `json:"email"`
+	Username              string `json:"username"`
+	DisplayName           string `json:"displayName"`
+	Role                  string `json:"role"`
+	Disabled              bool   `json:"disabled"`
+	PasswordResetRequired bool   `json:"passwordResetRequired"`
+	SingleSignOn          bool   `json:"singleSignOn"`
+}
+
+// UserListResponse is a page of users
+type UserListResponse struct {
+	Users []AdminUserView `json:"users"`
+	Total int             `json:"total"`
+	Page  int             `json:"page"`
+}
+
+// RoleChangeRequest is the data type for changing the role of a user
+type RoleChangeRequest struct {
+	UserID string `json:"userID" validate:"required"`
+	Role   string `json:"role" validate:"required,oneof=user admin"`
+}
+
+// UserDeletionRequest is the data type for deleting a user, the articles of the user are either deleted,
+// reassigned to the user given in ReassignTo or anonymized by reassigning them to the tombstone author
+type UserDeletionRequest struct {
+	UserID     string `json:"userID" validate:"required"`
+	Articles   string `json:"articles" validate:"required,oneof=delete reassign anonymize"`
+	ReassignTo string `json:"reassignTo"`
+}
+
+func newAdminUserView(u *data.User) AdminUserView {
+	return AdminUserView{
+		Email:                 u.Email,
+		Username:              u.Username,
+		DisplayName:           u.DisplayName,
+		Role:                  u.Role,
+		Disabled:              u.Disabled,
+		PasswordResetRequired: u.MustResetPassword,
+		SingleSignOn:          u.OIDCSubject != "",
+	}
+}
+
+// ListUsers handles ListUsers request and fetches a page of users matching the optional q search parameter
+func (adh *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	pageNumber := 1
+	if pageID := r.FormValue("pageid"); pageID != "" {
+		var err error
+		if pageNumber, err = strconv.Atoi(pageID); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidPageNumber}, w)
+			return
+		}
+	}
+
+	users, total, err := adh.repo.ListUsers(r.FormValue("q"), pageNumber, adh.configs.PageSize)
+	if err != nil {
+		adh.logger.Debug(utils.ErrInvalidPageNumber)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidPageNumber}, w)
+		return
+	}
+
+	views := []AdminUserView{}
+	for i := range users {
+		views = append(views, newAdminUserView(&users[i]))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Users fetched successfully", Data: &UserListResponse{Users: views, Total: total, Page: pageNumber}}, w)
+}
+
+// DisableUser handles DisableUser request, the user is rejected by the auth middlewares from the next request on
+func (adh *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	adh.updateUser(w, r, data.AuditUserDisable, "User disabled successfully", func(user *data.User) string {
+		user.Disabled = true
+		return ""
+	})
+}
+
+// EnableUser handles EnableUser request
+func (adh *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	adh.updateUser(w, r, data.AuditUserEnable, "User enabled successfully", func(user *data.User) string {
+		user.Disabled = false
+		return ""
+	})
+}
+
+// ForcePasswordReset handles ForcePasswordReset request. The tokens of the user stop working
+// and the user has to set a new password before being able to login again
+func (adh *AdminHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	adh.updateUser(w, r, data.AuditUserForceReset, "Password reset forced successfully", func(user *data.User) string {
+		if user.Password == "" {
+			return utils.ErrSingleSignOnUser
+		}
+		user.MustResetPassword = true
+		user.TokenHash = utils.GenerateRandomString(15)
+		return ""
+	})
+}
+
+// updateUser applies the change to the user given in the path, records it in the audit log as action and writes
+// the response. The change returns an error message when it can not be applied
+func (adh *AdminHandler) updateUser(w http.ResponseWriter, r *http.Request, action string, successMsg string, change func(user *data.User) string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	adminID := r.Context().Value(UserIDKey{}).(string)
+	userID := mux.Vars(r)["userID"]
+	if userID == adminID {
+		recordAudit(adh.repo, r, adminID, action, userID, utils.ErrCantManageOwnAccount)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCantManageOwnAccount}, w)
+		return
+	}
+
+	user, err := adh.repo.GetUserByEmail(userID)
+	if err != nil {
+		recordAudit(adh.repo, r, adminID, action, userID, utils.ErrUserNotFound)
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrUserNotFound}, w)
+		return
+	}
+
+	if errMsg := change(user); errMsg != "" {
+		recordAudit(adh.repo, r, adminID, action, userID, errMsg)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: errMsg}, w)
+		return
+	}
+	adh.repo.UpdateUser(user)
+	recordAudit(adh.repo, r, adminID, action, userID, "")
+
+	adh.logger.Debug(successMsg, "user", userID)
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: successMsg, Data: newAdminUserView(user)}, w)
+}
+
+// ChangeRole handles ChangeRole request
+func (adh *AdminHandler) ChangeRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	roleChange := &RoleChangeRequest{}
+	if !adh.decode(w, r, roleChange) {
+		return
+	}
+
+	r = mux.SetURLVars(r, map[string]string{"userID": roleChange.UserID})
+	adh.updateUser(w, r, data.AuditUserRoleChange, "Role changed successfully", func(user *data.User) string {
+		user.Role = roleChange.Role
+		return ""
+	})
+}
+
+// DeleteUser handles DeleteUser request
+func (adh *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	deletion := &UserDeletionRequest{}
+	if !adh.decode(w, r, deletion) {
+		return
+	}
+
+	adminID := r.Context().Value(UserIDKey{}).(string)
+	if deletion.UserID == adminID {
+		recordAudit(adh.repo, r, adminID, data.AuditUserDelete, deletion.UserID, utils.ErrCantManageOwnAccount)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCantManageOwnAccount}, w)
+		return
+	}
+
+	reassignTo := ""
+	if deletion.Articles == "reassign" {
+		if deletion.ReassignTo == "" {
+			recordAudit(adh.repo, r, adminID, data.AuditUserDelete, deletion.UserID, utils.ErrReassignUserNotFound)
+			w.WriteHeader(http.StatusBadRequest)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrReassignUserNotFound}, w)
+			return
+		}
+		reassignTo = deletion.ReassignTo
+	} else if deletion.Articles == "anonymize" {
+		reassignTo = data.TombstoneAuthor
+	}
+
+	if err := adh.repo.DeleteUser(deletion.UserID, reassignTo); err != nil {
+		adh.logger.Debug("unable to delete user", "error", err)
+		recordAudit(adh.repo, r, adminID, data.AuditUserDelete, deletion.UserID, err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	recordAudit(adh.repo, r, adminID, data.AuditUserDelete, deletion.UserID, "")
+	adh.logger.Debug("User deleted successfully", "user", deletion.UserID)
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "User deleted successfully"}, w)
+}
+
+// decode deserializes and validates the request body, it writes the error response when that fails
+func (adh *AdminHandler) decode(w http.ResponseWriter, r *http.Request, i interface{}) bool {
+	if err := data.FromJSON(i, r.Body); err != nil {
+		adh.logger.Error("deserialization of admin request failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return false
+	}
+	if errs := adh.validator.Validate(i); len(errs) != 0 {
+		adh.logger.Error("validation of admin request failed", "error", errs)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: strings.Join(errs.Errors(), ",")}, w)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// APIKeyHandler wraps instances needed to manage the API keys of a user
+type APIKeyHandler struct {
+	logger      hclog.Logger
+	configs     *utils.Configurations
+	validator   *data.Validation
+	repo        data.Repository
+	authService service.Authentication
+}
+
+// NewAPIKeyHandler returns a new APIKeyHandler instance
+func NewAPIKeyHandler(l hclog.Logger, c *utils.Configurations, v *data.Validation, r data.Repository, auth service.Authentication) *APIKeyHandler {
+	return &APIKeyHandler{
+		logger:      l,
+		configs:     c,
+		validator:   v,
+		repo:        r,
+		authService: auth,
+	}
+}
+
+// CreatedAPIKeyResponse is returned once when a key is created, it is the only time the raw key is exposed
+type CreatedAPIKeyResponse struct {
+	Key    string       `json:"key"`
+	APIKey *data.APIKey `json:"apiKey"`
+}
+
+// CreateAPIKey handles CreateAPIKey request
+func (kh *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	apiKey := &data.APIKey{}
+	err := data.FromJSON(apiKey, r.Body)
+	if err != nil {
+		kh.logger.Error("deserialization of api key json failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	errs := kh.validator.Validate(apiKey)
+	if len(errs) != 0 {
+		kh.logger.Error("validation of api key json failed", "error", errs)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: strings.Join(errs.Errors(), ",")}, w)
+		return
+	}
+
+	rawKey, err := kh.authService.GenerateAPIKey()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.APIKeyCreationFailed}, w)
+		return
+	}
+
+	apiKey.UserID = r.Context().Value(UserIDKey{}).(string)
+	apiKey.KeyHash = kh.authService.HashAPIKey(rawKey)
+	apiKey.Prefix = rawKey[:12]
+	apiKey.LastUsedAt = nil
+
+	createdKey, err := kh.repo.CreateAPIKey(apiKey)
+	if err != nil {
+		kh.logger.Error("unable to create api key", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.APIKeyCreationFailed}, w)
+		return
+	}
+
+	kh.logger.Debug("API key created successfully")
+	w.WriteHeader(http.StatusCreated)
+	data.ToJSON(&GenericResponse{Status: true, Message: "API key created successfully", Data: &CreatedAPIKeyResponse{Key: rawKey, APIKey: createdKey}}, w)
+}
+
+// GetAPIKeys handles GetAPIKeys request and lists the API keys of the current user
+func (kh *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	keys := kh.repo.GetAPIKeysByUser(userID)
+
+	kh.logger.Debug("API keys fetched successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "API keys fetched successfully", Data: keys}, w)
+}
+
+// RevokeAPIKey handles RevokeAPIKey request
+func (kh *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	keyID := mux.Vars(r)["keyID"]
+	userID := r.Context().Value(UserIDKey{}).(string)
+
+	if err := kh.repo.RevokeAPIKey(userID, keyID); err != nil {
+		kh.logger.Debug(utils.ErrAPIKeyNotFound)
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrAPIKeyNotFound}, w)
+		return
+	}
+
+	kh.logger.Debug("API key revoked successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "API key revoked successfully"}, w)
+}
@@ -6,8 +6,10 @@ import (
 	"MohsenArabi/ArticleManagementSystem/utils"
 	"context"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/hashicorp/go-hclog"
@@ -23,16 +25,18 @@ type ArticleHandler struct {
 	validator      *data.Validation
 	repo           data.Repository
 	ArticleService service.Article
+	analytics      service.Analytics
 }
 
 // NewArticleHandler returns a new ArticleHandler instance
-func NewArticleHandler(l hclog.Logger, c *utils.Configurations, v *data.Validation, r data.Repository, articleSrvc service.Article) *ArticleHandler {
+func NewArticleHandler(l hclog.Logger, c *utils.Configurations, v *data.Validation, r data.Repository, articleSrvc service.Article, analytics service.Analytics) *ArticleHandler {
 	return &ArticleHandler{
 		logger:         l,
 		configs:        c,
 		validator:      v,
 		repo:           r,
 		ArticleService: articleSrvc,
+		analytics:      analytics,
 	}
 }
 
@@ -79,12 +83,23 @@ func (ah *ArticleHandler) CreateArticle(w http.ResponseWriter, r *http.Request)
 	article := r.Context().Value(ArticleKey{}).(data.Article)
 	userID := r.Context().Value(UserIDKey{}).(string)
 	article.Author = userID
+	if err := ah.ArticleService.RenderContent(&article); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ArticleRenderingFailed}, w)
+		return
+	}
 	createdArticle, newErr := ah.repo.CreateArticle(&article)
 	if newErr == nil {
+		recordAudit(ah.repo, r, userID, data.AuditArticleCreate, createdArticle.ID, "")
 
 		ah.logger.Debug("Article created successfully")
 		w.WriteHeader(http.StatusCreated)
 		data.ToJSON(&GenericResponse{Status: true, Message: "Article created successfully", Data: createdArticle}, w)
+	} else {
+		ah.logger.Debug(newErr.Error())
+		recordAudit(ah.repo, r, userID, data.AuditArticleCreate, "", newErr.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: newErr.Error()}, w)
 	}
 }
 
@@ -95,16 +110,46 @@ func (ah *ArticleHandler) UpdateArticle(w http.ResponseWriter, r *http.Request)
 	article := r.Context().Value(ArticleKey{}).(data.Article)
 	userID := r.Context().Value(UserIDKey{}).(string)
 
-	if article.Author == userID {
+	// only the owner and the editors of the stored article can update it, the request does not carry the author
+	_, err := ah.repo.GetArticleByID(article.ID)
+	if err != nil {
+		ah.logger.Debug(utils.ErrArticleNotFound)
+		recordAudit(ah.repo, r, userID, data.AuditArticleUpdate, article.ID, utils.ErrArticleNotFound)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrArticleNotFound}, w)
+		return
+	}
+
+	if data.CanUpdateArticle(ah.repo.GetArticlePermission(article.ID, userID)) {
+		// edit locks are advisory, but a contributor can not overwrite the work of the one holding the lock
+		if lock := ah.repo.GetEditLock(article.ID); lock != nil && lock.Holder != userID {
+			ah.logger.Debug(utils.ErrArticleLocked)
+			recordAudit(ah.repo, r, userID, data.AuditArticleUpdate, article.ID, utils.ErrArticleLocked)
+			w.WriteHeader(http.StatusLocked)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrArticleLocked, Data: lock}, w)
+			return
+		}
+		if err := ah.ArticleService.RenderContent(&article); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ArticleRenderingFailed}, w)
+			return
+		}
 		updatedArticle, err := ah.repo.UpdateArticle(&article)
 		if err == nil {
+			recordAudit(ah.repo, r, userID, data.AuditArticleUpdate, article.ID, "")
 
 			ah.logger.Debug("Article updated successfully")
 			w.WriteHeader(http.StatusCreated)
 			data.ToJSON(&GenericResponse{Status: true, Message: "Article updated successfully", Data: updatedArticle}, w)
+		} else {
+			ah.logger.Debug(err.Error())
+			recordAudit(ah.repo, r, userID, data.AuditArticleUpdate, article.ID, err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
 		}
 	} else {
 		ah.logger.Debug(utils.ErrCantUpdateOthersArticle)
+		recordAudit(ah.repo, r, userID, data.AuditArticleUpdate, article.ID, utils.ErrCantUpdateOthersArticle)
 		w.WriteHeader(http.StatusBadRequest)
 		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCantUpdateOthersArticle}, w)
 	}
@@ -121,13 +166,16 @@ func (ah *ArticleHandler) DeleteArticle(w http.ResponseWriter, r *http.Request)
 
 	if err != nil {
 		ah.logger.Debug(utils.ErrArticleNotFound)
+		recordAudit(ah.repo, r, userID, data.AuditArticleDelete, articleID, utils.ErrArticleNotFound)
 		w.WriteHeader(http.StatusBadRequest)
 		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrArticleNotFound}, w)
+		return
 	}
 
-	if article.Author == userID {
+	if data.CanDeleteArticle(ah.repo.GetArticlePermission(article.ID, userID)) {
 		err := ah.repo.DeleteArticle(article.ID)
 		if err == nil {
+			recordAudit(ah.repo, r, userID, data.AuditArticleDelete, article.ID, "")
 
 			ah.logger.Debug("Article Deleted successfully")
 			w.WriteHeader(http.StatusCreated)
@@ -135,15 +183,22 @@ func (ah *ArticleHandler) DeleteArticle(w http.ResponseWriter, r *http.Request)
 		}
 	} else {
 		ah.logger.Debug(utils.ErrCantDeleteOthersArticle)
+		recordAudit(ah.repo, r, userID, data.AuditArticleDelete, article.ID, utils.ErrCantDeleteOthersArticle)
 		w.WriteHeader(http.StatusBadRequest)
 		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCantDeleteOthersArticle}, w)
 	}
 }
 
-//GetArticle handles getarticle request and fetch an article by id
+//GetArticle handles getarticle request and fetch an article by id, with content=rendered the content
+//is the sanitized HTML instead of the content as written. Drafts are not found except by their contributors
 func (ah *ArticleHandler) GetArticle(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	rendered, ok := ah.contentView(w, r)
+	if !ok {
+		return
+	}
+
 	params := mux.Vars(r)
 	articleID := params["articleID"]
 	article, err := ah.repo.GetArticleByID(articleID)
@@ -152,17 +207,94 @@ func (ah *ArticleHandler) GetArticle(w http.ResponseWriter, r *http.Request) {
 		ah.logger.Debug(utils.ErrArticleNotFound)
 		w.WriteHeader(http.StatusBadRequest)
 		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrArticleNotFound}, w)
+	} else if !visible(ah.repo, r, &article) {
+		ah.logger.Debug(utils.ErrArticleNotFound)
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrArticleNotFound}, w)
 	} else {
-		ah.logger.Debug("Article fetched successfully")
-		w.WriteHeader(http.StatusCreated)
-		data.ToJSON(&GenericResponse{Status: true, Message: "Article fetched successfully", Data: article}, w)
+		ah.writeArticle(w, r, &article, rendered)
+	}
+
+}
+
+//GetArticleBySlug handles GetArticleBySlug request and fetches an article by its slug like GetArticle,
+//previous slugs of an article are permanently redirected to its current slug
+func (ah *ArticleHandler) GetArticleBySlug(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rendered, ok := ah.contentView(w, r)
+	if !ok {
+		return
+	}
+
+	slug := mux.Vars(r)["slug"]
+	article, err := ah.repo.GetArticleBySlug(slug)
+	if err != nil || !visible(ah.repo, r, &article) {
+		ah.logger.Debug(utils.ErrArticleNotFound)
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrArticleNotFound}, w)
+		return
+	}
+
+	if article.Slug != slug {
+		location := url.URL{Path: strings.TrimSuffix(r.URL.Path, slug) + article.Slug, RawQuery: r.URL.RawQuery}
+		ah.logger.Debug("Article moved", "slug", slug, "location", location.String())
+		w.Header().Set("Location", location.String())
+		w.WriteHeader(http.StatusMovedPermanently)
+		data.ToJSON(&GenericResponse{Status: true, Message: "Article moved", Data: &ArticleMovedResponse{Slug: article.Slug}}, w)
+		return
+	}
+
+	ah.writeArticle(w, r, &article, rendered)
+}
+
+//visible reports whether the user can read the article, drafts are only visible to their contributors
+func visible(repo data.Repository, r *http.Request, article *data.Article) bool {
+	if article.Published() {
+		return true
 	}
+	userID := r.Context().Value(UserIDKey{}).(string)
+	return repo.GetArticlePermission(article.ID, userID) != ""
+}
+
+//visibleByID reports whether the article exists and the user can read it
+func visibleByID(repo data.Repository, r *http.Request, articleID string) bool {
+	article, err := repo.GetArticleByID(articleID)
+	return err == nil && visible(repo, r, &article)
+}
+
+// ArticleMovedResponse tells the current slug of an article fetched by a previous slug
+type ArticleMovedResponse struct {
+	Slug string `json:"slug"`
+}
 
+//writeArticle counts the view of the fetched article and writes it with the navigation of its series, the holder
+//of its edit lock and its public SEO metadata, contributors reading their own articles are not counted
+func (ah *ArticleHandler) writeArticle(w http.ResponseWriter, r *http.Request, article *data.Article, rendered bool) {
+	article.Series = ah.repo.GetSeriesNavigation(article.ID)
+	article.EditLock = ah.repo.GetEditLock(article.ID)
+	article.SEO = articleSEO(ah.configs, article)
+	if userID := r.Context().Value(UserIDKey{}).(string); ah.repo.GetArticlePermission(article.ID, userID) == "" {
+		ah.analytics.RecordView(article.ID, userID)
+	}
+	if rendered {
+		article.Content = article.RenderedContent
+	}
+	ah.logger.Debug("Article fetched successfully")
+	w.WriteHeader(http.StatusCreated)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Article fetched successfully", Data: article}, w)
 }
 
-//GetArticles handles GetArticles request and fetches a page of articles
+//GetArticles handles GetArticles request and fetches a page of published article summaries, the optional sort parameter
+//orders them by newest(the default) or likes. With full=true the whole articles are fetched and the content
+//parameter works like for GetArticle
 func (ah *ArticleHandler) GetArticles(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
+
+	rendered, ok := ah.contentView(w, r)
+	if !ok {
+		return
+	}
 	//params := mux.Vars(r)
 	//pageNumber, err := strconv.Atoi(params["id"])
 	params := r.FormValue("pageid")
@@ -175,11 +307,19 @@ func (ah *ArticleHandler) GetArticles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	articles, err := ah.repo.GetArticles(pageNumber, ah.configs.PageSize)
+	sortBy, ok := articleSort(r)
+	if !ok {
+		ah.logger.Debug(utils.ErrInvalidSort)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidSort}, w)
+		return
+	}
+
+	articles, err := ah.repo.GetArticles(pageNumber, ah.configs.PageSize, sortBy)
 	if err == nil {
 		ah.logger.Debug("Article(s) fetched successfully")
 		w.WriteHeader(http.StatusCreated)
-		data.ToJSON(&GenericResponse{Status: true, Message: "Article(s) fetched successfully", Data: articles}, w)
+		data.ToJSON(&GenericResponse{Status: true, Message: "Article(s) fetched successfully", Data: articleListing(articles, isFullListing(r), rendered)}, w)
 
 	} else {
 		ah.logger.Debug(utils.ErrInvalidPageNumber)
@@ -197,3 +337,216 @@ func (ah *ArticleHandler) GetArticlesTags(w http.ResponseWriter, r *http.Request
 	data.ToJSON(&GenericResponse{Status: true, Message: "Article tags fetched successfully", Data: tags}, w)
 
 }
+
+//contentView reports whether the content parameter asks for the rendered content, it writes the error response
+//when the parameter is invalid
+func (ah *ArticleHandler) contentView(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	switch r.FormValue("content") {
+	case "", "raw":
+		return false, true
+	case "rendered":
+		return true, true
+	default:
+		ah.logger.Debug(utils.ErrInvalidContentView)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidContentView}, w)
+		return false, false
+	}
+}
+
+//articleSort returns the order asked by the sort parameter, newest by default, and whether it is valid
+func articleSort(r *http.Request) (string, bool) {
+	switch sortBy := r.FormValue("sort"); sortBy {
+	case "":
+		return data.SortNewest, true
+	case data.SortNewest, data.SortMostLiked:
+		return sortBy, true
+	default:
+		return "", false
+	}
+}
+
+//isFullListing reports whether a listing asks for the whole articles instead of their summaries
+func isFullListing(r *http.Request) bool {
+	return r.FormValue("full") == "true"
+}
+
+//articleListing returns the summaries of the articles, or the articles themselves for full listings
+func articleListing(articles []data.Article, full bool, rendered bool) interface{} {
+	if full {
+		if rendered {
+			for i := range articles {
+				articles[i].Content = articles[i].RenderedContent
+			}
+		}
+		return articles
+	}
+
+	summaries := make([]data.ArticleSummary, 0, len(articles))
+	for i := range articles {
+		summaries = append(summaries, articles[i].Summary())
+	}
+	return summaries
+}
+
+//LikeArticle handles LikeArticle request, liking an article twice counts once
+func (ah *ArticleHandler) LikeArticle(w http.ResponseWriter, r *http.Request) {
+	ah.react(w, r, ah.repo.LikeArticle, "Article liked successfully")
+}
+
+//UnlikeArticle handles UnlikeArticle request
+func (ah *ArticleHandler) UnlikeArticle(w http.ResponseWriter, r *http.Request) {
+	ah.react(w, r, ah.repo.UnlikeArticle, "Article unliked successfully")
+}
+
+//BookmarkArticle handles BookmarkArticle request and saves the article for later
+func (ah *ArticleHandler) BookmarkArticle(w http.ResponseWriter, r *http.Request) {
+	ah.react(w, r, ah.repo.BookmarkArticle, "Article bookmarked successfully")
+}
+
+//RemoveBookmark handles RemoveBookmark request
+func (ah *ArticleHandler) RemoveBookmark(w http.ResponseWriter, r *http.Request) {
+	ah.react(w, r, ah.repo.RemoveBookmark, "Bookmark removed successfully")
+}
+
+//react applies the reaction of the current user to the article given in the path and writes the summary of the
+//article with its new counts, drafts are not found except by their contributors
+func (ah *ArticleHandler) react(w http.ResponseWriter, r *http.Request, reaction func(userID string, articleID string) (*data.Article, error), successMsg string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	articleID := mux.Vars(r)["articleID"]
+	if !visibleByID(ah.repo, r, articleID) {
+		ah.logger.Debug(utils.ErrArticleNotFound)
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrArticleNotFound}, w)
+		return
+	}
+	article, err := reaction(userID, articleID)
+	if err != nil {
+		ah.logger.Debug(utils.ErrArticleNotFound)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrArticleNotFound}, w)
+		return
+	}
+
+	ah.logger.Debug(successMsg)
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: successMsg, Data: article.Summary()}, w)
+}
+
+// BookmarkListResponse is a page of the bookmarks of a user, Articles are summaries unless the whole articles were asked
+type BookmarkListResponse struct {
+	Articles interface{} `json:"articles"`
+	Total    int         `json:"total"`
+	Page     int         `json:"page"`
+}
+
+//GetBookmarks handles GetBookmarks request and fetches a page of the published articles bookmarked by the current
+//user, the full and content parameters work like for GetArticles
+func (ah *ArticleHandler) GetBookmarks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rendered, ok := ah.contentView(w, r)
+	if !ok {
+		return
+	}
+
+	pageNumber := 1
+	if pageID := r.FormValue("pageid"); pageID != "" {
+		var err error
+		if pageNumber, err = strconv.Atoi(pageID); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidPageNumber}, w)
+			return
+		}
+	}
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	articles, total, err := ah.repo.GetBookmarks(userID, pageNumber, ah.configs.PageSize)
+	if err != nil {
+		ah.logger.Debug(utils.ErrInvalidPageNumber)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidPageNumber}, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Bookmarks fetched successfully", Data: &BookmarkListResponse{Articles: articleListing(articles, isFullListing(r), rendered), Total: total, Page: pageNumber}}, w)
+}
+
+//GetArticleStats handles GetArticleStats request and fetches the daily views of an article the current user
+//contributes to in the period given by the optional from and to parameters, the last 30 days by default
+func (ah *ArticleHandler) GetArticleStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	from, to, ok := ah.statsPeriod(w, r)
+	if !ok {
+		return
+	}
+
+	articleID := mux.Vars(r)["articleID"]
+	article, err := ah.repo.GetArticleByID(articleID)
+	if err != nil {
+		ah.logger.Debug(utils.ErrArticleNotFound)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrArticleNotFound}, w)
+		return
+	}
+	if ah.repo.GetArticlePermission(article.ID, r.Context().Value(UserIDKey{}).(string)) == "" {
+		ah.logger.Debug(utils.ErrCantViewOthersStats)
+		w.WriteHeader(http.StatusForbidden)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCantViewOthersStats}, w)
+		return
+	}
+
+	stats, err := ah.repo.GetArticleViewStats(articleID, from, to)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Article statistics fetched successfully", Data: stats}, w)
+}
+
+//GetAuthorStats handles GetAuthorStats request and fetches the daily views of all the articles of the current user
+//with the most viewed articles and tags, in the same period as GetArticleStats
+func (ah *ArticleHandler) GetAuthorStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	from, to, ok := ah.statsPeriod(w, r)
+	if !ok {
+		return
+	}
+
+	stats := ah.repo.GetAuthorViewStats(r.Context().Value(UserIDKey{}).(string), from, to)
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Author statistics fetched successfully", Data: stats}, w)
+}
+
+//statsPeriod reads the from and to days of the statistics, it writes the error response when they are invalid
+func (ah *ArticleHandler) statsPeriod(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -29)
+	var err error
+	if value := r.FormValue("to"); value != "" {
+		if to, err = time.Parse(data.DayFormat, value); err != nil {
+			to = time.Time{}
+		}
+	}
+	if value := r.FormValue("from"); value != "" {
+		if from, err = time.Parse(data.DayFormat, value); err != nil {
+			from = time.Time{}
+		}
+	}
+	if from.IsZero() || to.IsZero() || to.Before(from) || to.Sub(from) > 366*24*time.Hour {
+		ah.logger.Debug(utils.ErrInvalidStatsPeriod)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidStatsPeriod}, w)
+		return from, to, false
+	}
+	return from, to, true
+}
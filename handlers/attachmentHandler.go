@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// multipartOverhead is the room left for the multipart headers and boundaries on top of MAX_UPLOAD_SIZE
+const multipartOverhead = 64 << 10
+
+// AttachmentHandler wraps instances needed to manage the files uploaded for articles
+type AttachmentHandler struct {
+	logger            hclog.Logger
+	configs           *utils.Configurations
+	repo              data.Repository
+	AttachmentService service.Attachments
+}
+
+// NewAttachmentHandler returns a new AttachmentHandler instance
+func NewAttachmentHandler(l hclog.Logger, c *utils.Configurations, r data.Repository, as service.Attachments) *AttachmentHandler {
+	return &AttachmentHandler{
+		logger:            l,
+		configs:           c,
+		repo:              r,
+		AttachmentService: as,
+	}
+}
+
+// UploadAttachment handles UploadAttachment request. The owner and the editors of an article upload a file
+// as the file field of a multipart form
+func (ath *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	articleID := mux.Vars(r)["articleID"]
+	userID := r.Context().Value(UserIDKey{}).(string)
+	if _, err := ath.repo.GetArticleByID(articleID); err != nil {
+		recordAudit(ath.repo, r, userID, data.AuditAttachmentUpload, articleID, utils.ErrArticleNotFound)
+		ath.writeAttachmentError(w, err)
+		return
+	}
+	if !data.CanUpdateArticle(ath.repo.GetArticlePermission(articleID, userID)) {
+		ath.logger.Debug(utils.ErrCantUpdateOthersArticle)
+		recordAudit(ath.repo, r, userID, data.AuditAttachmentUpload, articleID, utils.ErrCantUpdateOthersArticle)
+		w.WriteHeader(http.StatusForbidden)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCantUpdateOthersArticle}, w)
+		return
+	}
+
+	// the file is streamed from the form instead of parsing the whole form to memory or disk first
+	r.Body = http.MaxBytesReader(w, r.Body, ath.configs.MaxUploadSize+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		ath.writeInvalidUpload(w, r, userID, articleID)
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			ath.writeInvalidUpload(w, r, userID, articleID)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		attachment, err := ath.AttachmentService.Upload(articleID, userID, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			recordAudit(ath.repo, r, userID, data.AuditAttachmentUpload, articleID, err.Error())
+			ath.writeAttachmentError(w, err)
+			return
+		}
+
+		recordAudit(ath.repo, r, userID, data.AuditAttachmentUpload, attachment.ID, "")
+		ath.logger.Debug("Attachment uploaded successfully")
+		w.WriteHeader(http.StatusCreated)
+		data.ToJSON(&GenericResponse{Status: true, Message: "Attachment uploaded successfully", Data: attachment}, w)
+		return
+	}
+}
+
+// GetAttachment handles GetAttachment request and sends the content of an attachment with its sniffed type.
+// With size=thumbnail, small, medium or large images are sent resized to fit the size. The attachments of drafts
+// are not found except by their contributors
+func (ath *AttachmentHandler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	size := r.URL.Query().Get("size")
+	if size != "" && !service.ValidImageSize(size) {
+		ath.logger.Debug(utils.ErrInvalidImageSize)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidImageSize}, w)
+		return
+	}
+
+	attachment, err := ath.repo.GetAttachmentByID(mux.Vars(r)["attachmentID"])
+	if err == nil && !visibleByID(ath.repo, r, attachment.ArticleID) {
+		err = errors.New(utils.ErrAttachmentNotFound)
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		ath.writeAttachmentError(w, err)
+		return
+	}
+	content, variant, err := ath.AttachmentService.Open(attachment, size)
+	if err != nil {
+		ath.logger.Error("unable to open the attachment", "id", attachment.ID, "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrAttachmentNotFound}, w)
+		return
+	}
+	defer content.Close()
+
+	// images and PDFs are shown inline, the browser must not guess another type from the content
+	w.Header().Set("Content-Type", variant.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(variant.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": variantFilename(attachment, variant)}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, content)
+}
+
+// GetAttachments handles GetAttachments request and lists the attachments of an article, like GetAttachment
+// for drafts
+func (ath *AttachmentHandler) GetAttachments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	articleID := mux.Vars(r)["articleID"]
+	if !visibleByID(ath.repo, r, articleID) {
+		ath.writeAttachmentError(w, errors.New(utils.ErrArticleNotFound))
+		return
+	}
+	articleAttachments, err := ath.repo.GetAttachments(articleID)
+	if err != nil {
+		ath.writeAttachmentError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Attachments fetched successfully", Data: articleAttachments}, w)
+}
+
+// DeleteAttachment handles DeleteAttachment request, the owner and the editors of the article delete its attachments
+func (ath *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	attachmentID := mux.Vars(r)["attachmentID"]
+	userID := r.Context().Value(UserIDKey{}).(string)
+	attachment, err := ath.repo.GetAttachmentByID(attachmentID)
+	if err != nil {
+		recordAudit(ath.repo, r, userID, data.AuditAttachmentDelete, attachmentID, err.Error())
+		ath.writeAttachmentError(w, err)
+		return
+	}
+	if !data.CanUpdateArticle(ath.repo.GetArticlePermission(attachment.ArticleID, userID)) {
+		ath.logger.Debug(utils.ErrCantUpdateOthersArticle)
+		recordAudit(ath.repo, r, userID, data.AuditAttachmentDelete, attachmentID, utils.ErrCantUpdateOthersArticle)
+		w.WriteHeader(http.StatusForbidden)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCantUpdateOthersArticle}, w)
+		return
+	}
+
+	if err := ath.AttachmentService.Delete(attachmentID); err != nil {
+		recordAudit(ath.repo, r, userID, data.AuditAttachmentDelete, attachmentID, err.Error())
+		ath.writeAttachmentError(w, err)
+		return
+	}
+
+	recordAudit(ath.repo, r, userID, data.AuditAttachmentDelete, attachmentID, "")
+	ath.logger.Debug("Attachment deleted successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Attachment deleted successfully"}, w)
+}
+
+// writeInvalidUpload writes the response of a request without a file to upload
+func (ath *AttachmentHandler) writeInvalidUpload(w http.ResponseWriter, r *http.Request, userID string, articleID string) {
+	ath.logger.Debug(utils.ErrInvalidUpload)
+	recordAudit(ath.repo, r, userID, data.AuditAttachmentUpload, articleID, utils.ErrInvalidUpload)
+	w.WriteHeader(http.StatusBadRequest)
+	data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidUpload}, w)
+}
+
+// writeAttachmentError writes the response of a failed attachment request
+func (ath *AttachmentHandler) writeAttachmentError(w http.ResponseWriter, err error) {
+	ath.logger.Debug(err.Error())
+	switch err.Error() {
+	case utils.ErrArticleNotFound, utils.ErrAttachmentNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case utils.ErrUploadTooLarge:
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	case utils.ErrUnsupportedUploadType:
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+	case utils.UploadFailed:
+		w.WriteHeader(http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+}
+
+// variantFilename is the filename of the attachment with the extension of the variant, gif images have png variants
+func variantFilename(attachment *data.Attachment, variant *data.AttachmentVariant) string {
+	if variant.ContentType == attachment.ContentType {
+		return attachment.Filename
+	}
+	extensions, _ := mime.ExtensionsByType(variant.ContentType)
+	if len(extensions) == 0 {
+		return attachment.Filename
+	}
+	return strings.TrimSuffix(attachment.Filename, path.Ext(attachment.Filename)) + extensions[0]
+}
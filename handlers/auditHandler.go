@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// AuditHandler wraps instances needed by admins to query the audit log
+type AuditHandler struct {
+	logger  hclog.Logger
+	configs *utils.Configurations
+	repo    data.Repository
+}
+
+// NewAuditHandler returns a new AuditHandler instance
+func NewAuditHandler(l hclog.Logger, c *utils.Configurations, r data.Repository) *AuditHandler {
+	return &AuditHandler{
+		logger:  l,
+		configs: c,
+		repo:    r,
+	}
+}
+
+// AuditEventListResponse is a page of audit events
+type AuditEventListResponse struct {
+	Events []data.AuditEvent `json:"events"`
+	Total  int               `json:"total"`
+	Page   int               `json:"page"`
+}
+
+// recordAudit appends the outcome of an action to the audit log. The IP is the address of the
+// connection, forwarding headers are not trusted as any client can set them
+func recordAudit(repo data.Repository, r *http.Request, actor string, action string, target string, reason string) {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	outcome := data.AuditSuccess
+	if reason != "" {
+		outcome = data.AuditFailure
+	}
+	repo.AppendAuditEvent(&data.AuditEvent{Actor: actor, Action: action, Target: target, IP: ip, Outcome: outcome, Reason: reason})
+}
+
+// ListAuditEvents handles ListAuditEvents request and fetches a page of the audit events matching the
+// optional actor, action, target, outcome, since and until parameters, newest first
+func (auh *AuditHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, ok := auh.filter(w, r)
+	if !ok {
+		return
+	}
+
+	pageNumber := 1
+	if pageID := r.FormValue("pageid"); pageID != "" {
+		var err error
+		if pageNumber, err = strconv.Atoi(pageID); err != nil || pageNumber < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidPageNumber}, w)
+			return
+		}
+	}
+
+	events := auh.repo.GetAuditEvents(*filter)
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	start := (pageNumber - 1) * auh.configs.PageSize
+	if start >= len(events) && pageNumber != 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidPageNumber}, w)
+		return
+	}
+	stop := start + auh.configs.PageSize
+	if stop > len(events) {
+		stop = len(events)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Audit events fetched successfully", Data: &AuditEventListResponse{Events: events[start:stop], Total: len(events), Page: pageNumber}}, w)
+}
+
+// ExportAuditEvents handles ExportAuditEvents request and downloads the audit events matching the
+// same parameters as ListAuditEvents as JSON Lines, oldest first
+func (auh *AuditHandler) ExportAuditEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, ok := auh.filter(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.jsonl"`)
+	w.WriteHeader(http.StatusOK)
+	e := json.NewEncoder(w)
+	for _, event := range auh.repo.GetAuditEvents(*filter) {
+		e.Encode(event)
+	}
+}
+
+// filter reads the audit filter from the query, it writes the error response when a timestamp is invalid
+func (auh *AuditHandler) filter(w http.ResponseWriter, r *http.Request) (*data.AuditFilter, bool) {
+	filter := &data.AuditFilter{
+		Actor:   r.FormValue("actor"),
+		Action:  r.FormValue("action"),
+		Target:  r.FormValue("target"),
+		Outcome: r.FormValue("outcome"),
+	}
+	for param, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := r.FormValue(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			auh.logger.Debug(utils.ErrInvalidAuditFilter, "param", param)
+			w.WriteHeader(http.StatusBadRequest)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidAuditFilter}, w)
+			return nil, false
+		}
+		*t = parsed
+	}
+	return filter, true
+}
@@ -5,11 +5,13 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"MohsenArabi/ArticleManagementSystem/data"
 	"MohsenArabi/ArticleManagementSystem/service"
 	"MohsenArabi/ArticleManagementSystem/utils"
 
+	"github.com/gorilla/mux"
 	"github.com/hashicorp/go-hclog"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -20,6 +22,13 @@ type UserKey struct{}
 // UserIDKey is used as a key for storing the UserID in context at middleware
 type UserIDKey struct{}
 
+// APIKeyIDKey is used as a key for storing the ID of the API key that authenticated the request
+type APIKeyIDKey struct{}
+
+// ScopesKey is used as a key for storing the scopes granted to the API key or third-party app
+// that authenticated the request. Requests signed in with the user's own access token have no scopes stored
+type ScopesKey struct{}
+
 // UserHandler wraps instances needed to perform operations on user object
 type AuthHandler struct {
 	logger      hclog.Logger
@@ -71,6 +80,16 @@ func (ah *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 
 	user := r.Context().Value(UserKey{}).(data.User)
 
+	invitation, status, msg := ah.signupInvitation(&user)
+	if msg != "" {
+		ah.logger.Debug("sign up refused", "mode", ah.configs.RegistrationMode, "reason", msg)
+		recordAudit(ah.repo, r, user.Email, data.AuditSignup, user.Email, msg)
+		w.WriteHeader(status)
+		data.ToJSON(&GenericResponse{Status: false, Message: msg}, w)
+		return
+	}
+	user.InvitationCode = ""
+
 	hashedPass, err := ah.hashPassword(user.Password)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -79,14 +98,23 @@ func (ah *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 	}
 	user.Password = hashedPass
 	user.TokenHash = utils.GenerateRandomString(15)
+	if ah.configs.IsAdminEmail(user.Email) {
+		user.Role = data.RoleAdmin
+	} else if invitation != nil {
+		user.Role = invitation.Role
+	}
 
 	err = ah.repo.Create(&user)
 	if err != nil {
 		ah.logger.Error("unable to create user", "error", err)
 		errMsg := err.Error()
+		recordAudit(ah.repo, r, user.Email, data.AuditSignup, user.Email, errMsg)
 		if strings.Contains(errMsg, utils.ErrUserAlreadyExists) {
 			w.WriteHeader(http.StatusBadRequest)
 			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrUserAlreadyExists}, w)
+		} else if strings.Contains(errMsg, utils.ErrUsernameTaken) {
+			w.WriteHeader(http.StatusBadRequest)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrUsernameTaken}, w)
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 			data.ToJSON(&GenericResponse{Status: false, Message: utils.UserCreationFailed}, w)
@@ -94,11 +122,38 @@ func (ah *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if invitation != nil {
+		if err := ah.repo.ConsumeInvitation(invitation.ID, user.Email); err != nil {
+			ah.logger.Error("unable to consume invitation", "id", invitation.ID, "error", err)
+		}
+	}
+
+	recordAudit(ah.repo, r, user.Email, data.AuditSignup, user.Email, "")
 	ah.logger.Debug("User created successfully")
 	w.WriteHeader(http.StatusCreated)
 	data.ToJSON(&GenericResponse{Status: true, Message: "user created successfully"}, w)
 }
 
+// signupInvitation checks that the registration mode allows the sign up. When registration is invite-only
+// it returns the invitation matching the code sent with the request, otherwise the status and message to reply with
+func (ah *AuthHandler) signupInvitation(user *data.User) (*data.Invitation, int, string) {
+	switch ah.configs.RegistrationMode {
+	case utils.RegistrationOpen:
+		return nil, 0, ""
+	case utils.RegistrationInviteOnly:
+		if user.InvitationCode == "" {
+			return nil, http.StatusForbidden, utils.ErrInvitationRequired
+		}
+		invitation, err := ah.repo.GetValidInvitation(ah.authService.HashAPIKey(user.InvitationCode), user.Email)
+		if err != nil {
+			return nil, http.StatusForbidden, utils.ErrInvalidInvitation
+		}
+		return invitation, 0, ""
+	default:
+		return nil, http.StatusForbidden, utils.ErrRegistrationClosed
+	}
+}
+
 //hashpasword hashes the password
 func (ah *AuthHandler) hashPassword(password string) (string, error) {
 
@@ -122,6 +177,7 @@ func (ah *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		ah.logger.Error("error fetching the user", "error", err)
 		errMsg := err.Error()
+		recordAudit(ah.repo, r, reqUser.Email, data.AuditLogin, reqUser.Email, errMsg)
 		if strings.Contains(errMsg, utils.ErrUserNotFound) {
 			w.WriteHeader(http.StatusBadRequest)
 			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrUserNotFound}, w)
@@ -134,33 +190,125 @@ func (ah *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	if valid := ah.authService.Authenticate(&reqUser, user); !valid {
 		ah.logger.Debug("Authetication of user failed")
+		recordAudit(ah.repo, r, user.Email, data.AuditLogin, user.Email, utils.ErrIncorrectPassword)
 		w.WriteHeader(http.StatusBadRequest)
-		data.ToJSON(&GenericResponse{Status: false, Message: "Incorrect password"}, w)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrIncorrectPassword}, w)
 		return
 	}
 
-	accessToken, err := ah.authService.GenerateAccessToken(user)
+	recordAudit(ah.repo, r, user.Email, data.AuditLogin, user.Email, writeAuthResponse(ah.logger, ah.authService, w, user))
+}
+
+// writeAuthResponse generates a new pair of tokens for the logged in user and writes them to the response.
+// It returns why the login failed, or an empty string
+func writeAuthResponse(logger hclog.Logger, authService service.Authentication, w http.ResponseWriter, user *data.User) string {
+	if errMsg := accountStatusError(user); errMsg != "" {
+		logger.Debug("login of user rejected", "email", user.Email, "reason", errMsg)
+		w.WriteHeader(http.StatusForbidden)
+		data.ToJSON(&GenericResponse{Status: false, Message: errMsg}, w)
+		return errMsg
+	}
+
+	accessToken, err := authService.GenerateAccessToken(user)
 	if err != nil {
-		ah.logger.Error("unable to generate access token", "error", err)
+		logger.Error("unable to generate access token", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		data.ToJSON(&GenericResponse{Status: false, Message: "Unable to login the user. Please try again later"}, w)
-		return
+		return err.Error()
 	}
-	refreshToken, err := ah.authService.GenerateRefreshToken(user)
+	refreshToken, err := authService.GenerateRefreshToken(user)
 	if err != nil {
-		ah.logger.Error("unable to generate refresh token", "error", err)
+		logger.Error("unable to generate refresh token", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		data.ToJSON(&GenericResponse{Status: false, Message: "Unable to login the user. Please try again later"}, w)
-		return
+		return err.Error()
 	}
 
-	ah.logger.Debug("successfully generated token", "accesstoken", accessToken, "refreshtoken", refreshToken)
+	logger.Debug("successfully generated token", "accesstoken", accessToken, "refreshtoken", refreshToken)
 	w.WriteHeader(http.StatusOK)
 	data.ToJSON(&GenericResponse{
 		Status:  true,
 		Message: "Successfully logged in",
 		Data:    &AuthResponse{AccessToken: accessToken, RefreshToken: refreshToken, Username: user.Email},
 	}, w)
+	return ""
+}
+
+// accountStatusError returns why a user holding valid credentials is still rejected, or an empty string
+func accountStatusError(user *data.User) string {
+	if user.Disabled {
+		return utils.ErrUserDisabled
+	}
+	if user.MustResetPassword {
+		return utils.ErrPasswordResetRequired
+	}
+	return ""
+}
+
+// PasswordResetRequest is the data type for changing the password of a user
+type PasswordResetRequest struct {
+	Email       string `json:"email" validate:"required"`
+	Password    string `json:"password" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required,nefield=Password"`
+}
+
+// ResetPassword handles reset password request. Users set a new password with their current one,
+// which is also how they complete a password reset forced by an admin
+func (ah *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "application/json")
+
+	reset := &PasswordResetRequest{}
+	if err := data.FromJSON(reset, r.Body); err != nil {
+		ah.logger.Error("deserialization of password reset json failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+	if errs := ah.validator.Validate(reset); len(errs) != 0 {
+		ah.logger.Error("validation of password reset json failed", "error", errs)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: strings.Join(errs.Errors(), ",")}, w)
+		return
+	}
+
+	user, err := ah.repo.GetUserByEmail(reset.Email)
+	if err != nil {
+		recordAudit(ah.repo, r, reset.Email, data.AuditPasswordReset, reset.Email, utils.ErrUserNotFound)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrUserNotFound}, w)
+		return
+	}
+	if valid := ah.authService.Authenticate(&data.User{Password: reset.Password}, user); !valid {
+		ah.logger.Debug("Authetication of user failed")
+		recordAudit(ah.repo, r, user.Email, data.AuditPasswordReset, user.Email, utils.ErrIncorrectPassword)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrIncorrectPassword}, w)
+		return
+	}
+	if user.Disabled {
+		recordAudit(ah.repo, r, user.Email, data.AuditPasswordReset, user.Email, utils.ErrUserDisabled)
+		w.WriteHeader(http.StatusForbidden)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrUserDisabled}, w)
+		return
+	}
+
+	hashedPass, err := ah.hashPassword(reset.NewPassword)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		data.ToJSON(&GenericResponse{Status: false, Message: "Unable to reset the password.Please try again later"}, w)
+		return
+	}
+	user.Password = hashedPass
+	user.MustResetPassword = false
+	// a new token hash invalidates all the refresh tokens issued before
+	user.TokenHash = utils.GenerateRandomString(15)
+	ah.repo.UpdateUser(user)
+	recordAudit(ah.repo, r, user.Email, data.AuditPasswordReset, user.Email, "")
+
+	ah.logger.Debug("Password reset successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Password reset successfully. Please login again"}, w)
 }
 
 // MiddlewareValidateUser validates the user in the request
@@ -205,6 +353,12 @@ func (ah *AuthHandler) MiddlewareValidateAccessToken(next http.Handler) http.Han
 
 		w.Header().Set("Content-Type", "application/json")
 
+		// the request was already authenticated with an API key
+		if _, ok := r.Context().Value(APIKeyIDKey{}).(string); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		ah.logger.Debug("validating access token")
 
 		token, err := extractToken(r)
@@ -216,7 +370,7 @@ func (ah *AuthHandler) MiddlewareValidateAccessToken(next http.Handler) http.Han
 		}
 		ah.logger.Debug("token present in header", token)
 
-		userID, err := ah.authService.ValidateAccessToken(token)
+		claims, err := ah.authService.ParseAccessToken(token)
 		if err != nil {
 			ah.logger.Error("token validation failed", "error", err)
 			w.WriteHeader(http.StatusBadRequest)
@@ -225,14 +379,143 @@ func (ah *AuthHandler) MiddlewareValidateAccessToken(next http.Handler) http.Han
 		}
 		ah.logger.Debug("access token validated")
 
-		ctx := context.WithValue(r.Context(), UserIDKey{}, userID)
+		if errMsg := ah.userStatusError(claims.UserID); errMsg != "" {
+			ah.logger.Debug("user of access token rejected", "reason", errMsg)
+			w.WriteHeader(http.StatusForbidden)
+			data.ToJSON(&GenericResponse{Status: false, Message: errMsg}, w)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), UserIDKey{}, claims.UserID)
+
+		// tokens issued to third-party apps are limited to the granted scopes and can be revoked
+		if claims.ClientID != "" {
+			if ah.repo.IsTokenRevoked(claims.Id) {
+				ah.logger.Debug("token was revoked", "client", claims.ClientID)
+				w.WriteHeader(http.StatusBadRequest)
+				data.ToJSON(&GenericResponse{Status: false, Message: "Authentication failed. Invalid token"}, w)
+				return
+			}
+			ctx = context.WithValue(ctx, ScopesKey{}, service.ParseScope(claims.Scope))
+		}
+
+		r = r.WithContext(ctx)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MiddlewareValidateAPIKey authenticates requests carrying an X-API-Key header.
+// Requests without the header are passed on untouched so MiddlewareValidateAccessToken can authenticate them
+func (ah *AuthHandler) MiddlewareValidateAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		rawKey := r.Header.Get("X-API-Key")
+		if rawKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
 
+		ah.logger.Debug("validating api key")
+		key, err := ah.repo.GetAPIKeyByHash(ah.authService.HashAPIKey(rawKey))
+		if err != nil {
+			ah.logger.Error("api key validation failed", "error", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidAPIKey}, w)
+			return
+		}
+		if errMsg := ah.userStatusError(key.UserID); errMsg != "" {
+			ah.logger.Debug("user of api key rejected", "reason", errMsg)
+			w.WriteHeader(http.StatusForbidden)
+			data.ToJSON(&GenericResponse{Status: false, Message: errMsg}, w)
+			return
+		}
+		ah.repo.TouchAPIKey(key.ID, time.Now())
+		ah.logger.Debug("api key validated", "key", key.ID)
+
+		ctx := context.WithValue(r.Context(), UserIDKey{}, key.UserID)
+		ctx = context.WithValue(ctx, APIKeyIDKey{}, key.ID)
+		ctx = context.WithValue(ctx, ScopesKey{}, key.Scopes)
 		r = r.WithContext(ctx)
 
 		next.ServeHTTP(w, r)
 	})
 }
 
+// MiddlewareRequireScope checks that the API key or third-party app that authenticated the request
+// was granted the given scope. It must be used after the authentication middlewares
+func (ah *AuthHandler) MiddlewareRequireScope(scope string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			if scopes, ok := r.Context().Value(ScopesKey{}).([]string); ok && !containsScope(scopes, scope) {
+				ah.logger.Debug("credentials are missing scope", "scope", scope)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrScopeMissing}, w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MiddlewareRequireUserSession rejects requests authenticated with an API key or a third-party app token,
+// so delegated credentials can not be used to manage credentials. It must be used after the authentication middlewares
+func (ah *AuthHandler) MiddlewareRequireUserSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if _, ok := r.Context().Value(ScopesKey{}).([]string); ok {
+			ah.logger.Debug("delegated credentials used for a user only operation")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrDelegatedCredentials}, w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MiddlewareRequireAdmin rejects requests of users without the admin role. It must be used after the authentication middlewares
+func (ah *AuthHandler) MiddlewareRequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		userID, _ := r.Context().Value(UserIDKey{}).(string)
+		user, err := ah.repo.GetUserByEmail(userID)
+		if err != nil || !user.IsAdmin() {
+			ah.logger.Debug("admin operation requested by non admin", "user", userID)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrAdminRequired}, w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// userStatusError looks the user up so disabled accounts are rejected immediately, even while their tokens are still valid
+func (ah *AuthHandler) userStatusError(userID string) string {
+	user, err := ah.repo.GetUserByEmail(userID)
+	if err != nil {
+		return utils.ErrUserNotFound
+	}
+	return accountStatusError(user)
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 // MiddlewareValidateRefreshToken validates whether the request contains a bearer token
 // it also decodes and authenticates the given token
 func (ah *AuthHandler) MiddlewareValidateRefreshToken(next http.Handler) http.Handler {
@@ -263,15 +546,25 @@ func (ah *AuthHandler) MiddlewareValidateRefreshToken(next http.Handler) http.Ha
 		user, err := ah.repo.GetUserByEmail(userID)
 		if err != nil {
 			ah.logger.Error("invalid token: wrong userID while parsing", err)
+			recordAudit(ah.repo, r, userID, data.AuditTokenRefresh, userID, utils.ErrUserNotFound)
 			w.WriteHeader(http.StatusBadRequest)
 			// data.ToJSON(&GenericError{Error: "invalid token: authentication failed"}, w)
 			data.ToJSON(&GenericResponse{Status: false, Message: "Unable to fetch corresponding user"}, w)
 			return
 		}
 
+		if errMsg := accountStatusError(user); errMsg != "" {
+			ah.logger.Debug("user of refresh token rejected", "reason", errMsg)
+			recordAudit(ah.repo, r, userID, data.AuditTokenRefresh, userID, errMsg)
+			w.WriteHeader(http.StatusForbidden)
+			data.ToJSON(&GenericResponse{Status: false, Message: errMsg}, w)
+			return
+		}
+
 		actualCustomKey := ah.authService.GenerateCustomKey(user.Email, user.TokenHash)
 		if customKey != actualCustomKey {
 			ah.logger.Debug("wrong token: authetincation failed")
+			recordAudit(ah.repo, r, userID, data.AuditTokenRefresh, userID, "refresh token was revoked")
 			w.WriteHeader(http.StatusBadRequest)
 			data.ToJSON(&GenericResponse{Status: false, Message: "Authentication failed. Invalid token"}, w)
 			return
@@ -303,10 +596,12 @@ func (ah *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	accessToken, err := ah.authService.GenerateAccessToken(&user)
 	if err != nil {
 		ah.logger.Error("unable to generate access token", "error", err)
+		recordAudit(ah.repo, r, user.Email, data.AuditTokenRefresh, user.Email, err.Error())
 		w.WriteHeader(http.StatusInternalServerError)
 		data.ToJSON(&GenericResponse{Status: false, Message: "Unable to generate access token.Please try again later"}, w)
 		return
 	}
+	recordAudit(ah.repo, r, user.Email, data.AuditTokenRefresh, user.Email, "")
 
 	w.WriteHeader(http.StatusOK)
 	data.ToJSON(&GenericResponse{
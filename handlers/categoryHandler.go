@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// CategoryHandler wraps instances needed to browse categories and by admins to manage the category tree
+type CategoryHandler struct {
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *data.Validation
+	repo      data.Repository
+}
+
+// NewCategoryHandler returns a new CategoryHandler instance
+func NewCategoryHandler(l hclog.Logger, c *utils.Configurations, v *data.Validation, r data.Repository) *CategoryHandler {
+	return &CategoryHandler{
+		logger:    l,
+		configs:   c,
+		validator: v,
+		repo:      r,
+	}
+}
+
+// CategoryRenameRequest is the request body of RenameCategory
+type CategoryRenameRequest struct {
+	ID   string `json:"ID" validate:"required"`
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// CategoryMoveRequest is the request body of MoveCategory, an empty ParentID moves the category to the top of the tree
+type CategoryMoveRequest struct {
+	ID       string `json:"ID" validate:"required"`
+	ParentID string `json:"parentID"`
+}
+
+// CategoryArticlesResponse is a page of the articles of a category, Articles are summaries unless the whole
+// articles were asked
+type CategoryArticlesResponse struct {
+	Category *data.Category `json:"category"`
+	Articles interface{}    `json:"articles"`
+	Total    int            `json:"total"`
+	Page     int            `json:"page"`
+}
+
+// GetCategories handles GetCategories request and fetches the category tree with the number of articles of each category
+func (cah *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Categories fetched successfully", Data: cah.repo.GetCategoryTree()}, w)
+}
+
+// GetCategoryArticles handles GetCategoryArticles request and fetches a page of the articles of a category,
+// with subcategories=true the articles of its subcategories are included. The sort and full parameters work
+// like for GetArticles
+func (cah *CategoryHandler) GetCategoryArticles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	pageNumber := 1
+	if pageID := r.FormValue("pageid"); pageID != "" {
+		var err error
+		if pageNumber, err = strconv.Atoi(pageID); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidPageNumber}, w)
+			return
+		}
+	}
+
+	sortBy, ok := articleSort(r)
+	if !ok {
+		cah.logger.Debug(utils.ErrInvalidSort)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidSort}, w)
+		return
+	}
+
+	category, err := cah.repo.GetCategoryByID(mux.Vars(r)["categoryID"])
+	if err != nil {
+		cah.logger.Debug(utils.ErrCategoryNotFound)
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCategoryNotFound}, w)
+		return
+	}
+
+	articles, total, err := cah.repo.GetArticlesByCategory(category.ID, r.FormValue("subcategories") == "true", pageNumber, cah.configs.PageSize, sortBy)
+	if err != nil {
+		cah.logger.Debug(utils.ErrInvalidPageNumber)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidPageNumber}, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Article(s) fetched successfully", Data: &CategoryArticlesResponse{Category: category, Articles: articleListing(articles, isFullListing(r), false), Total: total, Page: pageNumber}}, w)
+}
+
+// CreateCategory handles CreateCategory request, categories without parentID are created at the top of the tree
+func (cah *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	category := &data.Category{}
+	if !cah.decode(w, r, category) {
+		return
+	}
+
+	adminID := r.Context().Value(UserIDKey{}).(string)
+	createdCategory, err := cah.repo.CreateCategory(category)
+	if err != nil {
+		recordAudit(cah.repo, r, adminID, data.AuditCategoryCreate, category.Name, err.Error())
+		cah.writeCategoryError(w, err)
+		return
+	}
+
+	recordAudit(cah.repo, r, adminID, data.AuditCategoryCreate, createdCategory.ID, "")
+
+	cah.logger.Debug("Category created successfully")
+	w.WriteHeader(http.StatusCreated)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Category created successfully", Data: createdCategory}, w)
+}
+
+// RenameCategory handles RenameCategory request
+func (cah *CategoryHandler) RenameCategory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rename := &CategoryRenameRequest{}
+	if !cah.decode(w, r, rename) {
+		return
+	}
+
+	adminID := r.Context().Value(UserIDKey{}).(string)
+	category, err := cah.repo.RenameCategory(rename.ID, rename.Name)
+	if err != nil {
+		recordAudit(cah.repo, r, adminID, data.AuditCategoryRename, rename.ID, err.Error())
+		cah.writeCategoryError(w, err)
+		return
+	}
+
+	recordAudit(cah.repo, r, adminID, data.AuditCategoryRename, rename.ID, "")
+
+	cah.logger.Debug("Category renamed successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Category renamed successfully", Data: category}, w)
+}
+
+// MoveCategory handles MoveCategory request and moves a category with its subcategories under another parent
+func (cah *CategoryHandler) MoveCategory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	move := &CategoryMoveRequest{}
+	if !cah.decode(w, r, move) {
+		return
+	}
+
+	adminID := r.Context().Value(UserIDKey{}).(string)
+	category, err := cah.repo.MoveCategory(move.ID, move.ParentID)
+	if err != nil {
+		recordAudit(cah.repo, r, adminID, data.AuditCategoryMove, move.ID, err.Error())
+		cah.writeCategoryError(w, err)
+		return
+	}
+
+	recordAudit(cah.repo, r, adminID, data.AuditCategoryMove, move.ID, "")
+
+	cah.logger.Debug("Category moved successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Category moved successfully", Data: category}, w)
+}
+
+// DeleteCategory handles DeleteCategory request, the subcategories and articles of the category move to its parent
+func (cah *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	categoryID := mux.Vars(r)["categoryID"]
+	adminID := r.Context().Value(UserIDKey{}).(string)
+	if err := cah.repo.DeleteCategory(categoryID); err != nil {
+		recordAudit(cah.repo, r, adminID, data.AuditCategoryDelete, categoryID, err.Error())
+		cah.writeCategoryError(w, err)
+		return
+	}
+
+	recordAudit(cah.repo, r, adminID, data.AuditCategoryDelete, categoryID, "")
+
+	cah.logger.Debug("Category deleted successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Category deleted successfully"}, w)
+}
+
+// decode reads and validates the JSON body of the request, it writes the error response when it fails
+func (cah *CategoryHandler) decode(w http.ResponseWriter, r *http.Request, request interface{}) bool {
+	if err := data.FromJSON(request, r.Body); err != nil {
+		cah.logger.Error("deserialization of category json failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return false
+	}
+
+	if errs := cah.validator.Validate(request); len(errs) != 0 {
+		cah.logger.Error("validation of category json failed", "error", errs)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: strings.Join(errs.Errors(), ",")}, w)
+		return false
+	}
+	return true
+}
+
+// writeCategoryError writes the response of a failed category operation
+func (cah *CategoryHandler) writeCategoryError(w http.ResponseWriter, err error) {
+	cah.logger.Debug(err.Error())
+	if err.Error() == utils.ErrCategoryNotFound {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// CommentHandler wraps instances needed to perform operations on comments
+type CommentHandler struct {
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *data.Validation
+	repo      data.Repository
+}
+
+// NewCommentHandler returns a new CommentHandler instance
+func NewCommentHandler(l hclog.Logger, c *utils.Configurations, v *data.Validation, r data.Repository) *CommentHandler {
+	return &CommentHandler{
+		logger:    l,
+		configs:   c,
+		validator: v,
+		repo:      r,
+	}
+}
+
+// CommentListResponse is a page of the comment threads of an article
+type CommentListResponse struct {
+	Comments []data.Comment `json:"comments"`
+	Total    int            `json:"total"`
+	Page     int            `json:"page"`
+}
+
+// CommentUpdateRequest is the data type for editing the content of a comment
+type CommentUpdateRequest struct {
+	ID      string `json:"ID" validate:"required"`
+	Content string `json:"content" validate:"required,max=5000"`
+}
+
+// CreateComment handles CreateComment request, a comment with a parentID is a reply to that comment. Only the
+// contributors of a draft comment on it
+func (ch *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	comment := &data.Comment{}
+	if !ch.decode(w, r, comment) {
+		return
+	}
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	comment.Author = userID
+	var createdComment *data.Comment
+	err := errors.New(utils.ErrArticleNotFound)
+	if visibleByID(ch.repo, r, comment.ArticleID) {
+		createdComment, err = ch.repo.CreateComment(comment)
+	}
+	if err != nil {
+		ch.logger.Debug("unable to create comment", "error", err)
+		recordAudit(ch.repo, r, userID, data.AuditCommentCreate, comment.ArticleID, err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+	recordAudit(ch.repo, r, userID, data.AuditCommentCreate, createdComment.ID, "")
+
+	ch.logger.Debug("Comment created successfully")
+	w.WriteHeader(http.StatusCreated)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Comment created successfully", Data: createdComment}, w)
+}
+
+// UpdateComment handles UpdateComment request, only the author of a comment can edit it
+func (ch *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	comment := &CommentUpdateRequest{}
+	if !ch.decode(w, r, comment) {
+		return
+	}
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	storedComment, err := ch.repo.GetCommentByID(comment.ID)
+	if err != nil {
+		ch.logger.Debug(utils.ErrCommentNotFound)
+		recordAudit(ch.repo, r, userID, data.AuditCommentUpdate, comment.ID, utils.ErrCommentNotFound)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCommentNotFound}, w)
+		return
+	}
+	if storedComment.Author != userID {
+		ch.logger.Debug(utils.ErrCantUpdateOthersComment)
+		recordAudit(ch.repo, r, userID, data.AuditCommentUpdate, comment.ID, utils.ErrCantUpdateOthersComment)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCantUpdateOthersComment}, w)
+		return
+	}
+
+	updatedComment, err := ch.repo.UpdateComment(&data.Comment{ID: comment.ID, Content: comment.Content})
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+	recordAudit(ch.repo, r, userID, data.AuditCommentUpdate, comment.ID, "")
+
+	ch.logger.Debug("Comment updated successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Comment updated successfully", Data: updatedComment}, w)
+}
+
+// DeleteComment handles DeleteComment request. Comments can be deleted by their author and,
+// to moderate the discussion, by the author of the article
+func (ch *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	commentID := mux.Vars(r)["commentID"]
+	userID := r.Context().Value(UserIDKey{}).(string)
+	comment, err := ch.repo.GetCommentByID(commentID)
+	if err != nil {
+		ch.logger.Debug(utils.ErrCommentNotFound)
+		recordAudit(ch.repo, r, userID, data.AuditCommentDelete, commentID, utils.ErrCommentNotFound)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCommentNotFound}, w)
+		return
+	}
+
+	article, err := ch.repo.GetArticleByID(comment.ArticleID)
+	if comment.Author != userID && (err != nil || article.Author != userID) {
+		ch.logger.Debug(utils.ErrCantDeleteComment)
+		recordAudit(ch.repo, r, userID, data.AuditCommentDelete, commentID, utils.ErrCantDeleteComment)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCantDeleteComment}, w)
+		return
+	}
+
+	if err := ch.repo.DeleteComment(commentID); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+	recordAudit(ch.repo, r, userID, data.AuditCommentDelete, commentID, "")
+
+	ch.logger.Debug("Comment deleted successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Comment deleted successfully"}, w)
+}
+
+// GetComments handles GetComments request and fetches a page of the comment threads of an article, the comments
+// of drafts are not found except by their contributors
+func (ch *CommentHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	pageNumber := 1
+	if pageID := r.FormValue("pageid"); pageID != "" {
+		var err error
+		if pageNumber, err = strconv.Atoi(pageID); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidPageNumber}, w)
+			return
+		}
+	}
+
+	articleID := mux.Vars(r)["articleID"]
+	if !visibleByID(ch.repo, r, articleID) {
+		ch.logger.Debug(utils.ErrArticleNotFound)
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrArticleNotFound}, w)
+		return
+	}
+	threads, total, err := ch.repo.GetComments(articleID, pageNumber, ch.configs.PageSize)
+	if err != nil {
+		ch.logger.Debug("unable to fetch comments", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Comments fetched successfully", Data: &CommentListResponse{Comments: threads, Total: total, Page: pageNumber}}, w)
+}
+
+// decode deserializes and validates the request body, it writes the error response when that fails
+func (ch *CommentHandler) decode(w http.ResponseWriter, r *http.Request, comment interface{}) bool {
+	if err := data.FromJSON(comment, r.Body); err != nil {
+		ch.logger.Error("deserialization of comment json failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return false
+	}
+	if errs := ch.validator.Validate(comment); len(errs) != 0 {
+		ch.logger.Error("validation of comment json failed", "error", errs)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: strings.Join(errs.Errors(), ",")}, w)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// ContributorHandler wraps instances needed to share articles with other users
+type ContributorHandler struct {
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *data.Validation
+	repo      data.Repository
+}
+
+// NewContributorHandler returns a new ContributorHandler instance
+func NewContributorHandler(l hclog.Logger, c *utils.Configurations, v *data.Validation, r data.Repository) *ContributorHandler {
+	return &ContributorHandler{
+		logger:    l,
+		configs:   c,
+		validator: v,
+		repo:      r,
+	}
+}
+
+// ContributorRequest is the request body of SetContributor, RemoveContributor and TransferOwnership,
+// users are given by their username. Permission is only read by SetContributor
+type ContributorRequest struct {
+	ArticleID  string `json:"articleID" validate:"required"`
+	Username   string `json:"username" validate:"required"`
+	Permission string `json:"permission" validate:"omitempty,oneof=editor viewer"`
+}
+
+// GetContributors handles GetContributors request and lists the owner and the contributors of an article
+func (coh *ContributorHandler) GetContributors(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	articleContributors, err := coh.repo.GetContributors(mux.Vars(r)["articleID"])
+	if err != nil {
+		coh.logger.Debug(utils.ErrArticleNotFound)
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrArticleNotFound}, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Contributors fetched successfully", Data: articleContributors}, w)
+}
+
+// SetContributor handles SetContributor request, the owner of the article gives a user the editor or viewer permission
+func (coh *ContributorHandler) SetContributor(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	request, contributorID, ok := coh.ownerRequest(w, r, data.AuditArticleShare)
+	if !ok {
+		return
+	}
+	if request.Permission == "" {
+		request.Permission = data.PermissionViewer
+	}
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	contributor, err := coh.repo.SetContributor(request.ArticleID, contributorID, request.Permission)
+	if err != nil {
+		recordAudit(coh.repo, r, userID, data.AuditArticleShare, request.ArticleID, err.Error())
+		coh.logger.Debug(err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	recordAudit(coh.repo, r, userID, data.AuditArticleShare, request.ArticleID, "")
+	coh.logger.Debug("Contributor set successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Contributor set successfully", Data: contributor}, w)
+}
+
+// RemoveContributor handles RemoveContributor request, the owner removes a contributor or contributors remove themselves
+func (coh *ContributorHandler) RemoveContributor(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	request := &ContributorRequest{}
+	if !coh.decode(w, r, request) {
+		return
+	}
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	contributor, err := coh.repo.GetUserByUsername(request.Username)
+	if err != nil {
+		coh.writeUserNotFound(w, r, data.AuditArticleUnshare, request.ArticleID)
+		return
+	}
+	if contributor.Email != userID && !data.CanDeleteArticle(coh.repo.GetArticlePermission(request.ArticleID, userID)) {
+		coh.writeNotOwner(w, r, data.AuditArticleUnshare, request.ArticleID)
+		return
+	}
+
+	if err := coh.repo.RemoveContributor(request.ArticleID, contributor.Email); err != nil {
+		recordAudit(coh.repo, r, userID, data.AuditArticleUnshare, request.ArticleID, err.Error())
+		coh.logger.Debug(err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	recordAudit(coh.repo, r, userID, data.AuditArticleUnshare, request.ArticleID, "")
+	coh.logger.Debug("Contributor removed successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Contributor removed successfully"}, w)
+}
+
+// TransferOwnership handles TransferOwnership request, the owner makes another user the owner of the article
+// and becomes an editor
+func (coh *ContributorHandler) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	request, newOwnerID, ok := coh.ownerRequest(w, r, data.AuditArticleTransfer)
+	if !ok {
+		return
+	}
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	article, err := coh.repo.TransferOwnership(request.ArticleID, newOwnerID)
+	if err != nil {
+		recordAudit(coh.repo, r, userID, data.AuditArticleTransfer, request.ArticleID, err.Error())
+		coh.logger.Debug(err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	recordAudit(coh.repo, r, userID, data.AuditArticleTransfer, request.ArticleID, "")
+	coh.logger.Debug("Ownership transferred successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Ownership transferred successfully", Data: article}, w)
+}
+
+// ownerRequest decodes a request of the owner of an article and resolves the username it names, it writes
+// the error response when the request is invalid or the current user is not the owner
+func (coh *ContributorHandler) ownerRequest(w http.ResponseWriter, r *http.Request, action string) (*ContributorRequest, string, bool) {
+	request := &ContributorRequest{}
+	if !coh.decode(w, r, request) {
+		return nil, "", false
+	}
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	if _, err := coh.repo.GetArticleByID(request.ArticleID); err != nil {
+		recordAudit(coh.repo, r, userID, action, request.ArticleID, utils.ErrArticleNotFound)
+		coh.logger.Debug(utils.ErrArticleNotFound)
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrArticleNotFound}, w)
+		return nil, "", false
+	}
+	if !data.CanDeleteArticle(coh.repo.GetArticlePermission(request.ArticleID, userID)) {
+		coh.writeNotOwner(w, r, action, request.ArticleID)
+		return nil, "", false
+	}
+
+	user, err := coh.repo.GetUserByUsername(request.Username)
+	if err != nil {
+		coh.writeUserNotFound(w, r, action, request.ArticleID)
+		return nil, "", false
+	}
+	return request, user.Email, true
+}
+
+// writeNotOwner writes the response of a request that only the owner of the article can make
+func (coh *ContributorHandler) writeNotOwner(w http.ResponseWriter, r *http.Request, action string, articleID string) {
+	recordAudit(coh.repo, r, r.Context().Value(UserIDKey{}).(string), action, articleID, utils.ErrCantManageContributors)
+	coh.logger.Debug(utils.ErrCantManageContributors)
+	w.WriteHeader(http.StatusForbidden)
+	data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCantManageContributors}, w)
+}
+
+// writeUserNotFound writes the response of a request naming a missing user
+func (coh *ContributorHandler) writeUserNotFound(w http.ResponseWriter, r *http.Request, action string, articleID string) {
+	recordAudit(coh.repo, r, r.Context().Value(UserIDKey{}).(string), action, articleID, utils.ErrUserNotFound)
+	coh.logger.Debug(utils.ErrUserNotFound)
+	w.WriteHeader(http.StatusNotFound)
+	data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrUserNotFound}, w)
+}
+
+// decode reads and validates the JSON body of the request, it writes the error response when it fails
+func (coh *ContributorHandler) decode(w http.ResponseWriter, r *http.Request, request interface{}) bool {
+	if err := data.FromJSON(request, r.Body); err != nil {
+		coh.logger.Error("deserialization of contributor json failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return false
+	}
+
+	if errs := coh.validator.Validate(request); len(errs) != 0 {
+		coh.logger.Error("validation of contributor json failed", "error", errs)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: strings.Join(errs.Errors(), ",")}, w)
+		return false
+	}
+	return true
+}
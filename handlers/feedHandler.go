@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// FeedHandler wraps instances needed to publish the articles as RSS 2.0 and Atom feeds
+type FeedHandler struct {
+	logger  hclog.Logger
+	configs *utils.Configurations
+	repo    data.Repository
+}
+
+// NewFeedHandler returns a new FeedHandler instance
+func NewFeedHandler(l hclog.Logger, c *utils.Configurations, r data.Repository) *FeedHandler {
+	return &FeedHandler{
+		logger:  l,
+		configs: c,
+		repo:    r,
+	}
+}
+
+// feed is what the RSS and Atom feeds are generated from
+type feed struct {
+	title    string
+	link     string
+	self     string
+	updated  time.Time
+	articles []data.Article
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string      `xml:"title"`
+	Link          string      `xml:"link"`
+	Description   string      `xml:"description"`
+	LastBuildDate string      `xml:"lastBuildDate,omitempty"`
+	Self          rssAtomLink `xml:"atom:link"`
+	Items         []rssItem   `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	Description string   `xml:"description"`
+	Creator     string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     atomPerson     `xml:"author"`
+	Summary    string         `xml:"summary"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// RSSFeed handles RSSFeed request and writes the latest published articles as an RSS 2.0 feed,
+// with tag or author(a username) only the articles with the tag or by the author
+func (fh *FeedHandler) RSSFeed(w http.ResponseWriter, r *http.Request) {
+	f, ok := fh.feed(w, r)
+	if !ok {
+		return
+	}
+
+	rss := &rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       f.title,
+			Link:        f.link,
+			Description: fh.configs.SiteDescription,
+			Self:        rssAtomLink{Href: f.self, Rel: "self", Type: "application/rss+xml"},
+			Items:       []rssItem{},
+		},
+	}
+	if !f.updated.IsZero() {
+		rss.Channel.LastBuildDate = f.updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, article := range f.articles {
+		rss.Channel.Items = append(rss.Channel.Items, rssItem{
+			Title:       article.Title,
+			Link:        articleURL(fh.configs, &article),
+			GUID:        rssGUID{IsPermaLink: false, Value: "urn:uuid:" + article.ID},
+			Description: article.Excerpt,
+			Creator:     authorName(&article),
+			Categories:  article.Tags,
+			PubDate:     article.PublicationTime().UTC().Format(time.RFC1123Z),
+		})
+	}
+	writeXML(fh.logger, w, r, "application/rss+xml; charset=utf-8", rss, fh.repo.LastArticleChange())
+}
+
+// AtomFeed handles AtomFeed request and writes the latest published articles as an Atom feed,
+// with tag or author(a username) only the articles with the tag or by the author
+func (fh *FeedHandler) AtomFeed(w http.ResponseWriter, r *http.Request) {
+	f, ok := fh.feed(w, r)
+	if !ok {
+		return
+	}
+
+	// an empty feed was last updated when the server started
+	updated := f.updated
+	if updated.IsZero() {
+		updated = feedEpoch
+	}
+	atom := &atomFeed{
+		ID:       f.self,
+		Title:    f.title,
+		Subtitle: fh.configs.SiteDescription,
+		Updated:  updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.self, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.link, Rel: "alternate"},
+		},
+		Entries: []atomEntry{},
+	}
+	for _, article := range f.articles {
+		entry := atomEntry{
+			ID:        "urn:uuid:" + article.ID,
+			Title:     article.Title,
+			Link:      atomLink{Href: articleURL(fh.configs, &article), Rel: "alternate"},
+			Published: article.PublicationTime().UTC().Format(time.RFC3339),
+			Updated:   article.UpdatedAt.UTC().Format(time.RFC3339),
+			Author:    atomPerson{Name: authorName(&article)},
+			Summary:   article.Excerpt,
+		}
+		// atom entries must have an author
+		if entry.Author.Name == "" {
+			entry.Author.Name = fh.configs.SiteTitle
+		}
+		for _, tag := range article.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		atom.Entries = append(atom.Entries, entry)
+	}
+	writeXML(fh.logger, w, r, "application/atom+xml; charset=utf-8", atom, fh.repo.LastArticleChange())
+}
+
+// feedEpoch is when the server started, the last update of the feeds without articles
+var feedEpoch = time.Now()
+
+// feed fetches the articles of the feed requested, writing the response when the author is unknown
+func (fh *FeedHandler) feed(w http.ResponseWriter, r *http.Request) (*feed, bool) {
+	f := &feed{title: fh.configs.SiteTitle, link: fh.configs.PublicURL + "/"}
+	filter := data.PublishedFilter{}
+	query := url.Values{}
+
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		filter.Tag = tag
+		query.Set("tag", tag)
+		f.title += " - " + data.NormalizeTag(tag)
+		if fh.configs.FrontendEnabled {
+			f.link = fh.configs.PublicURL + "/tags/" + url.PathEscape(data.NormalizeTag(tag))
+		}
+	}
+	if username := r.URL.Query().Get("author"); username != "" {
+		user, err := fh.repo.GetUserByUsername(username)
+		if err != nil {
+			fh.logger.Debug(utils.ErrAuthorNotFound)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrAuthorNotFound}, w)
+			return nil, false
+		}
+		filter.Author = user.Email
+		query.Set("author", user.Username)
+		if user.DisplayName != "" {
+			f.title += " - " + user.DisplayName
+		} else {
+			f.title += " - " + user.Username
+		}
+		f.link = authorURL(fh.configs, user.Username)
+	}
+
+	f.self = fh.configs.PublicURL + r.URL.Path
+	if len(query) > 0 {
+		f.self += "?" + query.Encode()
+	}
+	f.articles, _ = fh.repo.GetPublishedArticles(filter, 1, fh.configs.FeedSize)
+	for _, article := range f.articles {
+		if article.UpdatedAt.After(f.updated) {
+			f.updated = article.UpdatedAt
+		}
+	}
+	return f, true
+}
+
+// writeXML writes the document with its ETag and the last change of the articles as its last modification, so
+// readers polling it get 304 Not Modified until the document changes. The newest article of the document is not
+// used as it moves back when that article is deleted or unpublished
+func writeXML(logger hclog.Logger, w http.ResponseWriter, r *http.Request, contentType string, v interface{}, modified time.Time) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		logger.Error("unable to generate the document", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	body = append([]byte(xml.Header), body...)
+
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	http.ServeContent(w, r, "", modified, bytes.NewReader(body))
+}
+
+// articleURL returns the public permanent link of an article, its page on the frontend when it is enabled
+func articleURL(c *utils.Configurations, article *data.Article) string {
+	if c.FrontendEnabled {
+		return c.PublicURL + "/articles/" + url.PathEscape(article.Slug)
+	}
+	return c.PublicURL + "/Article/Slug/" + url.PathEscape(article.Slug)
+}
+
+// authorURL returns the public page of an author, on the frontend when it is enabled
+func authorURL(c *utils.Configurations, username string) string {
+	if c.FrontendEnabled {
+		return c.PublicURL + "/authors/" + url.PathEscape(username)
+	}
+	return c.PublicURL + "/Author/" + url.PathEscape(username)
+}
+
+// authorName returns the name readers know the author of an article by
+func authorName(article *data.Article) string {
+	if article.AuthorProfile == nil {
+		return ""
+	}
+	if article.AuthorProfile.DisplayName != "" {
+		return article.AuthorProfile.DisplayName
+	}
+	return article.AuthorProfile.Username
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// FrontendHandler wraps instances needed to serve the published articles as the HTML pages of a public blog
+type FrontendHandler struct {
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	repo      data.Repository
+	templates map[string]*template.Template
+}
+
+// NewFrontendHandler returns a new FrontendHandler instance
+func NewFrontendHandler(l hclog.Logger, c *utils.Configurations, r data.Repository) *FrontendHandler {
+	funcs := template.FuncMap{
+		"articlePath": func(article data.Article) string { return "/articles/" + url.PathEscape(article.Slug) },
+		"tagPath":     func(tag string) string { return "/tags/" + url.PathEscape(tag) },
+		"authorPath": func(author *data.ArticleAuthor) string {
+			if author == nil || author.Username == "" {
+				return ""
+			}
+			return "/authors/" + url.PathEscape(author.Username)
+		},
+		"authorName": authorName,
+		"date":       func(t time.Time) string { return t.UTC().Format("January 2, 2006") },
+		"iso":        func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+	}
+	// every page is the layout with the content of the page
+	templates := map[string]*template.Template{}
+	for _, page := range []string{"list", "article", "notfound"} {
+		templates[page] = template.Must(template.New(page).Funcs(funcs).ParseFS(templateFiles, "templates/layout.html", "templates/"+page+".html"))
+	}
+	return &FrontendHandler{
+		logger:    l,
+		configs:   c,
+		repo:      r,
+		templates: templates,
+	}
+}
+
+// frontendPage is what the templates render
+type frontendPage struct {
+	SiteTitle    string
+	Title        string
+	Description  string
+	CanonicalURL string
+	FeedURL      string
+	OpenGraph    []data.MetaTag
+	TwitterCard  []data.MetaTag
+	Heading      string
+	Profile      *data.Profile
+	Articles     []data.Article
+	PreviousPage string
+	NextPage     string
+	Article      *data.Article
+	Content      template.HTML
+}
+
+// Home handles Home request and renders a page of the latest published articles
+func (fh *FrontendHandler) Home(w http.ResponseWriter, r *http.Request) {
+	page := fh.newPage("", fh.configs.SiteDescription, "/")
+	fh.renderList(w, r, page, data.PublishedFilter{})
+}
+
+// Tag handles Tag request and renders a page of the published articles with the tag
+func (fh *FrontendHandler) Tag(w http.ResponseWriter, r *http.Request) {
+	tag := data.NormalizeTag(mux.Vars(r)["tag"])
+	if tag == "" {
+		fh.notFound(w, r)
+		return
+	}
+	page := fh.newPage("#"+tag, "Articles tagged "+tag, "/tags/"+url.PathEscape(tag))
+	page.Heading = "#" + tag
+	page.FeedURL = "/feed.rss?tag=" + url.QueryEscape(tag)
+	fh.renderList(w, r, page, data.PublishedFilter{Tag: tag})
+}
+
+// Author handles Author request and renders the profile of the author with a page of their published articles
+func (fh *FrontendHandler) Author(w http.ResponseWriter, r *http.Request) {
+	user, err := fh.repo.GetUserByUsername(mux.Vars(r)["username"])
+	if err != nil {
+		fh.logger.Debug(utils.ErrAuthorNotFound)
+		fh.notFound(w, r)
+		return
+	}
+	profile := user.Profile()
+	name := profile.DisplayName
+	if name == "" {
+		name = profile.Username
+	}
+	description := profile.Bio
+	if description == "" {
+		description = "Articles by " + name
+	}
+	page := fh.newPage(name, description, "/authors/"+url.PathEscape(profile.Username))
+	page.Profile = &profile
+	page.FeedURL = "/feed.rss?author=" + url.QueryEscape(profile.Username)
+	fh.renderList(w, r, page, data.PublishedFilter{Author: user.Email})
+}
+
+// Article handles Article request and renders a published article, previous slugs are permanently redirected
+// to the current one and drafts are not found
+func (fh *FrontendHandler) Article(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+	article, err := fh.repo.GetArticleBySlug(slug)
+	if err != nil || !article.Published() {
+		fh.logger.Debug(utils.ErrArticleNotFound)
+		fh.notFound(w, r)
+		return
+	}
+	if article.Slug != slug {
+		http.Redirect(w, r, "/articles/"+url.PathEscape(article.Slug), http.StatusMovedPermanently)
+		return
+	}
+
+	seo := articleSEO(fh.configs, &article)
+	page := fh.newPage(article.Title, article.Excerpt, "")
+	page.CanonicalURL = seo.CanonicalURL
+	page.OpenGraph = seo.OpenGraph
+	page.TwitterCard = seo.TwitterCard
+	page.Article = &article
+	// the rendered content is sanitized when the article is written
+	page.Content = template.HTML(article.RenderedContent)
+	fh.render(w, r, http.StatusOK, "article", page)
+}
+
+func (fh *FrontendHandler) newPage(title string, description string, path string) *frontendPage {
+	page := &frontendPage{
+		SiteTitle:   fh.configs.SiteTitle,
+		Title:       title,
+		Description: description,
+		FeedURL:     "/feed.rss",
+	}
+	if path != "" {
+		page.CanonicalURL = fh.configs.PublicURL + path
+	}
+	return page
+}
+
+// renderList renders the page of the published articles matching the filter requested by the page query
+// parameter, pages after the last one are not found
+func (fh *FrontendHandler) renderList(w http.ResponseWriter, r *http.Request, page *frontendPage, filter data.PublishedFilter) {
+	pageNumber := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		var err error
+		if pageNumber, err = strconv.Atoi(p); err != nil || pageNumber < 1 {
+			fh.logger.Debug(utils.ErrInvalidPageNumber)
+			fh.notFound(w, r)
+			return
+		}
+	}
+	pageSize := fh.configs.FrontendPageSize
+	articles, total := fh.repo.GetPublishedArticles(filter, pageNumber, pageSize)
+	// the first page of the home page is there before anything is published, tags without articles are not
+	if len(articles) == 0 && (pageNumber > 1 || filter.Tag != "") {
+		fh.logger.Debug(utils.ErrInvalidPageNumber)
+		fh.notFound(w, r)
+		return
+	}
+	page.Articles = articles
+	path := r.URL.EscapedPath()
+	if pageNumber > 1 {
+		page.CanonicalURL += "?page=" + strconv.Itoa(pageNumber)
+		page.PreviousPage = path
+		if pageNumber > 2 {
+			page.PreviousPage += "?page=" + strconv.Itoa(pageNumber-1)
+		}
+	}
+	if pageNumber*pageSize < total {
+		page.NextPage = path + "?page=" + strconv.Itoa(pageNumber+1)
+	}
+	fh.render(w, r, http.StatusOK, "list", page)
+}
+
+func (fh *FrontendHandler) notFound(w http.ResponseWriter, r *http.Request) {
+	fh.render(w, r, http.StatusNotFound, "notfound", fh.newPage("Page not found", fh.configs.SiteDescription, ""))
+}
+
+// render executes the template into a buffer first, so a failing template does not send half a page
+func (fh *FrontendHandler) render(w http.ResponseWriter, r *http.Request, status int, name string, page *frontendPage) {
+	var body bytes.Buffer
+	if err := fh.templates[name].ExecuteTemplate(&body, "layout", page); err != nil {
+		fh.logger.Error("unable to render the page", "template", name, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if status == http.StatusOK {
+		w.Header().Set("Cache-Control", "public, max-age=60")
+	}
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		body.WriteTo(w)
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// InvitationHandler wraps instances needed by admins to invite users when registration is invite-only
+type InvitationHandler struct {
+	logger      hclog.Logger
+	configs     *utils.Configurations
+	validator   *data.Validation
+	repo        data.Repository
+	authService service.Authentication
+}
+
+// NewInvitationHandler returns a new InvitationHandler instance
+func NewInvitationHandler(l hclog.Logger, c *utils.Configurations, v *data.Validation, r data.Repository, auth service.Authentication) *InvitationHandler {
+	return &InvitationHandler{
+		logger:      l,
+		configs:     c,
+		validator:   v,
+		repo:        r,
+		authService: auth,
+	}
+}
+
+// CreatedInvitationResponse is returned once when an invitation is created, it is the only time the code is exposed
+type CreatedInvitationResponse struct {
+	Code       string           `json:"code"`
+	Invitation *data.Invitation `json:"invitation"`
+}
+
+// CreateInvitation handles CreateInvitation request. The invitation expires after the given number of hours
+// or the configured default, and the invited user gets the role preset of the invitation
+func (ih *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	invitation := &data.Invitation{}
+	err := data.FromJSON(invitation, r.Body)
+	if err != nil {
+		ih.logger.Error("deserialization of invitation json failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	errs := ih.validator.Validate(invitation)
+	if len(errs) != 0 {
+		ih.logger.Error("validation of invitation json failed", "error", errs)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: strings.Join(errs.Errors(), ",")}, w)
+		return
+	}
+
+	code, err := ih.authService.GenerateAPIKey()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		data.ToJSON(&GenericResponse{Status: false, Message: "Unable to create invitation.Please try again later"}, w)
+		return
+	}
+
+	if invitation.Role == "" {
+		invitation.Role = data.RoleUser
+	}
+	if invitation.ExpiresInHours == 0 {
+		invitation.ExpiresInHours = ih.configs.InvitationExpiration
+	}
+	invitation.CodeHash = ih.authService.HashAPIKey(code)
+	invitation.CreatedBy = r.Context().Value(UserIDKey{}).(string)
+	invitation.ExpiresAt = time.Now().Add(time.Duration(invitation.ExpiresInHours) * time.Hour)
+	invitation.UsedBy = ""
+	invitation.UsedAt = nil
+
+	createdInvitation, _ := ih.repo.CreateInvitation(invitation)
+
+	ih.logger.Debug("Invitation created successfully")
+	w.WriteHeader(http.StatusCreated)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Invitation created successfully", Data: &CreatedInvitationResponse{Code: code, Invitation: createdInvitation}}, w)
+}
+
+// GetInvitations handles GetInvitations request and lists all invitations
+func (ih *InvitationHandler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Invitations fetched successfully", Data: ih.repo.GetInvitations()}, w)
+}
+
+// DeleteInvitation handles DeleteInvitation request, an unused invitation can no longer be used to sign up
+func (ih *InvitationHandler) DeleteInvitation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	invitationID := mux.Vars(r)["invitationID"]
+	if err := ih.repo.DeleteInvitation(invitationID); err != nil {
+		ih.logger.Debug(utils.ErrInvitationNotFound, "id", invitationID)
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvitationNotFound}, w)
+		return
+	}
+
+	ih.logger.Debug("Invitation deleted successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Invitation deleted successfully"}, w)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// LockHandler wraps instances needed to manage the edit locks of articles
+type LockHandler struct {
+	logger  hclog.Logger
+	configs *utils.Configurations
+	repo    data.Repository
+}
+
+// NewLockHandler returns a new LockHandler instance
+func NewLockHandler(l hclog.Logger, c *utils.Configurations, r data.Repository) *LockHandler {
+	return &LockHandler{
+		logger:  l,
+		configs: c,
+		repo:    r,
+	}
+}
+
+// LockArticle handles LockArticle request. Contributors who can update the article acquire its edit lock
+// for EDIT_LOCK_TTL seconds, repeating the request is the heartbeat that renews the lock
+func (lh *LockHandler) LockArticle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	articleID := mux.Vars(r)["articleID"]
+	userID := r.Context().Value(UserIDKey{}).(string)
+	if !data.CanUpdateArticle(lh.repo.GetArticlePermission(articleID, userID)) {
+		lh.logger.Debug(utils.ErrCantUpdateOthersArticle)
+		w.WriteHeader(http.StatusForbidden)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCantUpdateOthersArticle}, w)
+		return
+	}
+
+	lock, err := lh.repo.AcquireEditLock(articleID, userID, time.Duration(lh.configs.EditLockTTL)*time.Second)
+	if err != nil {
+		lh.logger.Debug(err.Error())
+		if err.Error() == utils.ErrArticleLocked {
+			w.WriteHeader(http.StatusLocked)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error(), Data: lock}, w)
+		return
+	}
+
+	lh.logger.Debug("Article locked successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Article locked successfully", Data: lock}, w)
+}
+
+// UnlockArticle handles UnlockArticle request, the holder releases the edit lock when done editing
+func (lh *LockHandler) UnlockArticle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	articleID := mux.Vars(r)["articleID"]
+	userID := r.Context().Value(UserIDKey{}).(string)
+	if err := lh.repo.ReleaseEditLock(articleID, userID, false); err != nil {
+		lh.writeUnlockError(w, err)
+		return
+	}
+
+	lh.logger.Debug("Article unlocked successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Article unlocked successfully"}, w)
+}
+
+// ForceUnlockArticle handles ForceUnlockArticle request, admins release the edit lock of any user
+func (lh *LockHandler) ForceUnlockArticle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	articleID := mux.Vars(r)["articleID"]
+	adminID := r.Context().Value(UserIDKey{}).(string)
+	if err := lh.repo.ReleaseEditLock(articleID, adminID, true); err != nil {
+		recordAudit(lh.repo, r, adminID, data.AuditArticleUnlock, articleID, err.Error())
+		lh.writeUnlockError(w, err)
+		return
+	}
+
+	recordAudit(lh.repo, r, adminID, data.AuditArticleUnlock, articleID, "")
+	lh.logger.Debug("Article unlocked successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Article unlocked successfully"}, w)
+}
+
+// writeUnlockError writes the response of a failed unlock
+func (lh *LockHandler) writeUnlockError(w http.ResponseWriter, err error) {
+	lh.logger.Debug(err.Error())
+	if err.Error() == utils.ErrArticleLocked {
+		w.WriteHeader(http.StatusLocked)
+	} else {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+}
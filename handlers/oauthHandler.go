@@ -0,0 +1,513 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// OAuthHandler wraps instances needed to let third-party apps act on behalf of users
+type OAuthHandler struct {
+	logger      hclog.Logger
+	configs     *utils.Configurations
+	validator   *data.Validation
+	repo        data.Repository
+	authService service.Authentication
+	oauth       service.OAuth
+}
+
+// NewOAuthHandler returns a new OAuthHandler instance
+func NewOAuthHandler(l hclog.Logger, c *utils.Configurations, v *data.Validation, r data.Repository, auth service.Authentication, oauth service.OAuth) *OAuthHandler {
+	return &OAuthHandler{
+		logger:      l,
+		configs:     c,
+		validator:   v,
+		repo:        r,
+		authService: auth,
+		oauth:       oauth,
+	}
+}
+
+// AuthorizeRequest is the authorization request of a client, it is read from the query string
+// when asking for consent and from the JSON body when the user answers
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Approve             bool   `json:"approve"`
+}
+
+// AuthorizeInfo is shown to the user to decide whether the client gets access
+type AuthorizeInfo struct {
+	ClientID   string   `json:"clientID"`
+	ClientName string   `json:"clientName"`
+	Scopes     []string `json:"scopes"`
+	Consented  bool     `json:"consented"`
+}
+
+// AuthorizeResponse contains the client url the user has to be redirected to
+type AuthorizeResponse struct {
+	RedirectURL string `json:"redirectURL"`
+}
+
+// CreatedOAuthClientResponse is returned once when a client is registered, it is the only time the secret is exposed
+type CreatedOAuthClientResponse struct {
+	ClientSecret string            `json:"clientSecret,omitempty"`
+	Client       *data.OAuthClient `json:"client"`
+}
+
+// OAuthTokenResponse is the successful token endpoint response (RFC 6749 section 5.1)
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// OAuthError is the error response of the token, introspection and revocation endpoints (RFC 6749 section 5.2)
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// IntrospectionResponse is the token introspection response (RFC 7662 section 2.2)
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Nbf       int64  `json:"nbf,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+// RegisterClient handles RegisterClient request
+func (oh *OAuthHandler) RegisterClient(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	client := &data.OAuthClient{}
+	err := data.FromJSON(client, r.Body)
+	if err != nil {
+		oh.logger.Error("deserialization of oauth client json failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	errs := oh.validator.Validate(client)
+	if len(errs) != 0 {
+		oh.logger.Error("validation of oauth client json failed", "error", errs)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: strings.Join(errs.Errors(), ",")}, w)
+		return
+	}
+
+	// client secrets are random like API keys, so they are generated and hashed the same way
+	secret := ""
+	client.SecretHash = ""
+	if client.Confidential {
+		secret, err = oh.authService.GenerateAPIKey()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			data.ToJSON(&GenericResponse{Status: false, Message: "Unable to register the client.Please try again later"}, w)
+			return
+		}
+		client.SecretHash = oh.authService.HashAPIKey(secret)
+	}
+	client.Owner = r.Context().Value(UserIDKey{}).(string)
+
+	createdClient, _ := oh.repo.CreateOAuthClient(client)
+
+	oh.logger.Debug("OAuth client registered successfully")
+	w.WriteHeader(http.StatusCreated)
+	data.ToJSON(&GenericResponse{Status: true, Message: "OAuth client registered successfully", Data: &CreatedOAuthClientResponse{ClientSecret: secret, Client: createdClient}}, w)
+}
+
+// GetClients handles GetClients request and lists the clients registered by the current user
+func (oh *OAuthHandler) GetClients(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	clients := oh.repo.GetOAuthClientsByOwner(userID)
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "OAuth clients fetched successfully", Data: clients}, w)
+}
+
+// GetConsents handles GetConsents request and lists the apps the current user gave access to
+func (oh *OAuthHandler) GetConsents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	consents := oh.repo.GetOAuthConsentsByUser(userID)
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Consents fetched successfully", Data: consents}, w)
+}
+
+// RevokeConsent handles RevokeConsent request, the access tokens of the app are revoked and it has to ask the
+// user again for access
+func (oh *OAuthHandler) RevokeConsent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	clientID := mux.Vars(r)["clientID"]
+	userID := r.Context().Value(UserIDKey{}).(string)
+
+	if err := oh.repo.DeleteOAuthConsent(userID, clientID); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrOAuthConsentNotFound}, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Consent revoked successfully"}, w)
+}
+
+// GetAuthorization handles the authorization request of a client and returns what the user is asked to consent to
+func (oh *OAuthHandler) GetAuthorization(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	authReq := &AuthorizeRequest{
+		ResponseType:        q.Get("response_type"),
+		ClientID:            q.Get("client_id"),
+		RedirectURI:         q.Get("redirect_uri"),
+		Scope:               q.Get("scope"),
+		State:               q.Get("state"),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+	}
+
+	client, scopes, errMsg := oh.validateAuthorizeRequest(authReq)
+	if errMsg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: errMsg}, w)
+		return
+	}
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	consent, err := oh.repo.GetOAuthConsent(userID, client.ID)
+	consented := err == nil && isSubset(scopes, consent.Scopes)
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Authorization request is valid", Data: &AuthorizeInfo{
+		ClientID:   client.ID,
+		ClientName: client.Name,
+		Scopes:     scopes,
+		Consented:  consented,
+	}}, w)
+}
+
+// Authorize handles the answer of the user to an authorization request. On approval the consent is recorded
+// and an authorization code is issued, either way the user is sent back to the client with the result
+func (oh *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	authReq := &AuthorizeRequest{}
+	if err := data.FromJSON(authReq, r.Body); err != nil {
+		oh.logger.Error("deserialization of authorization request failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	client, scopes, errMsg := oh.validateAuthorizeRequest(authReq)
+	if errMsg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: errMsg}, w)
+		return
+	}
+
+	redirect, _ := url.Parse(authReq.RedirectURI)
+	params := redirect.Query()
+	if authReq.State != "" {
+		params.Set("state", authReq.State)
+	}
+
+	if !authReq.Approve {
+		oh.logger.Debug("user denied the authorization request", "client", client.ID)
+		params.Set("error", "access_denied")
+		redirect.RawQuery = params.Encode()
+		w.WriteHeader(http.StatusOK)
+		data.ToJSON(&GenericResponse{Status: true, Message: "Authorization denied", Data: &AuthorizeResponse{RedirectURL: redirect.String()}}, w)
+		return
+	}
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	consent := &data.OAuthConsent{UserID: userID, ClientID: client.ID, Scopes: scopes}
+	if existing, err := oh.repo.GetOAuthConsent(userID, client.ID); err == nil {
+		consent.Scopes = mergeScopes(existing.Scopes, scopes)
+	}
+	oh.repo.SaveOAuthConsent(consent)
+
+	code, err := oh.oauth.IssueAuthorizationCode(&service.OAuthGrant{
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   authReq.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: authReq.CodeChallenge,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		data.ToJSON(&GenericResponse{Status: false, Message: "Unable to authorize the client.Please try again later"}, w)
+		return
+	}
+
+	params.Set("code", code)
+	redirect.RawQuery = params.Encode()
+
+	oh.logger.Debug("client authorized", "client", client.ID)
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Authorization granted", Data: &AuthorizeResponse{RedirectURL: redirect.String()}}, w)
+}
+
+// validateAuthorizeRequest checks the request against the registered client and returns the requested scopes.
+// Errors are not redirected to the client as the redirect uri itself may not be trustworthy
+func (oh *OAuthHandler) validateAuthorizeRequest(authReq *AuthorizeRequest) (*data.OAuthClient, []string, string) {
+
+	if authReq.ResponseType != "code" {
+		return nil, nil, utils.ErrOAuthUnsupportedResponseType
+	}
+
+	client, err := oh.repo.GetOAuthClient(authReq.ClientID)
+	if err != nil {
+		return nil, nil, utils.ErrOAuthClientNotFound
+	}
+
+	if !validRedirectURI(client, authReq.RedirectURI) {
+		return nil, nil, utils.ErrOAuthInvalidRedirectURI
+	}
+
+	scopes := service.ParseScope(authReq.Scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	if !isSubset(scopes, client.Scopes) {
+		return nil, nil, utils.ErrOAuthInvalidScope
+	}
+
+	if authReq.CodeChallenge == "" || authReq.CodeChallengeMethod != "S256" {
+		return nil, nil, utils.ErrOAuthPKCERequired
+	}
+
+	return client, scopes, ""
+}
+
+// Token handles the token endpoint for the authorization_code and client_credentials grants (RFC 6749 section 3.2)
+func (oh *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	client, ok := oh.authenticateClient(r)
+	if !ok {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	var userID string
+	var scopes []string
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		grant, err := oh.oauth.RedeemAuthorizationCode(r.PostForm.Get("code"), client.ID, r.PostForm.Get("redirect_uri"), r.PostForm.Get("code_verifier"))
+		if err != nil {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", err.Error())
+			return
+		}
+		// the user may have withdrawn the consent before the code was redeemed
+		if consent, err := oh.repo.GetOAuthConsent(grant.UserID, client.ID); err != nil || !isSubset(grant.Scopes, consent.Scopes) {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", utils.ErrOAuthConsentNotFound)
+			return
+		}
+		userID = grant.UserID
+		scopes = grant.Scopes
+
+	case "client_credentials":
+		if !client.Confidential {
+			writeOAuthError(w, http.StatusBadRequest, "unauthorized_client", "Public clients can not use the client_credentials grant")
+			return
+		}
+		scopes = service.ParseScope(r.PostForm.Get("scope"))
+		if len(scopes) == 0 {
+			scopes = client.Scopes
+		}
+		if !isSubset(scopes, client.Scopes) {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_scope", utils.ErrOAuthInvalidScope)
+			return
+		}
+		// the client acts on its own behalf, which is the account that registered it
+		userID = client.Owner
+
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+		return
+	}
+
+	accessToken, err := oh.authService.GenerateScopedAccessToken(userID, client.ID, scopes)
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	// tokens issued on behalf of a user are revoked with the consent of the user
+	if r.PostForm.Get("grant_type") == "authorization_code" {
+		claims, err := oh.authService.ParseAccessToken(accessToken)
+		if err != nil {
+			writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+		oh.repo.AddConsentToken(userID, client.ID, claims.Id, time.Unix(claims.ExpiresAt, 0))
+	}
+
+	oh.logger.Debug("issued access token to client", "client", client.ID, "grant", r.PostForm.Get("grant_type"))
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   oh.configs.JwtExpiration * 60,
+		Scope:       service.FormatScope(scopes),
+	}, w)
+}
+
+// Introspect handles token introspection requests of confidential clients (RFC 7662).
+// A client can only introspect the tokens issued to itself
+func (oh *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	client, ok := oh.authenticateClient(r)
+	if !ok || !client.Confidential {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	claims, err := oh.authService.ParseAccessToken(r.PostForm.Get("token"))
+	if err != nil || claims.ClientID != client.ID || oh.repo.IsTokenRevoked(claims.Id) {
+		w.WriteHeader(http.StatusOK)
+		data.ToJSON(&IntrospectionResponse{Active: false}, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		Username:  claims.UserID,
+		TokenType: "Bearer",
+		Exp:       claims.ExpiresAt,
+		Iat:       claims.IssuedAt,
+		Nbf:       claims.NotBefore,
+		Sub:       claims.Subject,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.Id,
+	}, w)
+}
+
+// Revoke handles token revocation requests (RFC 7009). Invalid tokens and tokens of other
+// clients are ignored, the response is the same so it can not be used to probe tokens
+func (oh *OAuthHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	client, ok := oh.authenticateClient(r)
+	if !ok {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	claims, err := oh.authService.ParseAccessToken(r.PostForm.Get("token"))
+	if err == nil && claims.ClientID == client.ID {
+		oh.repo.RevokeToken(claims.Id, time.Unix(claims.ExpiresAt, 0))
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// authenticateClient identifies the client with HTTP basic auth or the client_id and client_secret form parameters.
+// Confidential clients must present their secret, public clients are identified by their id alone
+func (oh *OAuthHandler) authenticateClient(r *http.Request) (*data.OAuthClient, bool) {
+
+	if err := r.ParseForm(); err != nil {
+		return nil, false
+	}
+
+	clientID, secret, hasBasicAuth := r.BasicAuth()
+	if hasBasicAuth {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	client, err := oh.repo.GetOAuthClient(clientID)
+	if err != nil {
+		oh.logger.Debug("unknown oauth client", "client", clientID)
+		return nil, false
+	}
+
+	if client.Confidential {
+		hash := oh.authService.HashAPIKey(secret)
+		if secret == "" || subtle.ConstantTimeCompare([]byte(hash), []byte(client.SecretHash)) != 1 {
+			oh.logger.Debug("invalid oauth client secret", "client", clientID)
+			return nil, false
+		}
+	}
+	return client, true
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code string, description string) {
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	w.WriteHeader(status)
+	data.ToJSON(&OAuthError{Error: code, ErrorDescription: description}, w)
+}
+
+// validRedirectURI reports whether the redirect uri is registered for the client. Only exact matches are accepted,
+// prefixes or wildcards would let an attacker redirect the authorization code to a page they control
+func validRedirectURI(client *data.OAuthClient, redirectURI string) bool {
+	for _, registered := range client.RedirectURIs {
+		if registered == redirectURI {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubset reports whether all scopes are contained in allowed
+func isSubset(scopes []string, allowed []string) bool {
+	for _, s := range scopes {
+		if !containsScope(allowed, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeScopes returns the union of both scope lists
+func mergeScopes(a []string, b []string) []string {
+	result := append([]string{}, a...)
+	for _, s := range b {
+		if !containsScope(result, s) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// OIDCHandler wraps instances needed to login users with an external identity provider
+type OIDCHandler struct {
+	logger      hclog.Logger
+	configs     *utils.Configurations
+	repo        data.Repository
+	authService service.Authentication
+	oidc        service.OIDC
+}
+
+// NewOIDCHandler returns a new OIDCHandler instance
+func NewOIDCHandler(l hclog.Logger, c *utils.Configurations, r data.Repository, auth service.Authentication, oidc service.OIDC) *OIDCHandler {
+	return &OIDCHandler{
+		logger:      l,
+		configs:     c,
+		repo:        r,
+		authService: auth,
+		oidc:        oidc,
+	}
+}
+
+// Login handles the OIDC login request and redirects the user to the identity provider
+func (oh *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+
+	if !oh.oidc.Enabled() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrOIDCNotConfigured}, w)
+		return
+	}
+
+	authURL, err := oh.oidc.AuthCodeURL()
+	if err != nil {
+		oh.logger.Error("unable to start oidc login", "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrOIDCLoginFailed}, w)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback handles the redirect back from the identity provider. The user is looked up by the
+// provider identity, provisioned on first login, and receives the access and refresh tokens of this service
+func (oh *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if providerErr := r.FormValue("error"); providerErr != "" {
+		oh.logger.Debug("identity provider returned an error", "error", providerErr, "description", r.FormValue("error_description"))
+		w.WriteHeader(http.StatusUnauthorized)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrOIDCLoginFailed}, w)
+		return
+	}
+
+	identity, err := oh.oidc.Exchange(r.FormValue("state"), r.FormValue("code"))
+	if err != nil {
+		oh.logger.Error("oidc login failed", "error", err)
+		if strings.Contains(err.Error(), utils.ErrOIDCInvalidState) {
+			w.WriteHeader(http.StatusBadRequest)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrOIDCInvalidState}, w)
+		} else {
+			w.WriteHeader(http.StatusUnauthorized)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrOIDCLoginFailed}, w)
+		}
+		return
+	}
+
+	user, err := oh.userForIdentity(identity)
+	if err != nil {
+		oh.logger.Error("unable to map identity to a user", "error", err)
+		if strings.Contains(err.Error(), utils.ErrOIDCAccountConflict) {
+			w.WriteHeader(http.StatusConflict)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrOIDCAccountConflict}, w)
+		} else if strings.Contains(err.Error(), utils.ErrRegistrationClosed) {
+			w.WriteHeader(http.StatusForbidden)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrRegistrationClosed}, w)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.UserCreationFailed}, w)
+		}
+		return
+	}
+
+	recordAudit(oh.repo, r, user.Email, data.AuditLogin, user.Email, writeAuthResponse(oh.logger, oh.authService, w, user))
+}
+
+// userForIdentity returns the user linked to the identity. Unknown identities get a new account without a
+// password unless registration is not open, an existing local account is only linked when the provider verified
+// that the email belongs to the user
+func (oh *OIDCHandler) userForIdentity(identity *service.OIDCIdentity) (*data.User, error) {
+
+	user, err := oh.repo.GetUserByEmail(identity.Email)
+	if err != nil {
+		if oh.configs.RegistrationMode != utils.RegistrationOpen {
+			return nil, errors.New(utils.ErrRegistrationClosed)
+		}
+		oh.logger.Debug("provisioning user for oidc identity", "email", identity.Email)
+		user = &data.User{
+			Email:       identity.Email,
+			TokenHash:   utils.GenerateRandomString(15),
+			OIDCSubject: identity.Subject,
+		}
+		if identity.EmailVerified && oh.configs.IsAdminEmail(identity.Email) {
+			user.Role = data.RoleAdmin
+		}
+		if err := oh.repo.Create(user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	if user.OIDCSubject == identity.Subject {
+		return user, nil
+	}
+	if user.OIDCSubject != "" || !identity.EmailVerified {
+		return nil, errors.New(utils.ErrOIDCAccountConflict)
+	}
+
+	oh.logger.Debug("linking existing user to oidc identity", "email", identity.Email)
+	user.OIDCSubject = identity.Subject
+	if err := oh.repo.UpdateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
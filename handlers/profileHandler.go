@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// ProfileHandler wraps instances needed to show and edit user profiles
+type ProfileHandler struct {
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *data.Validation
+	repo      data.Repository
+}
+
+// NewProfileHandler returns a new ProfileHandler instance
+func NewProfileHandler(l hclog.Logger, c *utils.Configurations, v *data.Validation, r data.Repository) *ProfileHandler {
+	return &ProfileHandler{
+		logger:    l,
+		configs:   c,
+		validator: v,
+		repo:      r,
+	}
+}
+
+// OwnProfileResponse is the profile of the signed in user, the only place where the email is shown
+type OwnProfileResponse struct {
+	Email string `json:"email"`
+	data.Profile
+}
+
+// AuthorPageResponse is the public page of an author, Articles are summaries unless the whole articles were asked
+type AuthorPageResponse struct {
+	Profile  data.Profile `json:"profile"`
+	Articles interface{}  `json:"articles"`
+}
+
+// GetProfile handles GetProfile request and fetches the profile of the current user
+func (ph *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	user, err := ph.repo.GetUserByEmail(userID)
+	if err != nil {
+		ph.logger.Debug(utils.ErrUserNotFound)
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrUserNotFound}, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Profile fetched successfully", Data: &OwnProfileResponse{Email: user.Email, Profile: user.Profile()}}, w)
+}
+
+// UpdateProfile handles UpdateProfile request
+func (ph *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profile := &data.Profile{}
+	err := data.FromJSON(profile, r.Body)
+	if err != nil {
+		ph.logger.Error("deserialization of profile json failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	errs := ph.validator.Validate(profile)
+	if len(errs) != 0 {
+		ph.logger.Error("validation of profile json failed", "error", errs)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: strings.Join(errs.Errors(), ",")}, w)
+		return
+	}
+
+	userID := r.Context().Value(UserIDKey{}).(string)
+	user, err := ph.repo.UpdateProfile(userID, profile)
+	if err != nil {
+		ph.logger.Debug("unable to update profile", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return
+	}
+
+	ph.logger.Debug("Profile updated successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Profile updated successfully", Data: &OwnProfileResponse{Email: user.Email, Profile: user.Profile()}}, w)
+}
+
+// GetAuthor handles GetAuthor request and fetches the public page of an author with a page of the summaries
+// of their articles, or of the whole articles with full=true
+func (ph *ProfileHandler) GetAuthor(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	username := mux.Vars(r)["username"]
+	user, err := ph.repo.GetUserByUsername(username)
+	if err != nil {
+		ph.logger.Debug(utils.ErrAuthorNotFound)
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrAuthorNotFound}, w)
+		return
+	}
+
+	pageNumber := 1
+	if pageID := r.FormValue("pageid"); pageID != "" {
+		pageNumber, err = strconv.Atoi(pageID)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidPageNumber}, w)
+			return
+		}
+	}
+
+	articles, err := ph.repo.GetArticlesByAuthor(user.Email, pageNumber, ph.configs.PageSize)
+	if err != nil {
+		ph.logger.Debug(utils.ErrInvalidPageNumber)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidPageNumber}, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Author fetched successfully", Data: &AuthorPageResponse{Profile: user.Profile(), Articles: articleListing(articles, isFullListing(r), false)}}, w)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// SEOHandler wraps instances needed to make the published articles visible to search engines
+type SEOHandler struct {
+	logger  hclog.Logger
+	configs *utils.Configurations
+	repo    data.Repository
+}
+
+// NewSEOHandler returns a new SEOHandler instance
+func NewSEOHandler(l hclog.Logger, c *utils.Configurations, r data.Repository) *SEOHandler {
+	return &SEOHandler{
+		logger:  l,
+		configs: c,
+		repo:    r,
+	}
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// sitemapEntry is a public page with its last modification
+type sitemapEntry struct {
+	loc     string
+	updated time.Time
+}
+
+// Sitemap handles Sitemap request and writes the sitemap of the home page and the published articles. Above
+// SITEMAP_SIZE(at most 50000) URLs it writes a sitemap index of the sitemaps of SitemapPage instead
+func (sh *SEOHandler) Sitemap(w http.ResponseWriter, r *http.Request) {
+	entries := sh.sitemapEntries()
+	size := sh.configs.SitemapSize
+	if len(entries) <= size {
+		sh.writeURLSet(w, r, entries)
+		return
+	}
+
+	index := &sitemapIndex{}
+	for start := 0; start < len(entries); start += size {
+		page := entries[start:minInt(start+size, len(entries))]
+		index.Sitemaps = append(index.Sitemaps, sitemapURL{
+			Loc:     sh.configs.PublicURL + "/sitemap-" + strconv.Itoa(start/size+1) + ".xml",
+			LastMod: lastUpdate(page).UTC().Format(time.RFC3339),
+		})
+	}
+	writeXML(sh.logger, w, r, "application/xml; charset=utf-8", index, sh.repo.LastArticleChange())
+}
+
+// SitemapPage handles SitemapPage request and writes one of the sitemaps listed by the sitemap index
+func (sh *SEOHandler) SitemapPage(w http.ResponseWriter, r *http.Request) {
+	entries := sh.sitemapEntries()
+	size := sh.configs.SitemapSize
+	page, err := strconv.Atoi(mux.Vars(r)["page"])
+	start := (page - 1) * size
+	if err != nil || page < 1 || start >= len(entries) {
+		sh.logger.Debug(utils.ErrInvalidPageNumber)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidPageNumber}, w)
+		return
+	}
+	sh.writeURLSet(w, r, entries[start:minInt(start+size, len(entries))])
+}
+
+// sitemapEntries lists the home page and the published articles, the oldest articles first so the
+// sitemaps of a split sitemap only change at the end as articles are published
+func (sh *SEOHandler) sitemapEntries() []sitemapEntry {
+	articles, _ := sh.repo.GetPublishedArticles(data.PublishedFilter{}, 1, 0)
+	entries := make([]sitemapEntry, 0, len(articles)+1)
+	entries = append(entries, sitemapEntry{loc: sh.configs.PublicURL + "/"})
+	for i := len(articles) - 1; i >= 0; i-- {
+		entries = append(entries, sitemapEntry{loc: articleURL(sh.configs, &articles[i]), updated: articles[i].UpdatedAt})
+		if articles[i].UpdatedAt.After(entries[0].updated) {
+			entries[0].updated = articles[i].UpdatedAt
+		}
+	}
+	return entries
+}
+
+func (sh *SEOHandler) writeURLSet(w http.ResponseWriter, r *http.Request, entries []sitemapEntry) {
+	urlSet := &sitemapURLSet{URLs: []sitemapURL{}}
+	for _, entry := range entries {
+		u := sitemapURL{Loc: entry.loc}
+		if !entry.updated.IsZero() {
+			u.LastMod = entry.updated.UTC().Format(time.RFC3339)
+		}
+		urlSet.URLs = append(urlSet.URLs, u)
+	}
+	writeXML(sh.logger, w, r, "application/xml; charset=utf-8", urlSet, sh.repo.LastArticleChange())
+}
+
+func lastUpdate(entries []sitemapEntry) time.Time {
+	var updated time.Time
+	for _, entry := range entries {
+		if entry.updated.After(updated) {
+			updated = entry.updated
+		}
+	}
+	return updated
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// articleSEO returns the canonical URL, Open Graph and Twitter card metadata of a published article,
+// drafts have none as they are not public
+func articleSEO(c *utils.Configurations, article *data.Article) *data.ArticleSEO {
+	if !article.Published() {
+		return nil
+	}
+	canonical := articleURL(c, article)
+	description := article.Excerpt
+
+	seo := &data.ArticleSEO{
+		CanonicalURL: canonical,
+		OpenGraph: []data.MetaTag{
+			{Name: "og:type", Content: "article"},
+			{Name: "og:site_name", Content: c.SiteTitle},
+			{Name: "og:title", Content: article.Title},
+			{Name: "og:description", Content: description},
+			{Name: "og:url", Content: canonical},
+			{Name: "article:published_time", Content: article.PublicationTime().UTC().Format(time.RFC3339)},
+			{Name: "article:modified_time", Content: article.UpdatedAt.UTC().Format(time.RFC3339)},
+		},
+		TwitterCard: []data.MetaTag{
+			{Name: "twitter:card", Content: "summary"},
+			{Name: "twitter:title", Content: article.Title},
+			{Name: "twitter:description", Content: description},
+		},
+	}
+	if article.AuthorProfile != nil && article.AuthorProfile.Username != "" {
+		seo.OpenGraph = append(seo.OpenGraph, data.MetaTag{Name: "article:author", Content: authorURL(c, article.AuthorProfile.Username)})
+	}
+	for _, tag := range article.Tags {
+		seo.OpenGraph = append(seo.OpenGraph, data.MetaTag{Name: "article:tag", Content: tag})
+	}
+	return seo
+}
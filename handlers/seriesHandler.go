@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// SeriesHandler wraps instances needed to manage series of articles
+type SeriesHandler struct {
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *data.Validation
+	repo      data.Repository
+}
+
+// NewSeriesHandler returns a new SeriesHandler instance
+func NewSeriesHandler(l hclog.Logger, c *utils.Configurations, v *data.Validation, r data.Repository) *SeriesHandler {
+	return &SeriesHandler{
+		logger:    l,
+		configs:   c,
+		validator: v,
+		repo:      r,
+	}
+}
+
+// SeriesArticleRequest is the request body of AddArticle and RemoveArticle, Position starts at 1 and adds the
+// article at the end of the series when it is left out
+type SeriesArticleRequest struct {
+	SeriesID  string `json:"seriesID" validate:"required"`
+	ArticleID string `json:"articleID" validate:"required"`
+	Position  int    `json:"position" validate:"min=0"`
+}
+
+// SeriesOrderRequest is the request body of ReorderSeries, ArticleIDs lists every article of the series in the new order
+type SeriesOrderRequest struct {
+	ID         string   `json:"ID" validate:"required"`
+	ArticleIDs []string `json:"articleIDs" validate:"required"`
+}
+
+// SeriesResponse is a series with the summaries of its articles in order
+type SeriesResponse struct {
+	*data.Series
+	Articles []data.ArticleSummary `json:"articles"`
+}
+
+// CreateSeries handles CreateSeries request, the optional articleIDs are the first articles of the series
+func (sh *SeriesHandler) CreateSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	newSeries := &data.Series{}
+	if !sh.decode(w, r, newSeries) {
+		return
+	}
+
+	newSeries.Owner = r.Context().Value(UserIDKey{}).(string)
+	createdSeries, err := sh.repo.CreateSeries(newSeries)
+	if err != nil {
+		sh.writeSeriesError(w, err)
+		return
+	}
+
+	sh.logger.Debug("Series created successfully")
+	w.WriteHeader(http.StatusCreated)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Series created successfully", Data: createdSeries}, w)
+}
+
+// GetSeries handles GetSeries request and fetches a series with its articles in order, drafts are only listed
+// for their contributors
+func (sh *SeriesHandler) GetSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	found, err := sh.repo.GetSeriesByID(mux.Vars(r)["seriesID"])
+	if err != nil {
+		sh.writeSeriesError(w, err)
+		return
+	}
+
+	response := &SeriesResponse{Series: found, Articles: []data.ArticleSummary{}}
+	articleIDs := []string{}
+	for _, articleID := range found.ArticleIDs {
+		if article, err := sh.repo.GetArticleByID(articleID); err == nil && visible(sh.repo, r, &article) {
+			articleIDs = append(articleIDs, articleID)
+			response.Articles = append(response.Articles, article.Summary())
+		}
+	}
+	found.ArticleIDs = articleIDs
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Series fetched successfully", Data: response}, w)
+}
+
+// UpdateSeries handles UpdateSeries request and updates the title and description of a series of the current user
+func (sh *SeriesHandler) UpdateSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	newSeries := &data.Series{}
+	if !sh.decode(w, r, newSeries) || !sh.checkOwner(w, r, newSeries.ID) {
+		return
+	}
+
+	updatedSeries, err := sh.repo.UpdateSeries(newSeries)
+	if err != nil {
+		sh.writeSeriesError(w, err)
+		return
+	}
+
+	sh.logger.Debug("Series updated successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Series updated successfully", Data: updatedSeries}, w)
+}
+
+// DeleteSeries handles DeleteSeries request and deletes a series of the current user, its articles are kept
+func (sh *SeriesHandler) DeleteSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	seriesID := mux.Vars(r)["seriesID"]
+	if !sh.checkOwner(w, r, seriesID) {
+		return
+	}
+
+	if err := sh.repo.DeleteSeries(seriesID); err != nil {
+		sh.writeSeriesError(w, err)
+		return
+	}
+
+	sh.logger.Debug("Series deleted successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Series deleted successfully"}, w)
+}
+
+// AddArticle handles AddArticle request and adds an article of the current user to their series
+func (sh *SeriesHandler) AddArticle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	request := &SeriesArticleRequest{}
+	if !sh.decode(w, r, request) || !sh.checkOwner(w, r, request.SeriesID) {
+		return
+	}
+
+	updatedSeries, err := sh.repo.AddArticleToSeries(request.SeriesID, request.ArticleID, request.Position)
+	if err != nil {
+		sh.writeSeriesError(w, err)
+		return
+	}
+
+	sh.logger.Debug("Article added to series successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Article added to series successfully", Data: updatedSeries}, w)
+}
+
+// RemoveArticle handles RemoveArticle request and removes an article from a series of the current user
+func (sh *SeriesHandler) RemoveArticle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	request := &SeriesArticleRequest{}
+	if !sh.decode(w, r, request) || !sh.checkOwner(w, r, request.SeriesID) {
+		return
+	}
+
+	updatedSeries, err := sh.repo.RemoveArticleFromSeries(request.SeriesID, request.ArticleID)
+	if err != nil {
+		sh.writeSeriesError(w, err)
+		return
+	}
+
+	sh.logger.Debug("Article removed from series successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Article removed from series successfully", Data: updatedSeries}, w)
+}
+
+// ReorderSeries handles ReorderSeries request and sets the order of the articles of a series of the current user
+func (sh *SeriesHandler) ReorderSeries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	request := &SeriesOrderRequest{}
+	if !sh.decode(w, r, request) || !sh.checkOwner(w, r, request.ID) {
+		return
+	}
+
+	updatedSeries, err := sh.repo.ReorderSeries(request.ID, request.ArticleIDs)
+	if err != nil {
+		sh.writeSeriesError(w, err)
+		return
+	}
+
+	sh.logger.Debug("Series reordered successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Series reordered successfully", Data: updatedSeries}, w)
+}
+
+// checkOwner checks that the series exists and belongs to the current user, it writes the error response when it does not
+func (sh *SeriesHandler) checkOwner(w http.ResponseWriter, r *http.Request, seriesID string) bool {
+	found, err := sh.repo.GetSeriesByID(seriesID)
+	if err != nil {
+		sh.writeSeriesError(w, err)
+		return false
+	}
+	if found.Owner != r.Context().Value(UserIDKey{}).(string) {
+		sh.logger.Debug(utils.ErrCantUpdateOthersSeries)
+		w.WriteHeader(http.StatusForbidden)
+		data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrCantUpdateOthersSeries}, w)
+		return false
+	}
+	return true
+}
+
+// decode reads and validates the JSON body of the request, it writes the error response when it fails
+func (sh *SeriesHandler) decode(w http.ResponseWriter, r *http.Request, request interface{}) bool {
+	if err := data.FromJSON(request, r.Body); err != nil {
+		sh.logger.Error("deserialization of series json failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return false
+	}
+
+	if errs := sh.validator.Validate(request); len(errs) != 0 {
+		sh.logger.Error("validation of series json failed", "error", errs)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: strings.Join(errs.Errors(), ",")}, w)
+		return false
+	}
+	return true
+}
+
+// writeSeriesError writes the response of a failed series operation
+func (sh *SeriesHandler) writeSeriesError(w http.ResponseWriter, err error) {
+	sh.logger.Debug(err.Error())
+	switch err.Error() {
+	case utils.ErrSeriesNotFound, utils.ErrArticleNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case utils.ErrCantAddOthersArticle:
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// default and maximum number of tags suggested by the autocomplete
+const (
+	defaultTagSuggestions = 10
+	maxTagSuggestions     = 50
+)
+
+// TagHandler wraps instances needed to list tags and by admins to manage them
+type TagHandler struct {
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *data.Validation
+	repo      data.Repository
+}
+
+// NewTagHandler returns a new TagHandler instance
+func NewTagHandler(l hclog.Logger, c *utils.Configurations, v *data.Validation, r data.Repository) *TagHandler {
+	return &TagHandler{
+		logger:    l,
+		configs:   c,
+		validator: v,
+		repo:      r,
+	}
+}
+
+// TagRenameRequest is the request body of RenameTag
+type TagRenameRequest struct {
+	Name    string `json:"name" validate:"required"`
+	NewName string `json:"newName" validate:"required"`
+}
+
+// TagMergeRequest is the request body of MergeTags, the sources are merged into the target
+type TagMergeRequest struct {
+	Sources []string `json:"sources" validate:"required,min=1"`
+	Target  string   `json:"target" validate:"required"`
+}
+
+// GetTags handles GetTags request and lists all tags in use with the number of their articles
+func (th *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Tags fetched successfully", Data: th.repo.GetTags()}, w)
+}
+
+// SuggestTags handles SuggestTags request and lists the most used tags starting with the prefix parameter,
+// the optional limit parameter is 10 by default and at most 50
+func (th *TagHandler) SuggestTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit := defaultTagSuggestions
+	if value := r.FormValue("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			data.ToJSON(&GenericResponse{Status: false, Message: utils.ErrInvalidLimit}, w)
+			return
+		}
+		if limit > maxTagSuggestions {
+			limit = maxTagSuggestions
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Tags fetched successfully", Data: th.repo.SearchTags(r.FormValue("prefix"), limit)}, w)
+}
+
+// RenameTag handles RenameTag request and renames a tag on all its articles, articles written later
+// with the old name get the new one
+func (th *TagHandler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rename := &TagRenameRequest{}
+	if !th.decode(w, r, rename) {
+		return
+	}
+
+	adminID := r.Context().Value(UserIDKey{}).(string)
+	tag, err := th.repo.RenameTag(rename.Name, rename.NewName)
+	if err != nil {
+		recordAudit(th.repo, r, adminID, data.AuditTagRename, rename.Name, err.Error())
+		th.writeTagError(w, err)
+		return
+	}
+
+	recordAudit(th.repo, r, adminID, data.AuditTagRename, rename.Name, "")
+	th.logger.Debug("Tag renamed successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Tag renamed successfully", Data: tag}, w)
+}
+
+// MergeTags handles MergeTags request and replaces the source tags by the target tag on all their articles
+func (th *TagHandler) MergeTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	merge := &TagMergeRequest{}
+	if !th.decode(w, r, merge) {
+		return
+	}
+
+	adminID := r.Context().Value(UserIDKey{}).(string)
+	target := strings.Join(merge.Sources, ",") + "->" + merge.Target
+	tag, err := th.repo.MergeTags(merge.Sources, merge.Target)
+	if err != nil {
+		recordAudit(th.repo, r, adminID, data.AuditTagMerge, target, err.Error())
+		th.writeTagError(w, err)
+		return
+	}
+
+	recordAudit(th.repo, r, adminID, data.AuditTagMerge, target, "")
+	th.logger.Debug("Tags merged successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Tags merged successfully", Data: tag}, w)
+}
+
+// DeleteTag handles DeleteTag request and removes a tag from all its articles
+func (th *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	name := mux.Vars(r)["tag"]
+	adminID := r.Context().Value(UserIDKey{}).(string)
+	if err := th.repo.DeleteTag(name); err != nil {
+		recordAudit(th.repo, r, adminID, data.AuditTagDelete, name, err.Error())
+		th.writeTagError(w, err)
+		return
+	}
+
+	recordAudit(th.repo, r, adminID, data.AuditTagDelete, name, "")
+	th.logger.Debug("Tag deleted successfully")
+	w.WriteHeader(http.StatusOK)
+	data.ToJSON(&GenericResponse{Status: true, Message: "Tag deleted successfully"}, w)
+}
+
+// decode reads and validates the JSON body of the request, it writes the error response when it fails
+func (th *TagHandler) decode(w http.ResponseWriter, r *http.Request, request interface{}) bool {
+	if err := data.FromJSON(request, r.Body); err != nil {
+		th.logger.Error("deserialization of tag request json failed", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+		return false
+	}
+
+	if errs := th.validator.Validate(request); len(errs) != 0 {
+		th.logger.Error("validation of tag request json failed", "error", errs)
+		w.WriteHeader(http.StatusBadRequest)
+		data.ToJSON(&GenericResponse{Status: false, Message: strings.Join(errs.Errors(), ",")}, w)
+		return false
+	}
+	return true
+}
+
+// writeTagError writes the response of a failed tag operation
+func (th *TagHandler) writeTagError(w http.ResponseWriter, err error) {
+	th.logger.Debug(err.Error())
+	if err.Error() == utils.ErrTagNotFound {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	data.ToJSON(&GenericResponse{Status: false, Message: err.Error()}, w)
+}
@@ -0,0 +1,260 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// withEXIF inserts an EXIF segment with a location after the start of the JPEG image
+func withEXIF(content []byte) []byte {
+	payload := []byte("Exif\x00\x00GPS 52.3676 4.9041")
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(append(append([]byte{}, content[:2]...), append(segment, payload...)...), content[2:]...)
+}
+
+// withOrientation inserts a little endian EXIF segment with the orientation and a location after the start of
+// the JPEG image
+func withOrientation(content []byte, orientation uint16) []byte {
+	location := []byte("GPS 52.3676 4.9041\x00")
+	tiff := []byte("II\x2A\x00\x08\x00\x00\x00\x02\x00")
+	entry := make([]byte, 12)
+	binary.LittleEndian.PutUint16(entry, 0x0112)
+	binary.LittleEndian.PutUint16(entry[2:], 3)
+	binary.LittleEndian.PutUint32(entry[4:], 1)
+	binary.LittleEndian.PutUint16(entry[8:], orientation)
+	tiff = append(tiff, entry...)
+	binary.LittleEndian.PutUint16(entry, 0x010E)
+	binary.LittleEndian.PutUint16(entry[2:], 2)
+	binary.LittleEndian.PutUint32(entry[4:], uint32(len(location)))
+	binary.LittleEndian.PutUint32(entry[8:], uint32(len(tiff)+12+4))
+	tiff = append(append(append(tiff, entry...), 0, 0, 0, 0), location...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(append(append([]byte{}, content[:2]...), append(segment, payload...)...), content[2:]...)
+}
+
+// withTextChunk inserts a text chunk after the header chunk of the PNG image
+func withTextChunk(content []byte) []byte {
+	payload := []byte("Comment\x00taken at home")
+	chunk := make([]byte, 8, 12+len(payload))
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	copy(chunk[4:], "tEXt")
+	chunk = append(chunk, payload...)
+	chunk = append(chunk, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(chunk[len(chunk)-4:], crc32.ChecksumIEEE(chunk[4:len(chunk)-4]))
+	headerEnd := 8 + 12 + 13
+	return append(append(append([]byte{}, content[:headerEnd]...), chunk...), content[headerEnd:]...)
+}
+
+func TestImageVariants(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	store := service.NewLocalBlobStore(logger, t.TempDir())
+	images := service.NewImageService(logger, configs, repository, store)
+	attachments := service.NewAttachmentService(logger, configs, repository, store, images)
+	ath := handlers.NewAttachmentHandler(logger, configs, repository, attachments)
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	done := make(chan struct{})
+	go func() {
+		images.Run(ctx)
+		close(done)
+	}()
+
+	repository.Create(&data.User{Email: "owner@image.example.com", Password: "hash"})
+	article, _ := repository.CreateArticle(&data.Article{Title: "article with pictures", Author: "owner@image.example.com"})
+
+	photo := image.NewRGBA(image.Rect(0, 0, 1000, 500))
+	for i := range photo.Pix {
+		photo.Pix[i] = 0xFF
+	}
+	var encoded bytes.Buffer
+	jpeg.Encode(&encoded, photo, nil)
+	uploaded, err := attachments.Upload(article.ID, "owner@image.example.com", "photo.jpg", bytes.NewReader(withEXIF(encoded.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploaded.Width != 1000 || uploaded.Height != 500 {
+		t.Errorf("unexpected dimensions %dx%d", uploaded.Width, uploaded.Height)
+	}
+
+	download := func(attachmentID string, size string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/Attachment/"+attachmentID+"?size="+size, nil)
+		req = mux.SetURLVars(req, map[string]string{"attachmentID": attachmentID})
+		rec := httptest.NewRecorder()
+		ath.GetAttachment(rec, req)
+		return rec
+	}
+	if original := download(uploaded.ID, ""); bytes.Contains(original.Body.Bytes(), []byte("Exif")) || bytes.Contains(original.Body.Bytes(), []byte("GPS")) {
+		t.Errorf("EXIF metadata not stripped")
+	}
+
+	// the variants are generated by the worker pool in the background
+	var processed *data.Attachment
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if processed, _ = repository.GetAttachmentByID(uploaded.ID); len(processed.Variants) > 0 {
+			break
+		}
+	}
+	if len(processed.Variants) != 3 {
+		t.Fatalf("unexpected variants %+v", processed.Variants)
+	}
+	if thumbnail := processed.Variant("thumbnail"); thumbnail.Width != 160 || thumbnail.Height != 80 || thumbnail.ContentType != "image/jpeg" {
+		t.Errorf("unexpected thumbnail %+v", thumbnail)
+	}
+
+	rec := download(uploaded.ID, "thumbnail")
+	if config, format, err := image.DecodeConfig(rec.Body); err != nil || format != "jpeg" || config.Width != 160 || config.Height != 80 {
+		t.Errorf("unexpected thumbnail download %v %s %v", config, format, err)
+	}
+	// the image is smaller than the large size, so the original is sent
+	rec = download(uploaded.ID, "large")
+	if config, _, err := image.DecodeConfig(rec.Body); err != nil || config.Width != 1000 {
+		t.Errorf("original not sent for a larger size %v %v", config, err)
+	}
+	if rec := download(uploaded.ID, "huge"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown size accepted: %d", rec.Code)
+	}
+
+	// the pool is stopped so the next image is only processed here
+	stop()
+	<-done
+
+	// png images keep their transparency and lose their text chunks
+	drawing := image.NewNRGBA(image.Rect(0, 0, 400, 800))
+	drawing.Set(0, 0, color.NRGBA{R: 0xFF, A: 0x80})
+	encoded.Reset()
+	png.Encode(&encoded, drawing)
+	uploaded, err = attachments.Upload(article.ID, "owner@image.example.com", "drawing.png", bytes.NewReader(withTextChunk(encoded.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original := download(uploaded.ID, ""); bytes.Contains(original.Body.Bytes(), []byte("tEXt")) {
+		t.Errorf("text chunk not stripped")
+	} else if _, err := png.Decode(original.Body); err != nil {
+		t.Errorf("stripped png can not be decoded: %v", err)
+	}
+	if err := images.Process(uploaded.ID); err != nil {
+		t.Fatal(err)
+	}
+	processed, _ = repository.GetAttachmentByID(uploaded.ID)
+	if small := processed.Variant("small"); small == nil || small.Width != 240 || small.Height != 480 || small.ContentType != "image/png" {
+		t.Errorf("unexpected variants %+v", processed.Variants)
+	}
+
+	// deleting the attachment deletes its variants as well
+	if err := attachments.Delete(uploaded.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, variant := range processed.Variants {
+		if _, err := store.Get(variant.Key); err != service.ErrBlobNotFound {
+			t.Errorf("variant %s not deleted: %v", variant.Name, err)
+		}
+	}
+}
+
+func TestJPEGOrientationKept(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	store := service.NewLocalBlobStore(logger, t.TempDir())
+	images := service.NewImageService(logger, configs, repository, store)
+	attachments := service.NewAttachmentService(logger, configs, repository, store, images)
+	ath := handlers.NewAttachmentHandler(logger, configs, repository, attachments)
+
+	article, _ := repository.CreateArticle(&data.Article{Title: "article with a turned photo", Author: "owner@orientation.example.com"})
+
+	// a landscape photo taken with the camera turned, red on the left and blue on the right
+	photo := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x++ {
+		for y := 0; y < 200; y++ {
+			if x < 200 {
+				photo.Set(x, y, color.RGBA{R: 0xFF, A: 0xFF})
+			} else {
+				photo.Set(x, y, color.RGBA{B: 0xFF, A: 0xFF})
+			}
+		}
+	}
+	var encoded bytes.Buffer
+	jpeg.Encode(&encoded, photo, &jpeg.Options{Quality: 100})
+	uploaded, err := attachments.Upload(article.ID, "owner@orientation.example.com", "turned.jpg", bytes.NewReader(withOrientation(encoded.Bytes(), 6)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploaded.Width != 200 || uploaded.Height != 400 {
+		t.Errorf("unexpected dimensions %dx%d", uploaded.Width, uploaded.Height)
+	}
+
+	download := func(size string) []byte {
+		req := httptest.NewRequest(http.MethodGet, "/Attachment/"+uploaded.ID+"?size="+size, nil)
+		rec := httptest.NewRecorder()
+		ath.GetAttachment(rec, mux.SetURLVars(req, map[string]string{"attachmentID": uploaded.ID}))
+		return rec.Body.Bytes()
+	}
+	original := download("")
+	if bytes.Contains(original, []byte("GPS")) {
+		t.Errorf("location not stripped")
+	}
+	// the original keeps the orientation tag, big endian
+	if !bytes.Contains(original, []byte{0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, 6}) {
+		t.Errorf("orientation not kept")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(original)); err != nil {
+		t.Errorf("stripped jpeg can not be decoded: %v", err)
+	}
+
+	// the variants are turned upright: the left of the photo is at the top
+	if err := images.Process(uploaded.ID); err != nil {
+		t.Fatal(err)
+	}
+	thumbnail, err := jpeg.Decode(bytes.NewReader(download("thumbnail")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := thumbnail.Bounds(); bounds.Dx() != 80 || bounds.Dy() != 160 {
+		t.Fatalf("unexpected thumbnail %v", bounds)
+	}
+	if r, _, b, _ := thumbnail.At(40, 20).RGBA(); r < b {
+		t.Errorf("top of the thumbnail is not red")
+	}
+	if r, _, b, _ := thumbnail.At(40, 140).RGBA(); b < r {
+		t.Errorf("bottom of the thumbnail is not blue")
+	}
+}
+
+func TestInvalidImageQueueSizeFallsBackToDefault(t *testing.T) {
+	t.Setenv("IMAGE_QUEUE_SIZE", "-1")
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	if configs.ImageQueueSize != 256 {
+		t.Fatalf("unexpected image queue size %d", configs.ImageQueueSize)
+	}
+
+	// the queue is created without panicking and takes images while no worker runs
+	repository := data.NewRepo(logger)
+	images := service.NewImageService(logger, configs, repository, service.NewLocalBlobStore(logger, t.TempDir()))
+	if !images.Enqueue("queued-attachment") {
+		t.Errorf("image not queued")
+	}
+}
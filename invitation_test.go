@@ -0,0 +1,64 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func signup(uh *handlers.AuthHandler, user data.User) int {
+	req := httptest.NewRequest(http.MethodPost, "/signup", nil)
+	req = req.WithContext(context.WithValue(req.Context(), handlers.UserKey{}, user))
+	rec := httptest.NewRecorder()
+	uh.Signup(rec, req)
+	return rec.Code
+}
+
+func TestInviteOnlySignup(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	configs.RegistrationMode = utils.RegistrationInviteOnly
+	auth := service.NewAuthService(logger, configs)
+	repository := data.NewRepo(logger)
+	uh := handlers.NewAuthHandler(logger, configs, data.NewValidation(), repository, auth)
+
+	repository.CreateInvitation(&data.Invitation{CodeHash: auth.HashAPIKey("admin-code"), Role: data.RoleAdmin, ExpiresAt: time.Now().Add(time.Hour)})
+	repository.CreateInvitation(&data.Invitation{CodeHash: auth.HashAPIKey("bound-code"), Role: data.RoleUser, Email: "bound@example.com", ExpiresAt: time.Now().Add(time.Hour)})
+	repository.CreateInvitation(&data.Invitation{CodeHash: auth.HashAPIKey("expired-code"), Role: data.RoleUser, ExpiresAt: time.Now().Add(-time.Minute)})
+
+	tests := []struct {
+		name   string
+		user   data.User
+		status int
+	}{
+		{"without code", data.User{Email: "nocode@example.com", Password: "secret"}, http.StatusForbidden},
+		{"unknown code", data.User{Email: "unknown@example.com", Password: "secret", InvitationCode: "nope"}, http.StatusForbidden},
+		{"expired code", data.User{Email: "expired@example.com", Password: "secret", InvitationCode: "expired-code"}, http.StatusForbidden},
+		{"code for another email", data.User{Email: "other@example.com", Password: "secret", InvitationCode: "bound-code"}, http.StatusForbidden},
+		{"code for the email", data.User{Email: "bound@example.com", Password: "secret", InvitationCode: "bound-code"}, http.StatusCreated},
+		{"valid code", data.User{Email: "invited@example.com", Password: "secret", InvitationCode: "admin-code"}, http.StatusCreated},
+		{"used code", data.User{Email: "second@example.com", Password: "secret", InvitationCode: "admin-code"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := signup(uh, tt.user); status != tt.status {
+				t.Errorf("expected %d, got %d", tt.status, status)
+			}
+		})
+	}
+
+	if user, err := repository.GetUserByEmail("invited@example.com"); err != nil || !user.IsAdmin() || user.InvitationCode != "" {
+		t.Errorf("role preset of the invitation not applied: %v", err)
+	}
+
+	configs.RegistrationMode = utils.RegistrationClosed
+	if status := signup(uh, data.User{Email: "closed@example.com", Password: "secret"}); status != http.StatusForbidden {
+		t.Errorf("sign up accepted while registration is closed: %d", status)
+	}
+}
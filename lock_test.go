@@ -0,0 +1,69 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEditLocks(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	ah := handlers.NewArticleHandler(logger, configs, data.NewValidation(), repository, service.NewArticleService(logger, configs), service.NewAnalyticsService(logger, configs, repository))
+	for _, email := range []string{"owner@lock.example.com", "editor@lock.example.com"} {
+		repository.Create(&data.User{Email: email, Password: "hash"})
+	}
+	article, _ := repository.CreateArticle(&data.Article{Title: "locked article", Author: "owner@lock.example.com"})
+	repository.SetContributor(article.ID, "editor@lock.example.com", data.PermissionEditor)
+
+	lock, err := repository.AcquireEditLock(article.ID, "editor@lock.example.com", 100*time.Millisecond)
+	if err != nil || lock.HolderUser == nil {
+		t.Fatalf("unexpected lock %+v, %v", lock, err)
+	}
+	if held, err := repository.AcquireEditLock(article.ID, "owner@lock.example.com", time.Minute); err == nil || held.Holder != "editor@lock.example.com" {
+		t.Errorf("lock acquired while held by another user")
+	}
+
+	update := func(userID string) int {
+		req := asUser(httptest.NewRequest(http.MethodPost, "/Article/Update", nil), userID)
+		req = req.WithContext(context.WithValue(req.Context(), handlers.ArticleKey{}, data.Article{ID: article.ID, Title: "locked article"}))
+		rec := httptest.NewRecorder()
+		ah.UpdateArticle(rec, req)
+		return rec.Code
+	}
+	if code := update("owner@lock.example.com"); code != http.StatusLocked {
+		t.Errorf("non holder updated the locked article: %d", code)
+	}
+	if code := update("editor@lock.example.com"); code != http.StatusCreated {
+		t.Errorf("holder could not update: %d", code)
+	}
+
+	// the heartbeat keeps the lock past its first expiry
+	time.Sleep(60 * time.Millisecond)
+	repository.AcquireEditLock(article.ID, "editor@lock.example.com", 100*time.Millisecond)
+	time.Sleep(60 * time.Millisecond)
+	if current := repository.GetEditLock(article.ID); current == nil || !current.AcquiredAt.Equal(lock.AcquiredAt) {
+		t.Errorf("lock not renewed %+v", current)
+	}
+
+	time.Sleep(120 * time.Millisecond)
+	if repository.GetEditLock(article.ID) != nil {
+		t.Errorf("expired lock still held")
+	}
+	if _, err := repository.AcquireEditLock(article.ID, "owner@lock.example.com", time.Minute); err != nil {
+		t.Errorf("expired lock not taken over: %v", err)
+	}
+	if err := repository.ReleaseEditLock(article.ID, "editor@lock.example.com", false); err == nil {
+		t.Errorf("lock released by a non holder")
+	}
+	if err := repository.ReleaseEditLock(article.ID, "admin@lock.example.com", true); err != nil || repository.GetEditLock(article.ID) != nil {
+		t.Errorf("lock not force released: %v", err)
+	}
+}
@@ -33,13 +33,85 @@ func main() {
 	// authService contains all methods that help in authorizing a user request
 	authService := service.NewAuthService(logger, configs)
 
+	// oidcService contains all methods that help in logging in users with an external identity provider
+	oidcService := service.NewOIDCService(logger, configs)
+
+	// oauthService contains all methods that help in granting third-party apps access on behalf of users
+	oauthService := service.NewOAuthService(logger, configs)
+
 	// articleService contains all methods that help in managing articles
 	articleService := service.NewArticleService(logger, configs)
 
+	// analyticsService counts article views and aggregates them into daily buckets in the background
+	analyticsService := service.NewAnalyticsService(logger, configs, repository)
+	analyticsCtx, stopAnalytics := context.WithCancel(context.Background())
+	analyticsDone := make(chan struct{})
+	go func() {
+		analyticsService.Run(analyticsCtx)
+		close(analyticsDone)
+	}()
+
+	// attachmentService stores the files uploaded for articles and deletes the blobs of deleted articles in the background
+	blobStore, err := service.NewBlobStore(logger, configs)
+	if err != nil {
+		logger.Error("could not create the blob store", "error", err)
+		os.Exit(1)
+	}
+	// imageService generates the resized variants of uploaded images with a bounded pool of workers
+	imageService := service.NewImageService(logger, configs, repository, blobStore)
+	attachmentService := service.NewAttachmentService(logger, configs, repository, blobStore, imageService)
+	attachmentsCtx, stopAttachments := context.WithCancel(context.Background())
+	attachmentsDone := make(chan struct{})
+	imagesDone := make(chan struct{})
+	go func() {
+		imageService.Run(attachmentsCtx)
+		close(imagesDone)
+	}()
+	go func() {
+		attachmentService.Run(attachmentsCtx)
+		close(attachmentsDone)
+	}()
+
 	// UserHandler encapsulates all the requests related to user
 	uh := handlers.NewAuthHandler(logger, configs, validator, repository, authService)
 	// ArticleHandler encapsulates all the requests related to article
-	ah := handlers.NewArticleHandler(logger, configs, validator, repository, articleService)
+	ah := handlers.NewArticleHandler(logger, configs, validator, repository, articleService, analyticsService)
+	// CommentHandler encapsulates all the requests related to comments
+	ch := handlers.NewCommentHandler(logger, configs, validator, repository)
+	// TagHandler encapsulates all the requests related to tags
+	th := handlers.NewTagHandler(logger, configs, validator, repository)
+	// CategoryHandler encapsulates all the requests related to categories
+	cah := handlers.NewCategoryHandler(logger, configs, validator, repository)
+	// SeriesHandler encapsulates all the requests related to series of articles
+	sh := handlers.NewSeriesHandler(logger, configs, validator, repository)
+	// ContributorHandler encapsulates all the requests related to sharing articles
+	coh := handlers.NewContributorHandler(logger, configs, validator, repository)
+	// LockHandler encapsulates all the requests related to edit locks
+	lh := handlers.NewLockHandler(logger, configs, repository)
+	// AttachmentHandler encapsulates all the requests related to the files of articles
+	ath := handlers.NewAttachmentHandler(logger, configs, repository, attachmentService)
+	// FeedHandler encapsulates all the requests related to the RSS and Atom feeds
+	fh := handlers.NewFeedHandler(logger, configs, repository)
+	// SEOHandler encapsulates all the requests related to search engines
+	seh := handlers.NewSEOHandler(logger, configs, repository)
+	// FrontendHandler encapsulates all the requests related to the public HTML pages
+	feh := handlers.NewFrontendHandler(logger, configs, repository)
+	// OIDCHandler encapsulates all the requests related to single sign-on
+	oh := handlers.NewOIDCHandler(logger, configs, repository, authService, oidcService)
+	// OAuthHandler encapsulates all the requests related to third-party apps
+	oah := handlers.NewOAuthHandler(logger, configs, validator, repository, authService, oauthService)
+	// ProfileHandler encapsulates all the requests related to user profiles
+	ph := handlers.NewProfileHandler(logger, configs, validator, repository)
+	// AdminHandler encapsulates all the requests related to user management
+	adh := handlers.NewAdminHandler(logger, configs, validator, repository)
+	// InvitationHandler encapsulates all the requests related to invitations
+	ih := handlers.NewInvitationHandler(logger, configs, validator, repository, authService)
+	// AuditHandler encapsulates all the requests related to the audit log
+	auh := handlers.NewAuditHandler(logger, configs, repository)
+	// AccountHandler encapsulates all the requests related to exporting and erasing accounts
+	ach := handlers.NewAccountHandler(logger, configs, repository, authService)
+	// APIKeyHandler encapsulates all the requests related to api keys
+	kh := handlers.NewAPIKeyHandler(logger, configs, validator, repository, authService)
 
 	// create a serve mux
 	sm := mux.NewRouter()
@@ -50,6 +122,15 @@ func main() {
 	postR.HandleFunc("/login", uh.Login)
 	postR.Use(uh.MiddlewareValidateUser)
 
+	// users set a new password with their current one, also after an admin forced a password reset
+	resetPassword := sm.PathPrefix("/reset-password").Methods(http.MethodPost).Subrouter()
+	resetPassword.HandleFunc("", uh.ResetPassword)
+
+	// single sign-on with the external identity provider
+	oidcR := sm.PathPrefix("/oidc").Methods(http.MethodGet).Subrouter()
+	oidcR.HandleFunc("/login", oh.Login)
+	oidcR.HandleFunc("/callback", oh.Callback)
+
 	// used the PathPrefix as workaround for scenarios where all the
 	// get requests must use the ValidateAccessToken middleware except
 	// the /refresh-token request which has to use ValidateRefreshToken middleware
@@ -61,16 +142,248 @@ func main() {
 	postRArticles := sm.PathPrefix("/Article").Methods(http.MethodPost).Subrouter()
 	postRArticles.HandleFunc("/Create", ah.CreateArticle)
 	postRArticles.HandleFunc("/Update", ah.UpdateArticle)
+	postRArticles.Use(uh.MiddlewareValidateAPIKey)
 	postRArticles.Use(uh.MiddlewareValidateAccessToken)
+	postRArticles.Use(uh.MiddlewareRequireScope(data.ScopeArticlesWrite))
 	postRArticles.Use(ah.MiddlewareValidateArticle)
 
-	//handlers for fetching and deleting article and validates access token at middleware
+	//handler for deleting article, kept apart from the other GET requests as api keys and apps need the write scope for it
+	deleteArticles := sm.PathPrefix("/Article/Delete").Methods(http.MethodGet).Subrouter()
+	deleteArticles.HandleFunc("/{articleID}", ah.DeleteArticle)
+	deleteArticles.Use(uh.MiddlewareValidateAPIKey)
+	deleteArticles.Use(uh.MiddlewareValidateAccessToken)
+	deleteArticles.Use(uh.MiddlewareRequireScope(data.ScopeArticlesWrite))
+
+	//handlers for liking and bookmarking articles, they need the write scope like the other changes
+	reactions := sm.PathPrefix("/Article").Methods(http.MethodGet).Subrouter()
+	reactions.HandleFunc("/Like/{articleID}", ah.LikeArticle)
+	reactions.HandleFunc("/Unlike/{articleID}", ah.UnlikeArticle)
+	reactions.HandleFunc("/Bookmark/{articleID}", ah.BookmarkArticle)
+	reactions.HandleFunc("/Unbookmark/{articleID}", ah.RemoveBookmark)
+	reactions.Use(uh.MiddlewareValidateAPIKey)
+	reactions.Use(uh.MiddlewareValidateAccessToken)
+	reactions.Use(uh.MiddlewareRequireScope(data.ScopeArticlesWrite))
+
+	//handlers for the edit locks of articles, the lock request is repeated as heartbeat while editing
+	locks := sm.PathPrefix("/Article").Methods(http.MethodGet).Subrouter()
+	locks.HandleFunc("/Lock/{articleID}", lh.LockArticle)
+	locks.HandleFunc("/Unlock/{articleID}", lh.UnlockArticle)
+	locks.Use(uh.MiddlewareValidateAPIKey)
+	locks.Use(uh.MiddlewareValidateAccessToken)
+	locks.Use(uh.MiddlewareRequireScope(data.ScopeArticlesWrite))
+
+	//handlers for uploading and deleting the files of articles, api keys and apps need the write scope for them
+	attachmentsWrite := sm.PathPrefix("/Attachment").Subrouter()
+	attachmentsWrite.HandleFunc("/Upload/{articleID}", ath.UploadAttachment).Methods(http.MethodPost)
+	attachmentsWrite.HandleFunc("/Delete/{attachmentID}", ath.DeleteAttachment).Methods(http.MethodGet)
+	attachmentsWrite.Use(uh.MiddlewareValidateAPIKey)
+	attachmentsWrite.Use(uh.MiddlewareValidateAccessToken)
+	attachmentsWrite.Use(uh.MiddlewareRequireScope(data.ScopeArticlesWrite))
+
+	//handler for downloading the files of articles
+	attachmentsRead := sm.PathPrefix("/Attachment").Methods(http.MethodGet).Subrouter()
+	attachmentsRead.HandleFunc("/{attachmentID}", ath.GetAttachment)
+	attachmentsRead.Use(uh.MiddlewareValidateAPIKey)
+	attachmentsRead.Use(uh.MiddlewareValidateAccessToken)
+	attachmentsRead.Use(uh.MiddlewareRequireScope(data.ScopeArticlesRead))
+
+	//handler for the bookmarks of the signed in user
+	bookmarksR := sm.PathPrefix("/Bookmarks").Methods(http.MethodGet).Subrouter()
+	bookmarksR.HandleFunc("", ah.GetBookmarks)
+	bookmarksR.Use(uh.MiddlewareValidateAPIKey)
+	bookmarksR.Use(uh.MiddlewareValidateAccessToken)
+	bookmarksR.Use(uh.MiddlewareRequireScope(data.ScopeArticlesRead))
+
+	//handlers for fetching article and validates api key or access token at middleware
 	getArticles := sm.PathPrefix("/Article").Methods(http.MethodGet).Subrouter()
 	getArticles.HandleFunc("/Tags", ah.GetArticlesTags)
-	getArticles.HandleFunc("/Delete/{articleID}", ah.DeleteArticle)
+	getArticles.HandleFunc("/Slug/{slug}", ah.GetArticleBySlug)
 	getArticles.HandleFunc("", ah.GetArticles).Queries("pageid", "{id:[0-9]+}")
 	getArticles.HandleFunc("/{articleID}", ah.GetArticle)
+	getArticles.HandleFunc("/{articleID}/Comments", ch.GetComments)
+	getArticles.HandleFunc("/{articleID}/Contributors", coh.GetContributors)
+	getArticles.HandleFunc("/{articleID}/Attachments", ath.GetAttachments)
+	getArticles.Use(uh.MiddlewareValidateAPIKey)
 	getArticles.Use(uh.MiddlewareValidateAccessToken)
+	getArticles.Use(uh.MiddlewareRequireScope(data.ScopeArticlesRead))
+
+	//handlers for listing tags and autocompleting them
+	tagsR := sm.PathPrefix("/Tags").Methods(http.MethodGet).Subrouter()
+	tagsR.HandleFunc("", th.GetTags)
+	tagsR.HandleFunc("/Suggest", th.SuggestTags)
+	tagsR.Use(uh.MiddlewareValidateAPIKey)
+	tagsR.Use(uh.MiddlewareValidateAccessToken)
+	tagsR.Use(uh.MiddlewareRequireScope(data.ScopeArticlesRead))
+
+	//handlers for browsing the category tree and the articles of a category
+	categoriesR := sm.PathPrefix("/Categories").Methods(http.MethodGet).Subrouter()
+	categoriesR.HandleFunc("", cah.GetCategories)
+	categoriesR.HandleFunc("/{categoryID}/Articles", cah.GetCategoryArticles)
+	categoriesR.Use(uh.MiddlewareValidateAPIKey)
+	categoriesR.Use(uh.MiddlewareValidateAccessToken)
+	categoriesR.Use(uh.MiddlewareRequireScope(data.ScopeArticlesRead))
+
+	//handlers for managing the series of the current user, api keys and apps need the write scope for them
+	seriesWrite := sm.PathPrefix("/Series").Subrouter()
+	seriesWrite.HandleFunc("/Create", sh.CreateSeries).Methods(http.MethodPost)
+	seriesWrite.HandleFunc("/Update", sh.UpdateSeries).Methods(http.MethodPost)
+	seriesWrite.HandleFunc("/Delete/{seriesID}", sh.DeleteSeries).Methods(http.MethodGet)
+	seriesWrite.HandleFunc("/Articles/Add", sh.AddArticle).Methods(http.MethodPost)
+	seriesWrite.HandleFunc("/Articles/Remove", sh.RemoveArticle).Methods(http.MethodPost)
+	seriesWrite.HandleFunc("/Order", sh.ReorderSeries).Methods(http.MethodPost)
+	seriesWrite.Use(uh.MiddlewareValidateAPIKey)
+	seriesWrite.Use(uh.MiddlewareValidateAccessToken)
+	seriesWrite.Use(uh.MiddlewareRequireScope(data.ScopeArticlesWrite))
+
+	//handlers for fetching series
+	seriesRead := sm.PathPrefix("/Series").Methods(http.MethodGet).Subrouter()
+	seriesRead.HandleFunc("/{seriesID}", sh.GetSeries)
+	seriesRead.Use(uh.MiddlewareValidateAPIKey)
+	seriesRead.Use(uh.MiddlewareValidateAccessToken)
+	seriesRead.Use(uh.MiddlewareRequireScope(data.ScopeArticlesRead))
+
+	//handlers for sharing articles with other users, api keys and apps need the write scope for them
+	contributorsR := sm.PathPrefix("/Contributors").Methods(http.MethodPost).Subrouter()
+	contributorsR.HandleFunc("/Set", coh.SetContributor)
+	contributorsR.HandleFunc("/Remove", coh.RemoveContributor)
+	contributorsR.HandleFunc("/Transfer", coh.TransferOwnership)
+	contributorsR.Use(uh.MiddlewareValidateAPIKey)
+	contributorsR.Use(uh.MiddlewareValidateAccessToken)
+	contributorsR.Use(uh.MiddlewareRequireScope(data.ScopeArticlesWrite))
+
+	//handlers for writing comments, api keys and apps need the write scope for them
+	commentsR := sm.PathPrefix("/Comment").Subrouter()
+	commentsR.HandleFunc("/Create", ch.CreateComment).Methods(http.MethodPost)
+	commentsR.HandleFunc("/Update", ch.UpdateComment).Methods(http.MethodPost)
+	commentsR.HandleFunc("/Delete/{commentID}", ch.DeleteComment).Methods(http.MethodGet)
+	commentsR.Use(uh.MiddlewareValidateAPIKey)
+	commentsR.Use(uh.MiddlewareValidateAccessToken)
+	commentsR.Use(uh.MiddlewareRequireScope(data.ScopeArticlesWrite))
+
+	//handlers for the view statistics of the articles of the signed in user
+	stats := sm.PathPrefix("/Stats").Methods(http.MethodGet).Subrouter()
+	stats.HandleFunc("/Article/{articleID}", ah.GetArticleStats)
+	stats.HandleFunc("/Author", ah.GetAuthorStats)
+	stats.Use(uh.MiddlewareValidateAPIKey)
+	stats.Use(uh.MiddlewareValidateAccessToken)
+	stats.Use(uh.MiddlewareRequireScope(data.ScopeArticlesRead))
+
+	//handlers for the profile of the signed in user
+	profile := sm.PathPrefix("/Profile").Subrouter()
+	profile.HandleFunc("", ph.GetProfile).Methods(http.MethodGet)
+	profile.HandleFunc("/Update", ph.UpdateProfile).Methods(http.MethodPost)
+	profile.Use(uh.MiddlewareValidateAccessToken)
+	profile.Use(uh.MiddlewareRequireUserSession)
+
+	//handlers for the data requests of the signed in user
+	account := sm.PathPrefix("/Account").Subrouter()
+	account.HandleFunc("/Export", ach.ExportAccount).Methods(http.MethodGet)
+	account.HandleFunc("/Delete", ach.DeleteAccount).Methods(http.MethodPost)
+	account.Use(uh.MiddlewareValidateAccessToken)
+	account.Use(uh.MiddlewareRequireUserSession)
+
+	//public author pages
+	authors := sm.PathPrefix("/Author").Methods(http.MethodGet).Subrouter()
+	authors.HandleFunc("/{username}", ph.GetAuthor)
+
+	//public RSS and Atom feeds of the published articles, ?tag= and ?author= narrow them down
+	feeds := sm.Methods(http.MethodGet, http.MethodHead).Subrouter()
+	feeds.HandleFunc("/feed.rss", fh.RSSFeed)
+	feeds.HandleFunc("/feed.atom", fh.AtomFeed)
+
+	//public sitemap of the published articles, split into the numbered sitemaps of an index when too large
+	sitemaps := sm.Methods(http.MethodGet, http.MethodHead).Subrouter()
+	sitemaps.HandleFunc("/sitemap.xml", seh.Sitemap)
+	sitemaps.HandleFunc("/sitemap-{page:[0-9]+}.xml", seh.SitemapPage)
+
+	//optional public HTML pages of the published articles, ?page= pages through the lists
+	if configs.FrontendEnabled {
+		frontend := sm.Methods(http.MethodGet, http.MethodHead).Subrouter()
+		frontend.HandleFunc("/", feh.Home)
+		frontend.HandleFunc("/articles/{slug}", feh.Article)
+		frontend.HandleFunc("/tags/{tag}", feh.Tag)
+		frontend.HandleFunc("/authors/{username}", feh.Author)
+	}
+
+	//admin only handlers for managing users
+	admin := sm.PathPrefix("/Admin/Users").Subrouter()
+	admin.HandleFunc("", adh.ListUsers).Methods(http.MethodGet)
+	admin.HandleFunc("/Disable/{userID}", adh.DisableUser).Methods(http.MethodGet)
+	admin.HandleFunc("/Enable/{userID}", adh.EnableUser).Methods(http.MethodGet)
+	admin.HandleFunc("/ForcePasswordReset/{userID}", adh.ForcePasswordReset).Methods(http.MethodGet)
+	admin.HandleFunc("/Role", adh.ChangeRole).Methods(http.MethodPost)
+	admin.HandleFunc("/Delete", adh.DeleteUser).Methods(http.MethodPost)
+	admin.Use(uh.MiddlewareValidateAccessToken)
+	admin.Use(uh.MiddlewareRequireUserSession)
+	admin.Use(uh.MiddlewareRequireAdmin)
+
+	//admin only handlers for querying and exporting the audit log
+	audit := sm.PathPrefix("/Admin/Audit").Methods(http.MethodGet).Subrouter()
+	audit.HandleFunc("", auh.ListAuditEvents)
+	audit.HandleFunc("/Export", auh.ExportAuditEvents)
+	audit.Use(uh.MiddlewareValidateAccessToken)
+	audit.Use(uh.MiddlewareRequireUserSession)
+	audit.Use(uh.MiddlewareRequireAdmin)
+
+	//admin only handlers for managing tags across all articles
+	adminTags := sm.PathPrefix("/Admin/Tags").Subrouter()
+	adminTags.HandleFunc("/Rename", th.RenameTag).Methods(http.MethodPost)
+	adminTags.HandleFunc("/Merge", th.MergeTags).Methods(http.MethodPost)
+	adminTags.HandleFunc("/Delete/{tag}", th.DeleteTag).Methods(http.MethodGet)
+	adminTags.Use(uh.MiddlewareValidateAccessToken)
+	adminTags.Use(uh.MiddlewareRequireUserSession)
+	adminTags.Use(uh.MiddlewareRequireAdmin)
+
+	//admin only handlers for managing the category tree
+	adminCategories := sm.PathPrefix("/Admin/Categories").Subrouter()
+	adminCategories.HandleFunc("/Create", cah.CreateCategory).Methods(http.MethodPost)
+	adminCategories.HandleFunc("/Rename", cah.RenameCategory).Methods(http.MethodPost)
+	adminCategories.HandleFunc("/Move", cah.MoveCategory).Methods(http.MethodPost)
+	adminCategories.HandleFunc("/Delete/{categoryID}", cah.DeleteCategory).Methods(http.MethodGet)
+	adminCategories.Use(uh.MiddlewareValidateAccessToken)
+	adminCategories.Use(uh.MiddlewareRequireUserSession)
+	adminCategories.Use(uh.MiddlewareRequireAdmin)
+
+	//admin only handler for releasing the edit lock of any user
+	adminLocks := sm.PathPrefix("/Admin/Locks").Methods(http.MethodGet).Subrouter()
+	adminLocks.HandleFunc("/Release/{articleID}", lh.ForceUnlockArticle)
+	adminLocks.Use(uh.MiddlewareValidateAccessToken)
+	adminLocks.Use(uh.MiddlewareRequireUserSession)
+	adminLocks.Use(uh.MiddlewareRequireAdmin)
+
+	//admin only handlers for inviting users when registration is invite-only
+	invitationsR := sm.PathPrefix("/Admin/Invitations").Subrouter()
+	invitationsR.HandleFunc("", ih.GetInvitations).Methods(http.MethodGet)
+	invitationsR.HandleFunc("/Create", ih.CreateInvitation).Methods(http.MethodPost)
+	invitationsR.HandleFunc("/Delete/{invitationID}", ih.DeleteInvitation).Methods(http.MethodGet)
+	invitationsR.Use(uh.MiddlewareValidateAccessToken)
+	invitationsR.Use(uh.MiddlewareRequireUserSession)
+	invitationsR.Use(uh.MiddlewareRequireAdmin)
+
+	//handlers for managing api keys, they only accept access tokens so a leaked key can not mint new keys
+	apiKeys := sm.PathPrefix("/APIKey").Subrouter()
+	apiKeys.HandleFunc("/Create", kh.CreateAPIKey).Methods(http.MethodPost)
+	apiKeys.HandleFunc("/Revoke/{keyID}", kh.RevokeAPIKey).Methods(http.MethodGet)
+	apiKeys.HandleFunc("", kh.GetAPIKeys).Methods(http.MethodGet)
+	apiKeys.Use(uh.MiddlewareValidateAccessToken)
+	apiKeys.Use(uh.MiddlewareRequireUserSession)
+
+	//oauth2 endpoints called by third-party apps, they authenticate with their client credentials
+	oauthR := sm.PathPrefix("/oauth").Methods(http.MethodPost).Subrouter()
+	oauthR.HandleFunc("/token", oah.Token)
+	oauthR.HandleFunc("/introspect", oah.Introspect)
+	oauthR.HandleFunc("/revoke", oah.Revoke)
+
+	//oauth2 endpoints for the signed in user to register apps and to grant or withdraw access
+	oauthUser := sm.PathPrefix("/oauth").Subrouter()
+	oauthUser.HandleFunc("/authorize", oah.GetAuthorization).Methods(http.MethodGet)
+	oauthUser.HandleFunc("/authorize", oah.Authorize).Methods(http.MethodPost)
+	oauthUser.HandleFunc("/clients", oah.RegisterClient).Methods(http.MethodPost)
+	oauthUser.HandleFunc("/clients", oah.GetClients).Methods(http.MethodGet)
+	oauthUser.HandleFunc("/consents", oah.GetConsents).Methods(http.MethodGet)
+	oauthUser.HandleFunc("/consents/revoke/{clientID}", oah.RevokeConsent).Methods(http.MethodGet)
+	oauthUser.Use(uh.MiddlewareValidateAccessToken)
+	oauthUser.Use(uh.MiddlewareRequireUserSession)
 
 	// create a server
 	svr := http.Server{
@@ -102,6 +415,16 @@ func main() {
 	logger.Info("shutting down the server", "received signal", sig)
 
 	//gracefully shutdown the server, waiting max 30 seconds for current operations to complete
-	ctx, _ := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 	svr.Shutdown(ctx)
+
+	// store the views that were not aggregated yet
+	stopAnalytics()
+	<-analyticsDone
+
+	// finish the images being processed and delete the blobs that are still waiting for the cleanup
+	stopAttachments()
+	<-imagesDone
+	<-attachmentsDone
 }
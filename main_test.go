@@ -3,6 +3,8 @@ package main_test
 import (
 	"MohsenArabi/ArticleManagementSystem/data"
 	"MohsenArabi/ArticleManagementSystem/utils"
+	"bytes"
+	"strings"
 	"testing"
 	"time"
 )
@@ -86,3 +88,94 @@ func comapreArticles(article1 data.Article, article2 data.Article) bool {
 	}
 	return true
 }
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	logger := utils.NewLogger()
+	repository := data.NewRepo(logger)
+	key := data.APIKey{UserID: "bot@example.com", Name: "ci", KeyHash: "hash-1", Scopes: []string{data.ScopeArticlesRead}}
+	created, err := repository.CreateAPIKey(&key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetched, err := repository.GetAPIKeyByHash("hash-1")
+	if err != nil || fetched.ID != created.ID {
+		t.Fatalf("api key not found by hash: %v", err)
+	}
+	if !fetched.HasScope(data.ScopeArticlesRead) || fetched.HasScope(data.ScopeArticlesWrite) {
+		t.Errorf("unexpected scopes %v", fetched.Scopes)
+	}
+
+	if err := repository.RevokeAPIKey("other@example.com", created.ID); err == nil {
+		t.Errorf("api key revoked by another user")
+	}
+	if err := repository.RevokeAPIKey("bot@example.com", created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.GetAPIKeyByHash("hash-1"); err == nil {
+		t.Errorf("revoked api key still accepted")
+	}
+}
+
+func TestUsernamesAreUniqueAndArticlesHideAuthorEmail(t *testing.T) {
+	logger := utils.NewLogger()
+	repository := data.NewRepo(logger)
+	first := data.User{Email: "jane.roe@example.com", Password: "hash"}
+	second := data.User{Email: "jane.roe@example.org", Password: "hash"}
+	if err := repository.Create(&first); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.Create(&second); err != nil {
+		t.Fatal(err)
+	}
+	if first.Username != "janeroe" || second.Username != "janeroe2" {
+		t.Errorf("unexpected usernames %q and %q", first.Username, second.Username)
+	}
+
+	if _, err := repository.UpdateProfile(second.Email, &data.Profile{Username: "JaneRoe"}); err == nil {
+		t.Errorf("username taken by another user was accepted")
+	}
+	if _, err := repository.UpdateProfile(second.Email, &data.Profile{Username: "jroe", DisplayName: "Jane"}); err != nil {
+		t.Fatal(err)
+	}
+
+	article, _ := repository.CreateArticle(&data.Article{Title: "profile title", Author: second.Email})
+	if article.AuthorProfile == nil || article.AuthorProfile.Username != "jroe" || article.AuthorProfile.DisplayName != "Jane" {
+		t.Errorf("unexpected author profile %+v", article.AuthorProfile)
+	}
+	encoded := &bytes.Buffer{}
+	data.ToJSON(article, encoded)
+	if strings.Contains(encoded.String(), second.Email) {
+		t.Errorf("author email exposed in article json: %s", encoded.String())
+	}
+}
+
+func TestDeleteUserReassignsOrDeletesArticles(t *testing.T) {
+	logger := utils.NewLogger()
+	repository := data.NewRepo(logger)
+	for _, email := range []string{"leaving@example.com", "heir@example.com", "gone@example.com"} {
+		repository.Create(&data.User{Email: email, Password: "hash"})
+	}
+	kept, _ := repository.CreateArticle(&data.Article{Title: "kept", Author: "leaving@example.com"})
+	dropped, _ := repository.CreateArticle(&data.Article{Title: "dropped", Author: "gone@example.com"})
+
+	if err := repository.DeleteUser("leaving@example.com", "nobody@example.com"); err == nil {
+		t.Errorf("articles reassigned to a missing user")
+	}
+	if err := repository.DeleteUser("leaving@example.com", "heir@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if article, err := repository.GetArticleByID(kept.ID); err != nil || article.Author != "heir@example.com" {
+		t.Errorf("article not reassigned: %v", err)
+	}
+	if _, err := repository.GetUserByEmail("leaving@example.com"); err == nil {
+		t.Errorf("user not deleted")
+	}
+
+	if err := repository.DeleteUser("gone@example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.GetArticleByID(dropped.ID); err == nil {
+		t.Errorf("article of deleted user not deleted")
+	}
+}
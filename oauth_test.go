@@ -0,0 +1,302 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+type oauthFixture struct {
+	oh     *handlers.OAuthHandler
+	auth   *service.AuthService
+	repo   *data.Repo
+	client *data.OAuthClient
+	secret string
+}
+
+func newOAuthFixture(t *testing.T, confidential bool) *oauthFixture {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	auth := service.NewAuthService(logger, configs)
+	repository := data.NewRepo(logger)
+	oh := handlers.NewOAuthHandler(logger, configs, data.NewValidation(), repository, auth, service.NewOAuthService(logger, configs))
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"name":         "partner",
+		"redirectURIs": []string{"https://partner.example.com/cb"},
+		"scopes":       []string{data.ScopeArticlesRead, data.ScopeArticlesWrite},
+		"confidential": confidential,
+	})
+	rec := httptest.NewRecorder()
+	oh.RegisterClient(rec, asUser(httptest.NewRequest(http.MethodPost, "/oauth/clients", bytes.NewReader(body)), "owner@example.com"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("client registration failed: %s", rec.Body.String())
+	}
+	created := struct {
+		Data handlers.CreatedOAuthClientResponse `json:"data"`
+	}{}
+	json.Unmarshal(rec.Body.Bytes(), &created)
+
+	return &oauthFixture{oh: oh, auth: auth, repo: repository, client: created.Data.Client, secret: created.Data.ClientSecret}
+}
+
+func asUser(r *http.Request, userID string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), handlers.UserIDKey{}, userID))
+}
+
+func pkcePair() (string, string) {
+	verifier := "a-long-enough-code-verifier-for-the-tests-0123456789"
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// authorize approves an authorization request as the user and returns the issued code
+func (f *oauthFixture) authorize(t *testing.T, userID string, scope string, challenge string) string {
+	body, _ := json.Marshal(handlers.AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            f.client.ID,
+		RedirectURI:         "https://partner.example.com/cb",
+		Scope:               scope,
+		State:               "xyz",
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: "S256",
+		Approve:             true,
+	})
+	rec := httptest.NewRecorder()
+	f.oh.Authorize(rec, asUser(httptest.NewRequest(http.MethodPost, "/oauth/authorize", bytes.NewReader(body)), userID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("authorization failed: %s", rec.Body.String())
+	}
+	response := struct {
+		Data handlers.AuthorizeResponse `json:"data"`
+	}{}
+	json.Unmarshal(rec.Body.Bytes(), &response)
+	redirect, _ := url.Parse(response.Data.RedirectURL)
+	if redirect.Query().Get("state") != "xyz" {
+		t.Errorf("state not returned to the client")
+	}
+	return redirect.Query().Get("code")
+}
+
+func (f *oauthFixture) post(handler http.HandlerFunc, form url.Values, withSecret bool) *httptest.ResponseRecorder {
+	if !withSecret {
+		form.Set("client_id", f.client.ID)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/oauth", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if withSecret {
+		req.SetBasicAuth(f.client.ID, f.secret)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestOAuthAuthorizationCodeFlow(t *testing.T) {
+	f := newOAuthFixture(t, true)
+	verifier, challenge := pkcePair()
+	code := f.authorize(t, "reader@example.com", data.ScopeArticlesRead, challenge)
+
+	if consent, err := f.repo.GetOAuthConsent("reader@example.com", f.client.ID); err != nil || len(consent.Scopes) != 1 {
+		t.Errorf("consent not recorded: %v", err)
+	}
+
+	rec := f.post(f.oh.Token, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {"https://partner.example.com/cb"},
+		"code_verifier": {verifier},
+	}, true)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("token request failed: %s", rec.Body.String())
+	}
+	token := handlers.OAuthTokenResponse{}
+	json.Unmarshal(rec.Body.Bytes(), &token)
+	if token.Scope != data.ScopeArticlesRead || token.TokenType != "Bearer" {
+		t.Errorf("unexpected token response %+v", token)
+	}
+
+	claims, err := f.auth.ParseAccessToken(token.AccessToken)
+	if err != nil || claims.UserID != "reader@example.com" || claims.ClientID != f.client.ID {
+		t.Fatalf("unexpected access token claims: %v", err)
+	}
+
+	// the code can only be used once
+	if rec := f.post(f.oh.Token, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {"https://partner.example.com/cb"},
+		"code_verifier": {verifier},
+	}, true); rec.Code != http.StatusBadRequest {
+		t.Errorf("authorization code redeemed twice")
+	}
+
+	introspection := handlers.IntrospectionResponse{}
+	rec = f.post(f.oh.Introspect, url.Values{"token": {token.AccessToken}}, true)
+	json.Unmarshal(rec.Body.Bytes(), &introspection)
+	if !introspection.Active || introspection.Username != "reader@example.com" || introspection.Scope != data.ScopeArticlesRead {
+		t.Errorf("unexpected introspection %+v", introspection)
+	}
+
+	if rec := f.post(f.oh.Revoke, url.Values{"token": {token.AccessToken}}, true); rec.Code != http.StatusOK {
+		t.Errorf("revocation failed: %d", rec.Code)
+	}
+	introspection = handlers.IntrospectionResponse{}
+	rec = f.post(f.oh.Introspect, url.Values{"token": {token.AccessToken}}, true)
+	json.Unmarshal(rec.Body.Bytes(), &introspection)
+	if introspection.Active {
+		t.Errorf("revoked token still active")
+	}
+}
+
+func TestOAuthRevokingConsentRevokesTokens(t *testing.T) {
+	f := newOAuthFixture(t, true)
+	logger := utils.NewLogger()
+	uh := handlers.NewAuthHandler(logger, utils.NewConfigurations(logger), data.NewValidation(), f.repo, f.auth)
+	api := uh.MiddlewareValidateAccessToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	f.repo.Create(&data.User{Email: "consenter@example.com", Password: "hash"})
+
+	call := func(accessToken string) int {
+		req := httptest.NewRequest(http.MethodGet, "/Article/Tags", nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	issue := func() string {
+		verifier, challenge := pkcePair()
+		code := f.authorize(t, "consenter@example.com", data.ScopeArticlesRead, challenge)
+		rec := f.post(f.oh.Token, url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {"https://partner.example.com/cb"},
+			"code_verifier": {verifier},
+		}, true)
+		token := handlers.OAuthTokenResponse{}
+		json.Unmarshal(rec.Body.Bytes(), &token)
+		return token.AccessToken
+	}
+
+	first, second := issue(), issue()
+	if code := call(first); code != http.StatusOK {
+		t.Fatalf("token rejected before revoking the consent: %d", code)
+	}
+
+	req := asUser(httptest.NewRequest(http.MethodGet, "/oauth/consents/revoke/"+f.client.ID, nil), "consenter@example.com")
+	rec := httptest.NewRecorder()
+	f.oh.RevokeConsent(rec, mux.SetURLVars(req, map[string]string{"clientID": f.client.ID}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("consent not revoked: %s", rec.Body.String())
+	}
+	for _, accessToken := range []string{first, second} {
+		if code := call(accessToken); code == http.StatusOK {
+			t.Errorf("token still valid after revoking the consent")
+		}
+	}
+
+	// consenting again does not bring the old tokens back
+	if code := call(issue()); code != http.StatusOK {
+		t.Errorf("token issued under the new consent rejected: %d", code)
+	}
+	if code := call(first); code == http.StatusOK {
+		t.Errorf("old token valid again after consenting again")
+	}
+}
+
+func TestOAuthRejectsInvalidTokenRequests(t *testing.T) {
+	f := newOAuthFixture(t, true)
+	verifier, challenge := pkcePair()
+
+	tests := []struct {
+		name       string
+		form       func(code string) url.Values
+		withSecret bool
+		status     int
+	}{
+		{"wrong code verifier", func(code string) url.Values {
+			return url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {"https://partner.example.com/cb"}, "code_verifier": {"wrong"}}
+		}, true, http.StatusBadRequest},
+		{"missing code verifier", func(code string) url.Values {
+			return url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {"https://partner.example.com/cb"}}
+		}, true, http.StatusBadRequest},
+		{"other redirect uri", func(code string) url.Values {
+			return url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {"https://evil.example.com/cb"}, "code_verifier": {verifier}}
+		}, true, http.StatusBadRequest},
+		{"missing client secret", func(code string) url.Values {
+			return url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {"https://partner.example.com/cb"}, "code_verifier": {verifier}}
+		}, false, http.StatusUnauthorized},
+		{"unsupported grant", func(code string) url.Values {
+			return url.Values{"grant_type": {"password"}, "username": {"reader@example.com"}, "password": {"x"}}
+		}, true, http.StatusBadRequest},
+		{"scope beyond client", func(code string) url.Values {
+			return url.Values{"grant_type": {"client_credentials"}, "scope": {"admin"}}
+		}, true, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := f.authorize(t, "reader@example.com", data.ScopeArticlesRead, challenge)
+			if rec := f.post(f.oh.Token, tt.form(code), tt.withSecret); rec.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestOAuthClientCredentials(t *testing.T) {
+	f := newOAuthFixture(t, true)
+	rec := f.post(f.oh.Token, url.Values{"grant_type": {"client_credentials"}, "scope": {data.ScopeArticlesWrite}}, true)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("client credentials grant failed: %s", rec.Body.String())
+	}
+	token := handlers.OAuthTokenResponse{}
+	json.Unmarshal(rec.Body.Bytes(), &token)
+	claims, err := f.auth.ParseAccessToken(token.AccessToken)
+	if err != nil || claims.UserID != "owner@example.com" || claims.Scope != data.ScopeArticlesWrite {
+		t.Errorf("unexpected client credentials token: %v", err)
+	}
+
+	public := newOAuthFixture(t, false)
+	if rec := public.post(public.oh.Token, url.Values{"grant_type": {"client_credentials"}}, false); rec.Code != http.StatusBadRequest {
+		t.Errorf("public client used client credentials: %d", rec.Code)
+	}
+}
+
+func TestOAuthAuthorizeRequiresPKCEAndRegisteredRedirect(t *testing.T) {
+	f := newOAuthFixture(t, false)
+	_, challenge := pkcePair()
+
+	tests := []struct {
+		name  string
+		query url.Values
+	}{
+		{"missing challenge", url.Values{"response_type": {"code"}, "client_id": {f.client.ID}, "redirect_uri": {"https://partner.example.com/cb"}}},
+		{"plain challenge", url.Values{"response_type": {"code"}, "client_id": {f.client.ID}, "redirect_uri": {"https://partner.example.com/cb"}, "code_challenge": {challenge}, "code_challenge_method": {"plain"}}},
+		{"unregistered redirect", url.Values{"response_type": {"code"}, "client_id": {f.client.ID}, "redirect_uri": {"https://evil.example.com/cb"}, "code_challenge": {challenge}, "code_challenge_method": {"S256"}}},
+		{"token response type", url.Values{"response_type": {"token"}, "client_id": {f.client.ID}, "redirect_uri": {"https://partner.example.com/cb"}, "code_challenge": {challenge}, "code_challenge_method": {"S256"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			f.oh.GetAuthorization(rec, asUser(httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+tt.query.Encode(), nil), "reader@example.com"))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected the request to be rejected, got %d", rec.Code)
+			}
+		})
+	}
+}
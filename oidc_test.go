@@ -0,0 +1,235 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// mockOIDCProvider is a minimal OpenID Connect provider supporting the authorization code flow with PKCE
+type mockOIDCProvider struct {
+	t       *testing.T
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	subject string
+	email   string
+	// modifyClaims lets a test tamper with the id token before it is signed
+	modifyClaims func(claims jwt.MapClaims)
+	codes        map[string]url.Values
+}
+
+func newMockOIDCProvider(t *testing.T, subject string, email string) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &mockOIDCProvider{t: t, key: key, subject: subject, email: email, codes: make(map[string]url.Values)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kid": "k1",
+			"kty": "RSA",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+			http.Error(w, "pkce required", http.StatusBadRequest)
+			return
+		}
+		code := "code-" + q.Get("state")
+		p.codes[code] = q
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code="+url.QueryEscape(code)+"&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		clientID, _, _ := r.BasicAuth()
+		authorize, exists := p.codes[r.PostForm.Get("code")]
+		if !exists || clientID != "test-client" || r.PostForm.Get("grant_type") != "authorization_code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(challenge[:]) != authorize.Get("code_challenge") {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		delete(p.codes, r.PostForm.Get("code"))
+
+		claims := jwt.MapClaims{
+			"iss":            p.server.URL,
+			"sub":            p.subject,
+			"aud":            []string{"test-client"},
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          authorize.Get("nonce"),
+			"email":          p.email,
+			"email_verified": true,
+		}
+		if p.modifyClaims != nil {
+			p.modifyClaims(claims)
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "k1"
+		idToken, _ := token.SignedString(p.key)
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "idp-access", "token_type": "Bearer", "id_token": idToken})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func newTestOIDCHandler(provider *mockOIDCProvider) (*handlers.OIDCHandler, *data.Repo) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	configs.OIDCIssuer = provider.server.URL
+	configs.OIDCClientID = "test-client"
+	configs.OIDCClientSecret = "test-secret"
+	configs.OIDCRedirectURL = "http://app.test/oidc/callback"
+	repository := data.NewRepo(logger)
+	return handlers.NewOIDCHandler(logger, configs, repository, service.NewAuthService(logger, configs), service.NewOIDCService(logger, configs)), repository
+}
+
+// startLogin runs the login handler and follows the redirect to the provider, returning the callback query
+func startLogin(t *testing.T, oh *handlers.OIDCHandler) url.Values {
+	rec := httptest.NewRecorder()
+	oh.Login(rec, httptest.NewRequest(http.MethodGet, "/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected redirect to provider, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("provider did not redirect back: %d", resp.StatusCode)
+	}
+	return callback.Query()
+}
+
+func callback(oh *handlers.OIDCHandler, query url.Values) (*httptest.ResponseRecorder, handlers.GenericResponse) {
+	rec := httptest.NewRecorder()
+	oh.Callback(rec, httptest.NewRequest(http.MethodGet, "/oidc/callback?"+query.Encode(), nil))
+	response := handlers.GenericResponse{}
+	json.Unmarshal(rec.Body.Bytes(), &response)
+	return rec, response
+}
+
+func TestOIDCLoginProvisionsUser(t *testing.T) {
+	provider := newMockOIDCProvider(t, "idp-user-1", "sso-new@example.com")
+	oh, repository := newTestOIDCHandler(provider)
+
+	rec, response := callback(oh, startLogin(t, oh))
+	if rec.Code != http.StatusOK || !response.Status {
+		t.Fatalf("login failed: %d %s", rec.Code, rec.Body.String())
+	}
+	tokens := response.Data.(map[string]interface{})
+	if tokens["access_token"] == "" || tokens["refresh_token"] == "" || tokens["username"] != "sso-new@example.com" {
+		t.Errorf("unexpected token response %v", tokens)
+	}
+
+	user, err := repository.GetUserByEmail("sso-new@example.com")
+	if err != nil || user.OIDCSubject != "idp-user-1" {
+		t.Fatalf("user not provisioned: %v", err)
+	}
+
+	// a second login maps to the same account
+	if rec, _ := callback(oh, startLogin(t, oh)); rec.Code != http.StatusOK {
+		t.Errorf("second login failed: %s", rec.Body.String())
+	}
+}
+
+func TestOIDCLoginRejectsReplayedAndUnknownState(t *testing.T) {
+	provider := newMockOIDCProvider(t, "idp-user-2", "sso-state@example.com")
+	oh, _ := newTestOIDCHandler(provider)
+
+	query := startLogin(t, oh)
+	forged := url.Values{"code": {query.Get("code")}, "state": {"forged-state"}}
+	if rec, _ := callback(oh, forged); rec.Code != http.StatusBadRequest {
+		t.Errorf("forged state accepted: %d", rec.Code)
+	}
+	if rec, _ := callback(oh, query); rec.Code != http.StatusOK {
+		t.Fatalf("login failed: %s", rec.Body.String())
+	}
+	if rec, _ := callback(oh, query); rec.Code != http.StatusBadRequest {
+		t.Errorf("replayed state accepted: %d", rec.Code)
+	}
+}
+
+func TestOIDCLoginRejectsInvalidIDTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(claims jwt.MapClaims)
+	}{
+		{"wrong nonce", func(c jwt.MapClaims) { c["nonce"] = "other" }},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "other-client" }},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"missing expiration", func(c jwt.MapClaims) { delete(c, "exp") }},
+		{"missing email", func(c jwt.MapClaims) { delete(c, "email") }},
+		{"other authorized party", func(c jwt.MapClaims) { c["azp"] = "other-client" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newMockOIDCProvider(t, "idp-user-3", "sso-invalid@example.com")
+			provider.modifyClaims = tt.modify
+			oh, _ := newTestOIDCHandler(provider)
+
+			if rec, _ := callback(oh, startLogin(t, oh)); rec.Code != http.StatusUnauthorized {
+				t.Errorf("expected login to be rejected, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestOIDCLoginLinksOnlyVerifiedEmails(t *testing.T) {
+	logger := utils.NewLogger()
+	repository := data.NewRepo(logger)
+	repository.Create(&data.User{Email: "local@example.com", Password: "hash", TokenHash: "th"})
+
+	provider := newMockOIDCProvider(t, "idp-user-4", "local@example.com")
+	provider.modifyClaims = func(c jwt.MapClaims) { c["email_verified"] = false }
+	oh, _ := newTestOIDCHandler(provider)
+	if rec, _ := callback(oh, startLogin(t, oh)); rec.Code != http.StatusConflict {
+		t.Errorf("unverified email linked to local account: %d", rec.Code)
+	}
+
+	provider.modifyClaims = nil
+	if rec, _ := callback(oh, startLogin(t, oh)); rec.Code != http.StatusOK {
+		t.Fatalf("verified email not linked: %s", rec.Body.String())
+	}
+	if user, _ := repository.GetUserByEmail("local@example.com"); user.OIDCSubject != "idp-user-4" {
+		t.Errorf("local account not linked")
+	}
+}
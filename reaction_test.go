@@ -0,0 +1,61 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"testing"
+	"time"
+)
+
+func TestLikesAreIdempotentAndSortArticles(t *testing.T) {
+	logger := utils.NewLogger()
+	repository := data.NewRepo(logger)
+	popular, _ := repository.CreateArticle(&data.Article{Title: "popular", Author: "writer@example.com"})
+	time.Sleep(time.Millisecond)
+	repository.CreateArticle(&data.Article{Title: "newer", Author: "writer@example.com"})
+
+	for i := 0; i < 3; i++ {
+		repository.LikeArticle("fan@example.com", popular.ID)
+	}
+	article, _ := repository.LikeArticle("other-fan@example.com", popular.ID)
+	if article.LikeCount != 2 {
+		t.Errorf("expected 2 likes, got %d", article.LikeCount)
+	}
+	if _, err := repository.LikeArticle("fan@example.com", "missing"); err == nil {
+		t.Errorf("liked a missing article")
+	}
+
+	page, err := repository.GetArticles(1, 1, data.SortMostLiked)
+	if err != nil || page[0].ID != popular.ID {
+		t.Errorf("most liked article not first: %v", err)
+	}
+
+	repository.UnlikeArticle("fan@example.com", popular.ID)
+	article, _ = repository.UnlikeArticle("fan@example.com", popular.ID)
+	if article.LikeCount != 1 {
+		t.Errorf("expected 1 like after unlike, got %d", article.LikeCount)
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	logger := utils.NewLogger()
+	repository := data.NewRepo(logger)
+	first, _ := repository.CreateArticle(&data.Article{Title: "read later", Author: "writer@example.com"})
+	second, _ := repository.CreateArticle(&data.Article{Title: "read first", Author: "writer@example.com"})
+
+	repository.BookmarkArticle("saver@example.com", first.ID)
+	time.Sleep(time.Millisecond)
+	repository.BookmarkArticle("saver@example.com", second.ID)
+	repository.BookmarkArticle("saver@example.com", second.ID)
+
+	saved, total, err := repository.GetBookmarks("saver@example.com", 1, 10)
+	if err != nil || total != 2 || saved[0].ID != second.ID || saved[0].BookmarkCount != 1 {
+		t.Fatalf("unexpected bookmarks %+v, %v", saved, err)
+	}
+
+	repository.DeleteArticle(first.ID)
+	repository.RemoveBookmark("saver@example.com", second.ID)
+	if _, total, _ := repository.GetBookmarks("saver@example.com", 1, 10); total != 0 {
+		t.Errorf("expected no bookmarks, got %d", total)
+	}
+}
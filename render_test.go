@@ -0,0 +1,111 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderContent(t *testing.T) {
+	logger := utils.NewLogger()
+	articleService := service.NewArticleService(logger, utils.NewConfigurations(logger))
+
+	tests := []struct {
+		name     string
+		format   string
+		content  string
+		contains []string
+		excludes []string
+	}{
+		{"plain text is escaped", "", "1 < 2\n\nsecond <b>paragraph</b>", []string{"<p>1 &lt; 2</p>", "<p>second &lt;b&gt;paragraph&lt;/b&gt;</p>"}, []string{"<b>"}},
+		{"markdown", data.FormatMarkdown, "# Title\n\n**bold** and [link](https://example.com)\n\n```go\nfmt.Println()\n```", []string{"<h1", "<strong>bold</strong>", `href="https://example.com"`, `class="language-go"`}, nil},
+		{"markdown with raw html", data.FormatMarkdown, "text <script>alert(1)</script>", nil, []string{"<script", "alert(1)</script>"}},
+		{"markdown javascript link", data.FormatMarkdown, "[x](javascript:alert(1))", nil, []string{"javascript:"}},
+		{"html scripts and handlers", data.FormatHTML, `<p onclick="steal()">hi</p><script>alert(1)</script><img src="https://example.com/a.png" onerror="steal()"><a href="javascript:alert(1)">x</a>`, []string{"<p>hi</p>", `src="https://example.com/a.png"`}, []string{"onclick", "<script", "onerror", "javascript:"}},
+		{"html styles and iframes", data.FormatHTML, `<style>body{}</style><iframe src="https://evil.example.com"></iframe><p style="position:fixed">ok</p>`, []string{"ok"}, []string{"<style", "<iframe", "position:fixed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			article := &data.Article{Format: tt.format, Content: tt.content}
+			if err := articleService.RenderContent(article); err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(article.RenderedContent, s) {
+					t.Errorf("expected %q in %q", s, article.RenderedContent)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(article.RenderedContent, s) {
+					t.Errorf("unexpected %q in %q", s, article.RenderedContent)
+				}
+			}
+			if article.Format == data.FormatHTML && article.Content != article.RenderedContent {
+				t.Errorf("raw html content not sanitized")
+			}
+		})
+	}
+}
+
+func TestExcerptWordCountAndReadingTime(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	configs.ExcerptLength = 20
+	configs.WordsPerMinute = 2
+	articleService := service.NewArticleService(logger, configs)
+
+	article := &data.Article{Format: data.FormatMarkdown, Content: "# Heading\n\nSome **bold** words &amp; more text"}
+	if err := articleService.RenderContent(article); err != nil {
+		t.Fatal(err)
+	}
+	if article.WordCount != 7 || article.ReadingTime != 4 {
+		t.Errorf("unexpected word count %d and reading time %d", article.WordCount, article.ReadingTime)
+	}
+	if article.Excerpt != "Heading Some bold…" {
+		t.Errorf("unexpected excerpt %q", article.Excerpt)
+	}
+
+	article = &data.Article{Content: "Short", Excerpt: "  <b>Written</b> by the author "}
+	articleService.RenderContent(article)
+	if article.Excerpt != "Written by the author" || article.ReadingTime != 1 {
+		t.Errorf("unexpected excerpt %q and reading time %d", article.Excerpt, article.ReadingTime)
+	}
+
+	encoded := &bytes.Buffer{}
+	summary := article.Summary()
+	data.ToJSON(&summary, encoded)
+	if strings.Contains(encoded.String(), `"content"`) || !strings.Contains(encoded.String(), `"excerpt":"Written by the author"`) {
+		t.Errorf("unexpected summary %s", encoded.String())
+	}
+}
+
+func TestInvalidReadingSpeedFallsBackToDefault(t *testing.T) {
+	t.Setenv("WORDS_PER_MINUTE", "0")
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	if configs.WordsPerMinute != 200 {
+		t.Fatalf("unexpected reading speed %d", configs.WordsPerMinute)
+	}
+
+	article := &data.Article{Content: "a few words to read"}
+	if err := service.NewArticleService(logger, configs).RenderContent(article); err != nil || article.ReadingTime != 1 {
+		t.Errorf("unexpected reading time %d: %v", article.ReadingTime, err)
+	}
+}
+
+func TestInvalidExcerptLengthFallsBackToDefault(t *testing.T) {
+	t.Setenv("EXCERPT_LENGTH", "-1")
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	if configs.ExcerptLength != 200 {
+		t.Fatalf("unexpected excerpt length %d", configs.ExcerptLength)
+	}
+
+	article := &data.Article{Content: "a few words to read"}
+	if err := service.NewArticleService(logger, configs).RenderContent(article); err != nil || article.Excerpt != "a few words to read" {
+		t.Errorf("unexpected excerpt %q: %v", article.Excerpt, err)
+	}
+}
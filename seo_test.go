@@ -0,0 +1,171 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type parsedURLSet struct {
+	XMLName xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"url"`
+}
+
+type parsedSitemapIndex struct {
+	XMLName  xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"sitemap"`
+}
+
+func TestSitemap(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	seh := handlers.NewSEOHandler(logger, configs, repository)
+
+	published, _ := repository.CreateArticle(&data.Article{Title: "Article for search engines", Author: "writer@sitemap.example.com"})
+	draft, _ := repository.CreateArticle(&data.Article{Title: "Draft hidden from search engines", Status: data.StatusDraft, Author: "writer@sitemap.example.com"})
+	repository.CreateArticle(&data.Article{Title: "Another article for search engines", Author: "writer@sitemap.example.com"})
+
+	get := func(handler http.HandlerFunc, target string, vars map[string]string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, target, nil), vars)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	locations := func(rec *httptest.ResponseRecorder) map[string]string {
+		urlSet := &parsedURLSet{}
+		if err := xml.Unmarshal(rec.Body.Bytes(), urlSet); err != nil {
+			t.Fatal(err)
+		}
+		result := map[string]string{}
+		for _, u := range urlSet.URLs {
+			result[u.Loc] = u.LastMod
+		}
+		return result
+	}
+	publishedURL := configs.PublicURL + "/Article/Slug/" + published.Slug
+	draftURL := configs.PublicURL + "/Article/Slug/" + draft.Slug
+
+	rec := get(seh.Sitemap, "/sitemap.xml", nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/xml") {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	urls := locations(rec)
+	if lastMod, ok := urls[publishedURL]; !ok {
+		t.Fatalf("published article not in the sitemap %v", urls)
+	} else if modified, err := time.Parse(time.RFC3339, lastMod); err != nil || !modified.Equal(published.UpdatedAt.Truncate(time.Second)) {
+		t.Errorf("unexpected lastmod %q", lastMod)
+	}
+	if _, ok := urls[draftURL]; ok {
+		t.Errorf("draft in the sitemap")
+	}
+	if _, ok := urls[configs.PublicURL+"/"]; !ok {
+		t.Errorf("home page not in the sitemap")
+	}
+
+	// above the sitemap size the sitemap becomes an index of sitemaps listing every URL once
+	configs.SitemapSize = 2
+	index := &parsedSitemapIndex{}
+	if err := xml.Unmarshal(get(seh.Sitemap, "/sitemap.xml", nil).Body.Bytes(), index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Sitemaps) != (len(urls)+1)/2 {
+		t.Fatalf("%d sitemaps for %d URLs", len(index.Sitemaps), len(urls))
+	}
+	split := map[string]string{}
+	for i, sitemap := range index.Sitemaps {
+		page := strconv.Itoa(i + 1)
+		if sitemap.Loc != configs.PublicURL+"/sitemap-"+page+".xml" {
+			t.Errorf("unexpected sitemap %q", sitemap.Loc)
+		}
+		for loc, lastMod := range locations(get(seh.SitemapPage, sitemap.Loc, map[string]string{"page": page})) {
+			split[loc] = lastMod
+		}
+	}
+	if len(split) != len(urls) {
+		t.Errorf("split sitemaps list %d URLs instead of %d", len(split), len(urls))
+	}
+	page := strconv.Itoa(len(index.Sitemaps) + 1)
+	if rec := get(seh.SitemapPage, "/sitemap-"+page+".xml", map[string]string{"page": page}); rec.Code != http.StatusNotFound {
+		t.Errorf("sitemap after the last one: %d", rec.Code)
+	}
+}
+
+func TestArticleSEO(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	ah := handlers.NewArticleHandler(logger, configs, data.NewValidation(), repository, service.NewArticleService(logger, configs), service.NewAnalyticsService(logger, configs, repository))
+
+	repository.Create(&data.User{Email: "writer@seo.example.com", Username: "seowriter", Password: "hash"})
+	published, _ := repository.CreateArticle(&data.Article{Title: "Meta tags", Excerpt: "what crawlers read", Tags: []string{"seotest", "crawlers"}, Author: "writer@seo.example.com"})
+	draft, _ := repository.CreateArticle(&data.Article{Title: "Unpublished meta tags", Status: data.StatusDraft, Author: "writer@seo.example.com"})
+
+	get := func(articleID string) *data.ArticleSEO {
+		req := asUser(httptest.NewRequest(http.MethodGet, "/Article/"+articleID, nil), "writer@seo.example.com")
+		rec := httptest.NewRecorder()
+		ah.GetArticle(rec, mux.SetURLVars(req, map[string]string{"articleID": articleID}))
+		response := struct{ Data data.Article }{}
+		json.NewDecoder(rec.Body).Decode(&response)
+		return response.Data.SEO
+	}
+
+	seo := get(published.ID)
+	if seo == nil || seo.CanonicalURL != configs.PublicURL+"/Article/Slug/"+published.Slug {
+		t.Fatalf("unexpected metadata %+v", seo)
+	}
+	tags := map[string][]string{}
+	for _, tag := range append(seo.OpenGraph, seo.TwitterCard...) {
+		tags[tag.Name] = append(tags[tag.Name], tag.Content)
+	}
+	expected := map[string]string{
+		"og:type":        "article",
+		"og:title":       "Meta tags",
+		"og:description": "what crawlers read",
+		"og:url":         seo.CanonicalURL,
+		"article:author": configs.PublicURL + "/Author/seowriter",
+		"twitter:card":   "summary",
+		"twitter:title":  "Meta tags",
+	}
+	for name, content := range expected {
+		if len(tags[name]) != 1 || tags[name][0] != content {
+			t.Errorf("%s is %v instead of %q", name, tags[name], content)
+		}
+	}
+	if len(tags["article:tag"]) != 2 {
+		t.Errorf("unexpected article tags %v", tags["article:tag"])
+	}
+	if _, err := time.Parse(time.RFC3339, tags["article:published_time"][0]); err != nil {
+		t.Errorf("invalid publication time %v", tags["article:published_time"])
+	}
+
+	if seo := get(draft.ID); seo != nil {
+		t.Errorf("draft has public metadata %+v", seo)
+	}
+}
+
+func TestInvalidSitemapSizeFallsBackToDefault(t *testing.T) {
+	for _, size := range []string{"0", "-1", "50001"} {
+		t.Setenv("SITEMAP_SIZE", size)
+		if configs := utils.NewConfigurations(utils.NewLogger()); configs.SitemapSize != 50000 {
+			t.Errorf("unexpected sitemap size %d for %s", configs.SitemapSize, size)
+		}
+	}
+}
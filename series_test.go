@@ -0,0 +1,73 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestSeriesOrderAndNavigation(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	ah := handlers.NewArticleHandler(logger, configs, data.NewValidation(), repository, service.NewArticleService(logger, configs), service.NewAnalyticsService(logger, configs, repository))
+
+	parts := []*data.Article{}
+	for _, title := range []string{"Series part one", "Series part two", "Series part three"} {
+		article, _ := repository.CreateArticle(&data.Article{Title: title, Author: "teacher@example.com"})
+		parts = append(parts, article)
+	}
+	other, _ := repository.CreateArticle(&data.Article{Title: "Series of another author", Author: "other@example.com"})
+
+	tutorial, err := repository.CreateSeries(&data.Series{Title: "Tutorial", Owner: "teacher@example.com", ArticleIDs: []string{parts[0].ID, parts[2].ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.AddArticleToSeries(tutorial.ID, other.ID, 0); err == nil {
+		t.Errorf("added an article of another author")
+	}
+	if _, err := repository.CreateSeries(&data.Series{Title: "Copy", Owner: "teacher@example.com", ArticleIDs: []string{parts[0].ID}}); err == nil {
+		t.Errorf("added an article to a second series")
+	}
+	tutorial, _ = repository.AddArticleToSeries(tutorial.ID, parts[1].ID, 2)
+	if !reflect.DeepEqual(tutorial.ArticleIDs, []string{parts[0].ID, parts[1].ID, parts[2].ID}) {
+		t.Errorf("article not inserted at its position: %v", tutorial.ArticleIDs)
+	}
+	if _, err := repository.ReorderSeries(tutorial.ID, []string{parts[0].ID, parts[0].ID, parts[2].ID}); err == nil {
+		t.Errorf("accepted an order without every article")
+	}
+
+	get := func(articleID string) *data.SeriesNavigation {
+		req := asUser(httptest.NewRequest(http.MethodGet, "/Article/"+articleID, nil), "reader@example.com")
+		rec := httptest.NewRecorder()
+		ah.GetArticle(rec, mux.SetURLVars(req, map[string]string{"articleID": articleID}))
+		response := struct{ Data data.Article }{}
+		json.NewDecoder(rec.Body).Decode(&response)
+		return response.Data.Series
+	}
+	navigation := get(parts[1].ID)
+	if navigation == nil || navigation.Position != 2 || navigation.Total != 3 || navigation.Previous.ID != parts[0].ID || navigation.Next.ID != parts[2].ID {
+		t.Fatalf("unexpected navigation %+v", navigation)
+	}
+
+	repository.ReorderSeries(tutorial.ID, []string{parts[2].ID, parts[1].ID, parts[0].ID})
+	if navigation := get(parts[2].ID); navigation.Position != 1 || navigation.Previous != nil || navigation.Next.Slug != parts[1].Slug {
+		t.Errorf("unexpected navigation after reorder %+v", navigation)
+	}
+
+	repository.DeleteArticle(parts[1].ID)
+	if navigation := get(parts[2].ID); navigation.Total != 2 || navigation.Next.ID != parts[0].ID {
+		t.Errorf("deleted article still in the series %+v", navigation)
+	}
+	if navigation := get(other.ID); navigation != nil {
+		t.Errorf("navigation for an article without series %+v", navigation)
+	}
+}
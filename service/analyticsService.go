@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// viewQueueSize is how many views can wait for the worker before new views are dropped
+const viewQueueSize = 1024
+
+// Analytics interface lists the methods used to count article views
+type Analytics interface {
+	RecordView(articleID string, viewerID string) bool
+	Run(ctx context.Context)
+	Flush()
+}
+
+type articleView struct {
+	articleID string
+	at        time.Time
+}
+
+// AnalyticsService is the implementation of our Analytics. Views are deduplicated when they are recorded
+// and aggregated into the daily buckets of the repository by a background worker
+type AnalyticsService struct {
+	logger  hclog.Logger
+	configs *utils.Configurations
+	repo    data.Repository
+
+	mu        sync.Mutex
+	lastViews map[string]time.Time
+
+	views   chan articleView
+	flushes chan chan struct{}
+}
+
+// NewAnalyticsService returns a new instance of the Analytics service, Run has to be started for the views to be stored
+func NewAnalyticsService(logger hclog.Logger, configs *utils.Configurations, repo data.Repository) *AnalyticsService {
+	return &AnalyticsService{
+		logger:    logger,
+		configs:   configs,
+		repo:      repo,
+		lastViews: make(map[string]time.Time),
+		views:     make(chan articleView, viewQueueSize),
+		flushes:   make(chan chan struct{}),
+	}
+}
+
+// RecordView counts a view of the article unless the viewer already viewed it within the dedup window.
+// It never blocks the request, when the worker falls behind the view is dropped
+func (a *AnalyticsService) RecordView(articleID string, viewerID string) bool {
+	now := time.Now()
+	window := time.Duration(a.configs.ViewDedupWindow) * time.Minute
+	key := viewerID + "|" + articleID
+
+	a.mu.Lock()
+	if last, exists := a.lastViews[key]; exists && now.Sub(last) < window {
+		a.mu.Unlock()
+		return false
+	}
+	a.lastViews[key] = now
+	a.mu.Unlock()
+
+	select {
+	case a.views <- articleView{articleID: articleID, at: now}:
+		return true
+	default:
+		a.logger.Error("view queue is full, dropping view", "article", articleID)
+		return false
+	}
+}
+
+// Run aggregates the recorded views and stores them every flush interval until the context is done
+func (a *AnalyticsService) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(a.configs.ViewFlushInterval) * time.Second)
+	defer ticker.Stop()
+
+	pending := make(map[articleView]int)
+	for {
+		select {
+		case view := <-a.views:
+			view.at = view.at.UTC().Truncate(24 * time.Hour)
+			pending[view]++
+		case <-ticker.C:
+			a.store(pending)
+			a.pruneLastViews()
+		case done := <-a.flushes:
+			a.drain(pending)
+			a.store(pending)
+			close(done)
+		case <-ctx.Done():
+			a.drain(pending)
+			a.store(pending)
+			return
+		}
+	}
+}
+
+// Flush waits until the worker stored all the views recorded so far
+func (a *AnalyticsService) Flush() {
+	done := make(chan struct{})
+	a.flushes <- done
+	<-done
+}
+
+func (a *AnalyticsService) drain(pending map[articleView]int) {
+	for {
+		select {
+		case view := <-a.views:
+			view.at = view.at.UTC().Truncate(24 * time.Hour)
+			pending[view]++
+		default:
+			return
+		}
+	}
+}
+
+func (a *AnalyticsService) store(pending map[articleView]int) {
+	for view, count := range pending {
+		a.repo.AddViews(view.articleID, view.at.Format(data.DayFormat), count)
+		delete(pending, view)
+	}
+}
+
+// pruneLastViews forgets the views that are out of the dedup window
+func (a *AnalyticsService) pruneLastViews() {
+	window := time.Duration(a.configs.ViewDedupWindow) * time.Minute
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key, last := range a.lastViews {
+		if now.Sub(last) >= window {
+			delete(a.lastViews, key)
+		}
+	}
+}
@@ -1,21 +1,118 @@
 package service
 
 import (
+	"MohsenArabi/ArticleManagementSystem/data"
 	"MohsenArabi/ArticleManagementSystem/utils"
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
 )
 
 type Article interface {
+	RenderContent(article *data.Article) error
+	SanitizeHTML(content string) string
+	PlainText(html string) string
 }
 
 // ArticleService is the implementation of our Article
 type ArticleService struct {
-	logger  hclog.Logger
-	configs *utils.Configurations
+	logger   hclog.Logger
+	configs  *utils.Configurations
+	markdown goldmark.Markdown
+	policy   *bluemonday.Policy
+	strict   *bluemonday.Policy
 }
 
 // NewArticleService returns a new instance of the Article service
 func NewArticleService(logger hclog.Logger, configs *utils.Configurations) *ArticleService {
-	return &ArticleService{logger, configs}
+	// user generated content policy: formatting, links, images and tables, but no scripts, styles,
+	// event handler attributes or javascript: urls
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w-]+$`)).OnElements("code")
+
+	return &ArticleService{
+		logger:   logger,
+		configs:  configs,
+		markdown: goldmark.New(goldmark.WithExtensions(extension.GFM)),
+		policy:   policy,
+		strict:   bluemonday.StrictPolicy(),
+	}
+}
+
+// RenderContent sets the sanitized HTML of the content of the article and the fields derived from its text.
+// Articles without a format are plain text, the content of HTML articles is sanitized as well so the raw
+// content is safe to show too
+func (a *ArticleService) RenderContent(article *data.Article) error {
+	if article.Format == "" {
+		article.Format = data.FormatPlain
+	}
+
+	switch article.Format {
+	case data.FormatMarkdown:
+		buf := &bytes.Buffer{}
+		if err := a.markdown.Convert([]byte(article.Content), buf); err != nil {
+			a.logger.Error("unable to render markdown", "error", err)
+			return err
+		}
+		article.RenderedContent = a.SanitizeHTML(buf.String())
+	case data.FormatHTML:
+		article.Content = a.SanitizeHTML(article.Content)
+		article.RenderedContent = article.Content
+	default:
+		article.RenderedContent = plainToHTML(article.Content)
+	}
+
+	text := a.PlainText(article.RenderedContent)
+	article.WordCount = len(strings.Fields(text))
+	article.ReadingTime = 0
+	if article.WordCount > 0 {
+		article.ReadingTime = (article.WordCount + a.configs.WordsPerMinute - 1) / a.configs.WordsPerMinute
+	}
+	if article.Excerpt = strings.TrimSpace(a.PlainText(article.Excerpt)); article.Excerpt == "" {
+		article.Excerpt = excerpt(text, a.configs.ExcerptLength)
+	}
+	return nil
+}
+
+// PlainText strips all the tags of the HTML and collapses its whitespace
+func (a *ArticleService) PlainText(content string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(a.strict.Sanitize(content))), " ")
+}
+
+// excerpt shortens the text to at most length characters, cut at a word boundary
+func excerpt(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	cut := string(runes[:length])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+// SanitizeHTML removes the elements and attributes that are not allowed in articles
+func (a *ArticleService) SanitizeHTML(content string) string {
+	return a.policy.Sanitize(content)
+}
+
+// plainToHTML escapes plain text, blank lines separate paragraphs and single line breaks are kept
+func plainToHTML(content string) string {
+	var b strings.Builder
+	for _, paragraph := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
 }
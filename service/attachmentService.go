@@ -0,0 +1,201 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+
+	"github.com/hashicorp/go-hclog"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Attachments interface lists the methods used to store the files uploaded for articles
+type Attachments interface {
+	Upload(articleID string, userID string, filename string, content io.Reader) (*data.Attachment, error)
+	Open(attachment *data.Attachment, size string) (io.ReadCloser, *data.AttachmentVariant, error)
+	Delete(attachmentID string) error
+	CleanupOrphans()
+	Run(ctx context.Context)
+}
+
+// AttachmentService is the implementation of our Attachments. The content is kept in the blob store and the
+// metadata in the repository, the blobs of deleted articles are deleted by a background worker
+type AttachmentService struct {
+	logger  hclog.Logger
+	configs *utils.Configurations
+	repo    data.Repository
+	store   BlobStore
+	images  Images
+}
+
+// NewAttachmentService returns a new instance of the Attachments service, Run has to be started for the
+// blobs of deleted articles to be deleted
+func NewAttachmentService(logger hclog.Logger, configs *utils.Configurations, repo data.Repository, store BlobStore, images Images) *AttachmentService {
+	return &AttachmentService{
+		logger:  logger,
+		configs: configs,
+		repo:    repo,
+		store:   store,
+		images:  images,
+	}
+}
+
+// Upload stores the content as an attachment of the article. The type is sniffed from the content and has
+// to be one of ALLOWED_UPLOAD_TYPES, the content can not be larger than MAX_UPLOAD_SIZE. The metadata of
+// images is stripped and their resized variants are queued to be generated
+func (as *AttachmentService) Upload(articleID string, userID string, filename string, content io.Reader) (*data.Attachment, error) {
+	body, err := io.ReadAll(io.LimitReader(content, as.configs.MaxUploadSize+1))
+	if err != nil {
+		as.logger.Error("unable to read the upload", "error", err)
+		return nil, errors.New(utils.ErrInvalidUpload)
+	}
+	if int64(len(body)) > as.configs.MaxUploadSize {
+		return nil, errors.New(utils.ErrUploadTooLarge)
+	}
+
+	contentType := sniffContentType(body)
+	if !as.allowed(contentType) {
+		as.logger.Debug("upload type not allowed", "contentType", contentType)
+		return nil, errors.New(utils.ErrUnsupportedUploadType)
+	}
+
+	var width, height int
+	if strings.HasPrefix(contentType, "image/") {
+		body = stripMetadata(contentType, body)
+		width, height = imageDimensions(body)
+	}
+
+	key := "attachments/" + articleID + "/" + uuid.NewV4().String()
+	if err := as.store.Put(key, bytes.NewReader(body), int64(len(body)), contentType); err != nil {
+		as.logger.Error("unable to store the upload", "key", key, "error", err)
+		return nil, errors.New(utils.UploadFailed)
+	}
+
+	attachment, err := as.repo.CreateAttachment(&data.Attachment{
+		ArticleID:   articleID,
+		Filename:    cleanFilename(filename),
+		ContentType: contentType,
+		Size:        int64(len(body)),
+		Width:       width,
+		Height:      height,
+		Key:         key,
+		UploadedBy:  userID,
+	})
+	if err != nil {
+		as.repo.AddOrphanedBlobs(key)
+		return nil, err
+	}
+	if width*height > 0 {
+		as.images.Enqueue(attachment.ID)
+	}
+	return attachment, nil
+}
+
+// Open returns the content of the variant of the size with its description. The original is returned when
+// size is empty, or when the image is smaller than the size or its variants are not generated yet
+func (as *AttachmentService) Open(attachment *data.Attachment, size string) (io.ReadCloser, *data.AttachmentVariant, error) {
+	variant := attachment.Variant(size)
+	if variant == nil {
+		variant = &data.AttachmentVariant{
+			ContentType: attachment.ContentType,
+			Size:        attachment.Size,
+			Width:       attachment.Width,
+			Height:      attachment.Height,
+			Key:         attachment.Key,
+		}
+	}
+	content, err := as.store.Get(variant.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, variant, nil
+}
+
+// Delete deletes the attachment and its content, content that can not be deleted now is left to the worker
+func (as *AttachmentService) Delete(attachmentID string) error {
+	attachment, err := as.repo.DeleteAttachment(attachmentID)
+	if err != nil {
+		return err
+	}
+	for _, key := range attachment.BlobKeys() {
+		if err := as.store.Delete(key); err != nil {
+			as.logger.Error("unable to delete the blob, retrying later", "key", key, "error", err)
+			as.repo.AddOrphanedBlobs(key)
+		}
+	}
+	return nil
+}
+
+// CleanupOrphans deletes the blobs no attachment refers to anymore, the ones that fail are retried next time
+func (as *AttachmentService) CleanupOrphans() {
+	failed := []string{}
+	for _, key := range as.repo.TakeOrphanedBlobs() {
+		if err := as.store.Delete(key); err != nil {
+			as.logger.Error("unable to delete orphaned blob", "key", key, "error", err)
+			failed = append(failed, key)
+		}
+	}
+	if len(failed) > 0 {
+		as.repo.AddOrphanedBlobs(failed...)
+	}
+}
+
+// Run deletes the orphaned blobs every cleanup interval until the context is done
+func (as *AttachmentService) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(as.configs.OrphanCleanupInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			as.CleanupOrphans()
+		case <-ctx.Done():
+			as.CleanupOrphans()
+			return
+		}
+	}
+}
+
+func (as *AttachmentService) allowed(contentType string) bool {
+	for _, allowed := range as.configs.AllowedUploadTypes {
+		if allowed == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffContentType returns the media type of the content without its parameters
+func sniffContentType(content []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(content))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// cleanFilename keeps the base name of the uploaded file without control characters or quotes, so it is
+// safe to send back in the Content-Disposition header
+func cleanFilename(filename string) string {
+	filename = path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	filename = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, filename)
+	if filename == "." || filename == "/" || filename == "" {
+		return "attachment"
+	}
+	return filename
+}
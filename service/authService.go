@@ -2,6 +2,7 @@ package service
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/hashicorp/go-hclog"
+	uuid "github.com/satori/go.uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -23,7 +25,11 @@ type Authentication interface {
 	GenerateRefreshToken(user *data.User) (string, error)
 	GenerateCustomKey(userID string, password string) string
 	ValidateAccessToken(token string) (string, error)
+	ParseAccessToken(token string) (*AccessTokenCustomClaims, error)
+	GenerateScopedAccessToken(userID string, clientID string, scopes []string) (string, error)
 	ValidateRefreshToken(token string) (string, string, error)
+	GenerateAPIKey() (string, error)
+	HashAPIKey(key string) string
 }
 
 // RefreshTokenCustomClaims specifies the claims for refresh token
@@ -34,10 +40,13 @@ type RefreshTokenCustomClaims struct {
 	jwt.StandardClaims
 }
 
-// AccessTokenCustomClaims specifies the claims for access token
+// AccessTokenCustomClaims specifies the claims for access token.
+// ClientID and Scope are only set on tokens issued to third-party apps
 type AccessTokenCustomClaims struct {
-	UserID  string
-	KeyType string
+	UserID   string
+	KeyType  string
+	ClientID string `json:",omitempty"`
+	Scope    string `json:",omitempty"`
 	jwt.StandardClaims
 }
 
@@ -72,9 +81,7 @@ func (auth *AuthService) GenerateRefreshToken(user *data.User) (string, error) {
 		user.Email,
 		cusKey,
 		tokenType,
-		jwt.StandardClaims{
-			Issuer: "bookite.auth.service",
-		},
+		auth.newStandardClaims(user.Email, time.Minute*time.Duration(auth.configs.RefreshExpiration)),
 	}
 
 	signBytes, err := ioutil.ReadFile(auth.configs.RefreshTokenPrivateKeyPath)
@@ -101,14 +108,32 @@ func (auth *AuthService) GenerateAccessToken(user *data.User) (string, error) {
 	tokenType := "access"
 
 	claims := AccessTokenCustomClaims{
-		userID,
-		tokenType,
-		jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Minute * time.Duration(auth.configs.JwtExpiration)).Unix(),
-			Issuer:    "bookite.auth.service",
-		},
+		UserID:         userID,
+		KeyType:        tokenType,
+		StandardClaims: auth.newStandardClaims(userID, time.Minute*time.Duration(auth.configs.JwtExpiration)),
+	}
+
+	return auth.signAccessToken(claims)
+}
+
+// GenerateScopedAccessToken generates an access token that lets the given third-party client
+// act on behalf of the user, limited to the given scopes
+func (auth *AuthService) GenerateScopedAccessToken(userID string, clientID string, scopes []string) (string, error) {
+
+	claims := AccessTokenCustomClaims{
+		UserID:         userID,
+		KeyType:        "access",
+		ClientID:       clientID,
+		Scope:          FormatScope(scopes),
+		StandardClaims: auth.newStandardClaims(userID, time.Minute*time.Duration(auth.configs.JwtExpiration)),
 	}
 
+	return auth.signAccessToken(claims)
+}
+
+// signAccessToken signs the claims with the access token private key
+func (auth *AuthService) signAccessToken(claims AccessTokenCustomClaims) (string, error) {
+
 	signBytes, err := ioutil.ReadFile(auth.configs.AccessTokenPrivateKeyPath)
 	if err != nil {
 		auth.logger.Error("unable to read private key", "error", err)
@@ -126,6 +151,21 @@ func (auth *AuthService) GenerateAccessToken(user *data.User) (string, error) {
 	return token.SignedString(signKey)
 }
 
+// newStandardClaims populates the registered claims shared by access and refresh tokens
+func (auth *AuthService) newStandardClaims(subject string, expiration time.Duration) jwt.StandardClaims {
+
+	now := time.Now()
+	return jwt.StandardClaims{
+		Audience:  auth.configs.JwtAudience,
+		ExpiresAt: now.Add(expiration).Unix(),
+		Id:        uuid.NewV4().String(),
+		IssuedAt:  now.Unix(),
+		Issuer:    auth.configs.JwtIssuer,
+		NotBefore: now.Unix(),
+		Subject:   subject,
+	}
+}
+
 // GenerateCustomKey creates a new key for our jwt payload
 // the key is a hashed combination of the userID and user tokenhash
 func (auth *AuthService) GenerateCustomKey(userID string, tokenHash string) string {
@@ -137,11 +177,62 @@ func (auth *AuthService) GenerateCustomKey(userID string, tokenHash string) stri
 	return sha
 }
 
+// tokenParser returns a parser that only accepts RS256 signed tokens. Time based claims
+// are skipped here and checked by validateStandardClaims so the clock skew can be applied
+func (auth *AuthService) tokenParser() *jwt.Parser {
+	return &jwt.Parser{
+		ValidMethods:         []string{jwt.SigningMethodRS256.Alg()},
+		SkipClaimsValidation: true,
+	}
+}
+
+// validateStandardClaims checks the registered claims of a token against the configured
+// issuer and audience, allowing the configured clock skew on exp, nbf and iat
+func (auth *AuthService) validateStandardClaims(claims *jwt.StandardClaims, userID string) error {
+
+	now := time.Now().Unix()
+	skew := int64(auth.configs.JwtClockSkew)
+
+	if claims.ExpiresAt == 0 || now > claims.ExpiresAt+skew {
+		return errors.New("invalid token: token is expired")
+	}
+	if claims.NotBefore == 0 || now < claims.NotBefore-skew {
+		return errors.New("invalid token: token is not valid yet")
+	}
+	if claims.IssuedAt == 0 || now < claims.IssuedAt-skew {
+		return errors.New("invalid token: token used before issued")
+	}
+	if !claims.VerifyIssuer(auth.configs.JwtIssuer, true) {
+		return errors.New("invalid token: unexpected issuer")
+	}
+	if !claims.VerifyAudience(auth.configs.JwtAudience, true) {
+		return errors.New("invalid token: unexpected audience")
+	}
+	if claims.Id == "" {
+		return errors.New("invalid token: missing token id")
+	}
+	if claims.Subject != userID {
+		return errors.New("invalid token: subject does not match user")
+	}
+	return nil
+}
+
 // ValidateAccessToken parses and validates the given access token
 // returns the userId present in the token payload
 func (auth *AuthService) ValidateAccessToken(tokenString string) (string, error) {
 
-	token, err := jwt.ParseWithClaims(tokenString, &AccessTokenCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
+	claims, err := auth.ParseAccessToken(tokenString)
+	if err != nil {
+		return "", err
+	}
+	return claims.UserID, nil
+}
+
+// ParseAccessToken parses and validates the given access token
+// returns all the claims present in the token payload
+func (auth *AuthService) ParseAccessToken(tokenString string) (*AccessTokenCustomClaims, error) {
+
+	token, err := auth.tokenParser().ParseWithClaims(tokenString, &AccessTokenCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			auth.logger.Error("Unexpected signing method in auth token")
 			return nil, errors.New("Unexpected signing method in auth token")
@@ -163,21 +254,25 @@ func (auth *AuthService) ValidateAccessToken(tokenString string) (string, error)
 
 	if err != nil {
 		auth.logger.Error("unable to parse claims", "error", err)
-		return "", err
+		return nil, err
 	}
 
 	claims, ok := token.Claims.(*AccessTokenCustomClaims)
 	if !ok || !token.Valid || claims.UserID == "" || claims.KeyType != "access" {
-		return "", errors.New("invalid token: authentication failed")
+		return nil, errors.New("invalid token: authentication failed")
 	}
-	return claims.UserID, nil
+	if err := auth.validateStandardClaims(&claims.StandardClaims, claims.UserID); err != nil {
+		auth.logger.Debug("standard claims validation failed", "error", err)
+		return nil, err
+	}
+	return claims, nil
 }
 
 // ValidateRefreshToken parses and validates the given refresh token
 // returns the userId and customkey present in the token payload
 func (auth *AuthService) ValidateRefreshToken(tokenString string) (string, string, error) {
 
-	token, err := jwt.ParseWithClaims(tokenString, &RefreshTokenCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
+	token, err := auth.tokenParser().ParseWithClaims(tokenString, &RefreshTokenCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			auth.logger.Error("Unexpected signing method in auth token")
 			return nil, errors.New("Unexpected signing method in auth token")
@@ -208,5 +303,31 @@ func (auth *AuthService) ValidateRefreshToken(tokenString string) (string, strin
 		auth.logger.Debug("could not extract claims from token")
 		return "", "", errors.New("invalid token: authentication failed")
 	}
+	if err := auth.validateStandardClaims(&claims.StandardClaims, claims.UserID); err != nil {
+		auth.logger.Debug("standard claims validation failed", "error", err)
+		return "", "", err
+	}
 	return claims.UserID, claims.CustomKey, nil
 }
+
+// apiKeyPrefix marks the keys issued by this service so they are easy to recognise in logs and secret scanners
+const apiKeyPrefix = "ams_"
+
+// GenerateAPIKey generates a new random API key
+func (auth *AuthService) GenerateAPIKey() (string, error) {
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		auth.logger.Error("unable to generate api key", "error", err)
+		return "", errors.New("could not generate api key. please try again later")
+	}
+	return apiKeyPrefix + hex.EncodeToString(b), nil
+}
+
+// HashAPIKey returns the hash of the API key which is stored instead of the key itself.
+// API keys are random and long, so a fast hash is enough and keeps per request lookups cheap
+func (auth *AuthService) HashAPIKey(key string) string {
+
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
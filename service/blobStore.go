@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"MohsenArabi/ArticleManagementSystem/utils"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// ErrBlobNotFound is returned by the blob stores when there is no content under a key
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore interface lists the methods used to store the content of uploaded files under a key
+type BlobStore interface {
+	Put(key string, content io.Reader, size int64, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// NewBlobStore returns the blob store selected by BLOB_STORE, local or s3
+func NewBlobStore(logger hclog.Logger, configs *utils.Configurations) (BlobStore, error) {
+	switch configs.BlobStore {
+	case "", "local":
+		return NewLocalBlobStore(logger, configs.BlobDir), nil
+	case "s3":
+		if configs.S3Endpoint == "" || configs.S3Bucket == "" {
+			return nil, errors.New("S3_ENDPOINT and S3_BUCKET are required by the s3 blob store")
+		}
+		return NewS3BlobStore(logger, configs.S3Endpoint, configs.S3Region, configs.S3Bucket, configs.S3AccessKey, configs.S3SecretKey), nil
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", configs.BlobStore)
+	}
+}
+
+// LocalBlobStore is the implementation of BlobStore that keeps the content in files under a directory
+type LocalBlobStore struct {
+	logger hclog.Logger
+	dir    string
+}
+
+// NewLocalBlobStore returns a new instance of LocalBlobStore storing the files under dir
+func NewLocalBlobStore(logger hclog.Logger, dir string) *LocalBlobStore {
+	return &LocalBlobStore{logger: logger, dir: dir}
+}
+
+// path returns the file of a key, keys can not escape the directory of the store
+func (s *LocalBlobStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(clean)), nil
+}
+
+// Put writes the content to the file of the key, replacing it when it exists
+func (s *LocalBlobStore) Put(key string, content io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so a failed upload never leaves partial content under the key
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// Get opens the file of the key
+func (s *LocalBlobStore) Get(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return file, err
+}
+
+// Delete removes the file of the key, deleting a missing key is not an error
+func (s *LocalBlobStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// remove the directories left empty, up to the directory of the store
+	for dir := filepath.Dir(path); strings.HasPrefix(dir, filepath.Clean(s.dir)+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// stripMetadata removes the EXIF, XMP and text metadata of an image without re-encoding it, so the camera,
+// location and the like of the uploader are never published. Content that can not be parsed is returned as is,
+// the color profiles are kept
+func stripMetadata(contentType string, content []byte) []byte {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEGMetadata(content)
+	case "image/png":
+		return stripPNGMetadata(content)
+	case "image/webp":
+		return stripWebPMetadata(content)
+	default:
+		return content
+	}
+}
+
+// stripJPEGMetadata drops the APP1(EXIF and XMP), APP13(IPTC) and comment segments before the image data. The
+// orientation is the only EXIF tag kept, in an EXIF segment of its own, as viewers rotate the image with it
+func stripJPEGMetadata(content []byte) []byte {
+	if len(content) < 4 || content[0] != 0xFF || content[1] != 0xD8 {
+		return content
+	}
+	stripped := bytes.NewBuffer(make([]byte, 0, len(content)))
+	stripped.Write(content[:2])
+	if orientation := jpegOrientation(content); orientation != 1 {
+		stripped.Write(orientationSegment(orientation))
+	}
+	for i := 2; i+4 <= len(content); {
+		if content[i] != 0xFF {
+			return content
+		}
+		marker := content[i+1]
+		// the start of scan is followed by the entropy coded image data, copied as is
+		if marker == 0xDA {
+			stripped.Write(content[i:])
+			return stripped.Bytes()
+		}
+		length := int(binary.BigEndian.Uint16(content[i+2:]))
+		if length < 2 || i+2+length > len(content) {
+			return content
+		}
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			stripped.Write(content[i : i+2+length])
+		}
+		i += 2 + length
+	}
+	return content
+}
+
+// exifHeader starts the APP1 segments holding EXIF metadata
+const exifHeader = "Exif\x00\x00"
+
+// jpegOrientation returns the EXIF orientation(1 to 8) of a JPEG image, 1 when the image is upright or not a JPEG
+func jpegOrientation(content []byte) int {
+	if len(content) < 4 || content[0] != 0xFF || content[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(content) && content[i] == 0xFF && content[i+1] != 0xDA; {
+		length := int(binary.BigEndian.Uint16(content[i+2:]))
+		if length < 2 || i+2+length > len(content) {
+			return 1
+		}
+		if segment := content[i+4 : i+2+length]; content[i+1] == 0xE1 && bytes.HasPrefix(segment, []byte(exifHeader)) {
+			return exifOrientation(segment[len(exifHeader):])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation tag of the first IFD of the TIFF structure of an EXIF segment
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for entry := offset + 2; count > 0 && entry+12 <= len(tiff); entry, count = entry+12, count-1 {
+		// the orientation is a single SHORT stored in the value field of its entry
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orientationSegment is an APP1 segment with an EXIF structure holding only the orientation
+func orientationSegment(orientation int) []byte {
+	segment := []byte{0xFF, 0xE1, 0, 34}
+	segment = append(segment, exifHeader...)
+	// big endian TIFF header with the first IFD right after it
+	segment = append(segment, 'M', 'M', 0, 42, 0, 0, 0, 8)
+	// one entry: orientation, SHORT, count 1, the value, then no next IFD
+	segment = append(segment, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, byte(orientation), 0, 0)
+	return append(segment, 0, 0, 0, 0)
+}
+
+// pngMetadataChunks are the chunks dropped from PNG images
+var pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+// stripPNGMetadata drops the EXIF, text and time chunks, the other chunks are copied with their checksums
+func stripPNGMetadata(content []byte) []byte {
+	const signatureLength = 8
+	if len(content) < signatureLength {
+		return content
+	}
+	stripped := bytes.NewBuffer(make([]byte, 0, len(content)))
+	stripped.Write(content[:signatureLength])
+	for i := signatureLength; i < len(content); {
+		if i+8 > len(content) {
+			return content
+		}
+		end := i + 12 + int(binary.BigEndian.Uint32(content[i:]))
+		if end > len(content) || end < i {
+			return content
+		}
+		if !pngMetadataChunks[string(content[i+4:i+8])] {
+			stripped.Write(content[i:end])
+		}
+		i = end
+	}
+	return stripped.Bytes()
+}
+
+// stripWebPMetadata drops the EXIF and XMP chunks and clears their flags in the extended header
+func stripWebPMetadata(content []byte) []byte {
+	const headerLength = 12
+	if len(content) < headerLength || string(content[:4]) != "RIFF" || string(content[8:12]) != "WEBP" {
+		return content
+	}
+	stripped := bytes.NewBuffer(make([]byte, 0, len(content)))
+	stripped.Write(content[:headerLength])
+	for i := headerLength; i < len(content); {
+		if i+8 > len(content) {
+			return content
+		}
+		size := int(binary.LittleEndian.Uint32(content[i+4:]))
+		// chunks are padded to an even size
+		end := i + 8 + size + size%2
+		if end > len(content) || end < i {
+			return content
+		}
+		switch string(content[i : i+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte{}, content[i:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= 0x04 | 0x08
+			}
+			stripped.Write(chunk)
+		default:
+			stripped.Write(content[i:end])
+		}
+		i = end
+	}
+	result := stripped.Bytes()
+	binary.LittleEndian.PutUint32(result[4:], uint32(len(result)-8))
+	return result
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"sync"
+
+	// registers the gif decoder, gif images get png variants
+	_ "image/gif"
+
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// maxImagePixels is the largest image variants are generated for, larger ones only keep their original
+const maxImagePixels = 50_000_000
+
+// ImageSize is a named box the variants of an image are resized to fit in
+type ImageSize struct {
+	Name      string
+	Dimension int
+}
+
+// ImageSizes are the variants generated for each image, smallest first. Images smaller than a size do not
+// get a variant for it
+var ImageSizes = []ImageSize{
+	{Name: "thumbnail", Dimension: 160},
+	{Name: "small", Dimension: 480},
+	{Name: "medium", Dimension: 960},
+	{Name: "large", Dimension: 1920},
+}
+
+// ValidImageSize reports whether name is one of ImageSizes
+func ValidImageSize(name string) bool {
+	for _, size := range ImageSizes {
+		if size.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Images interface lists the methods used to generate the resized variants of image attachments
+type Images interface {
+	Enqueue(attachmentID string) bool
+	Process(attachmentID string) error
+	Run(ctx context.Context)
+}
+
+// ImageService is the implementation of our Images. The uploads are queued and processed by a bounded pool
+// of IMAGE_WORKERS workers, so large images never hold up the upload request
+type ImageService struct {
+	logger  hclog.Logger
+	configs *utils.Configurations
+	repo    data.Repository
+	store   BlobStore
+	jobs    chan string
+}
+
+// NewImageService returns a new instance of the Images service, Run has to be started for the queued
+// images to be processed
+func NewImageService(logger hclog.Logger, configs *utils.Configurations, repo data.Repository, store BlobStore) *ImageService {
+	return &ImageService{
+		logger:  logger,
+		configs: configs,
+		repo:    repo,
+		store:   store,
+		jobs:    make(chan string, configs.ImageQueueSize),
+	}
+}
+
+// Enqueue queues the attachment to be processed. It never blocks the request, when the workers fall behind
+// the image is only served in its original size
+func (is *ImageService) Enqueue(attachmentID string) bool {
+	select {
+	case is.jobs <- attachmentID:
+		return true
+	default:
+		is.logger.Error("image queue is full, no variants are generated", "attachment", attachmentID)
+		return false
+	}
+}
+
+// Run processes the queued images with the pool of workers until the context is done
+func (is *ImageService) Run(ctx context.Context) {
+	workers := is.configs.ImageWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case attachmentID := <-is.jobs:
+					if err := is.Process(attachmentID); err != nil {
+						is.logger.Error("unable to process the image", "attachment", attachmentID, "error", err)
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Process generates and stores the variants of an image attachment that is larger than the sizes
+func (is *ImageService) Process(attachmentID string) error {
+	attachment, err := is.repo.GetAttachmentByID(attachmentID)
+	if err != nil {
+		return err
+	}
+	if attachment.Width*attachment.Height == 0 || attachment.Width*attachment.Height > maxImagePixels {
+		return nil
+	}
+
+	content, err := is.store.Get(attachment.Key)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(content)
+	content.Close()
+	if err != nil {
+		return err
+	}
+	original, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	// the variants have no EXIF metadata, so they are rotated the way viewers show the original
+	original = orient(original, jpegOrientation(body))
+
+	variants := []data.AttachmentVariant{}
+	for _, size := range ImageSizes {
+		width, height := fitDimensions(attachment.Width, attachment.Height, size.Dimension)
+		if width == attachment.Width && height == attachment.Height {
+			break
+		}
+
+		variant := data.AttachmentVariant{
+			Name:   size.Name,
+			Width:  width,
+			Height: height,
+			Key:    attachment.Key + "-" + size.Name,
+		}
+		var encoded bytes.Buffer
+		resized := resize(original, width, height)
+		if attachment.ContentType == "image/jpeg" {
+			variant.ContentType = "image/jpeg"
+			err = jpeg.Encode(&encoded, resized, &jpeg.Options{Quality: 85})
+		} else {
+			variant.ContentType = "image/png"
+			err = png.Encode(&encoded, resized)
+		}
+		if err == nil {
+			variant.Size = int64(encoded.Len())
+			err = is.store.Put(variant.Key, &encoded, variant.Size, variant.ContentType)
+		}
+		if err != nil {
+			is.repo.AddOrphanedBlobs(variantKeys(variants)...)
+			return err
+		}
+		variants = append(variants, variant)
+	}
+	if len(variants) == 0 {
+		return nil
+	}
+
+	// the attachment can be deleted while its variants are generated
+	if err := is.repo.SetAttachmentVariants(attachmentID, variants); err != nil {
+		is.repo.AddOrphanedBlobs(variantKeys(variants)...)
+		return err
+	}
+	is.logger.Debug("image variants generated", "attachment", attachmentID, "variants", len(variants))
+	return nil
+}
+
+// imageDimensions returns the dimensions of an image as it is shown without decoding it, zero when the type
+// has no decoder
+func imageDimensions(content []byte) (int, int) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return 0, 0
+	}
+	// the orientations from 5 on turn the image by a quarter
+	if jpegOrientation(content) >= 5 {
+		return config.Height, config.Width
+	}
+	return config.Width, config.Height
+}
+
+// fitDimensions scales the dimensions down to fit in a square box keeping the aspect ratio, never up
+func fitDimensions(width int, height int, box int) (int, int) {
+	if width <= box && height <= box {
+		return width, height
+	}
+	if width >= height {
+		return box, atLeastOne(height * box / width)
+	}
+	return atLeastOne(width * box / height), box
+}
+
+// resize scales the image down by averaging the pixels each pixel of the result covers
+func resize(src image.Image, width int, height int) image.Image {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, (y+1)*srcHeight/height
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, (x+1)*srcWidth/width
+			if x1 == x0 {
+				x1++
+			}
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					r += int(row[sx*4])
+					g += int(row[sx*4+1])
+					b += int(row[sx*4+2])
+					a += int(row[sx*4+3])
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
+
+// orient rotates and flips the image by its EXIF orientation so it is upright
+func orient(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if orientation >= 5 {
+		dst = image.NewRGBA(image.Rect(0, 0, height, width))
+	}
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			// the pixel of the source shown at x, y
+			sx, sy := x, y
+			switch orientation {
+			case 2:
+				sx = width - 1 - x
+			case 3:
+				sx, sy = width-1-x, height-1-y
+			case 4:
+				sy = height - 1 - y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, height-1-x
+			case 7:
+				sx, sy = width-1-y, height-1-x
+			case 8:
+				sx, sy = width-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], rgba.Pix[sy*rgba.Stride+sx*4:])
+		}
+	}
+	return dst
+}
+
+func variantKeys(variants []data.AttachmentVariant) []string {
+	keys := []string{}
+	for _, variant := range variants {
+		keys = append(keys, variant.Key)
+	}
+	return keys
+}
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"MohsenArabi/ArticleManagementSystem/utils"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// authorizationCodeLifetime is how long a client has to redeem an authorization code
+const authorizationCodeLifetime = time.Minute
+
+// OAuth interface lists the methods of the authorization server used to grant third-party apps access
+type OAuth interface {
+	IssueAuthorizationCode(grant *OAuthGrant) (string, error)
+	RedeemAuthorizationCode(code string, clientID string, redirectURI string, codeVerifier string) (*OAuthGrant, error)
+}
+
+// OAuthGrant is what a user approved for a client, it is bound to an authorization code until the client redeems it
+type OAuthGrant struct {
+	ClientID      string
+	UserID        string
+	RedirectURI   string
+	Scopes        []string
+	CodeChallenge string
+	expiresAt     time.Time
+}
+
+// OAuthService is the implementation of our OAuth
+type OAuthService struct {
+	logger  hclog.Logger
+	configs *utils.Configurations
+
+	mu    sync.Mutex
+	codes map[string]OAuthGrant
+}
+
+// NewOAuthService returns a new instance of the OAuth service
+func NewOAuthService(logger hclog.Logger, configs *utils.Configurations) *OAuthService {
+	return &OAuthService{
+		logger:  logger,
+		configs: configs,
+		codes:   make(map[string]OAuthGrant),
+	}
+}
+
+// IssueAuthorizationCode returns a new single use code for the grant
+func (o *OAuthService) IssueAuthorizationCode(grant *OAuthGrant) (string, error) {
+
+	code, err := randomURLSafeString(32)
+	if err != nil {
+		o.logger.Error("unable to generate authorization code", "error", err)
+		return "", err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	for c, g := range o.codes {
+		if now.After(g.expiresAt) {
+			delete(o.codes, c)
+		}
+	}
+	stored := *grant
+	stored.expiresAt = now.Add(authorizationCodeLifetime)
+	o.codes[code] = stored
+
+	return code, nil
+}
+
+// RedeemAuthorizationCode exchanges the code for its grant. The code must have been issued to the same
+// client and redirect uri, and the code verifier must match the PKCE challenge sent with the authorization request
+func (o *OAuthService) RedeemAuthorizationCode(code string, clientID string, redirectURI string, codeVerifier string) (*OAuthGrant, error) {
+
+	o.mu.Lock()
+	grant, exists := o.codes[code]
+	delete(o.codes, code)
+	o.mu.Unlock()
+
+	if !exists || time.Now().After(grant.expiresAt) {
+		o.logger.Debug("unknown or expired authorization code")
+		return nil, errors.New(utils.ErrOAuthInvalidGrant)
+	}
+	if grant.ClientID != clientID || grant.RedirectURI != redirectURI {
+		o.logger.Debug("authorization code used by another client or redirect uri", "client", clientID)
+		return nil, errors.New(utils.ErrOAuthInvalidGrant)
+	}
+	if !VerifyCodeChallenge(codeVerifier, grant.CodeChallenge) {
+		o.logger.Debug("pkce code verifier does not match the challenge", "client", clientID)
+		return nil, errors.New(utils.ErrOAuthInvalidGrant)
+	}
+
+	return &grant, nil
+}
+
+// VerifyCodeChallenge checks an S256 PKCE code verifier against its challenge
+func VerifyCodeChallenge(codeVerifier string, codeChallenge string) bool {
+	if codeVerifier == "" || codeChallenge == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(codeVerifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(codeChallenge)) == 1
+}
+
+// ParseScope splits a space delimited scope parameter
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// FormatScope joins scopes into a space delimited scope parameter
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"MohsenArabi/ArticleManagementSystem/utils"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/hashicorp/go-hclog"
+)
+
+// oidcLoginTimeout is how long a user has to complete the login at the identity provider
+const oidcLoginTimeout = 10 * time.Minute
+
+// OIDC interface lists the methods of the OpenID Connect authorization code login flow
+type OIDC interface {
+	Enabled() bool
+	AuthCodeURL() (string, error)
+	Exchange(state string, code string) (*OIDCIdentity, error)
+}
+
+// OIDCIdentity is the identity of a user as asserted by the identity provider
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// oidcProviderMetadata is the part of the provider discovery document used by the service
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// oidcLoginRequest holds the secrets of a login that was started but not completed yet
+type oidcLoginRequest struct {
+	codeVerifier string
+	nonce        string
+	expiresAt    time.Time
+}
+
+// OIDCService is the implementation of our OIDC
+type OIDCService struct {
+	logger  hclog.Logger
+	configs *utils.Configurations
+	client  *http.Client
+
+	mu       sync.Mutex
+	metadata *oidcProviderMetadata
+	keys     map[string]*rsa.PublicKey
+	pending  map[string]oidcLoginRequest
+}
+
+// NewOIDCService returns a new instance of the OIDC service
+func NewOIDCService(logger hclog.Logger, configs *utils.Configurations) *OIDCService {
+	return &OIDCService{
+		logger:  logger,
+		configs: configs,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		keys:    make(map[string]*rsa.PublicKey),
+		pending: make(map[string]oidcLoginRequest),
+	}
+}
+
+// Enabled reports whether an identity provider is configured
+func (o *OIDCService) Enabled() bool {
+	return o.configs.OIDCIssuer != "" && o.configs.OIDCClientID != ""
+}
+
+// AuthCodeURL starts a new login and returns the authorization endpoint url the user has to be redirected to.
+// The state, nonce and PKCE code verifier are kept server side until the callback
+func (o *OIDCService) AuthCodeURL() (string, error) {
+
+	metadata, err := o.discover()
+	if err != nil {
+		return "", err
+	}
+
+	state, err := randomURLSafeString(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomURLSafeString(32)
+	if err != nil {
+		return "", err
+	}
+	codeVerifier, err := randomURLSafeString(64)
+	if err != nil {
+		return "", err
+	}
+
+	o.mu.Lock()
+	o.removeExpiredLogins()
+	o.pending[state] = oidcLoginRequest{codeVerifier: codeVerifier, nonce: nonce, expiresAt: time.Now().Add(oidcLoginTimeout)}
+	o.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.configs.OIDCClientID},
+		"redirect_uri":          {o.configs.OIDCRedirectURL},
+		"scope":                 {o.configs.OIDCScopes},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange completes the login started with the given state. It redeems the code at the token endpoint
+// and returns the identity from the verified id token
+func (o *OIDCService) Exchange(state string, code string) (*OIDCIdentity, error) {
+
+	o.mu.Lock()
+	login, exists := o.pending[state]
+	// a state can only be used once
+	delete(o.pending, state)
+	o.mu.Unlock()
+
+	if !exists || time.Now().After(login.expiresAt) {
+		o.logger.Debug("unknown or expired oidc state")
+		return nil, errors.New(utils.ErrOIDCInvalidState)
+	}
+
+	metadata, err := o.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.configs.OIDCRedirectURL},
+		"client_id":     {o.configs.OIDCClientID},
+		"code_verifier": {login.codeVerifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.configs.OIDCClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.configs.OIDCClientID), url.QueryEscape(o.configs.OIDCClientSecret))
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		o.logger.Error("unable to reach the oidc token endpoint", "error", err)
+		return nil, errors.New(utils.ErrOIDCLoginFailed)
+	}
+	defer resp.Body.Close()
+
+	tokenResponse := struct {
+		IDToken string `json:"id_token"`
+	}{}
+	if resp.StatusCode != http.StatusOK {
+		o.logger.Error("oidc token endpoint rejected the code", "status", resp.StatusCode)
+		return nil, errors.New(utils.ErrOIDCLoginFailed)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil || tokenResponse.IDToken == "" {
+		o.logger.Error("invalid oidc token response", "error", err)
+		return nil, errors.New(utils.ErrOIDCLoginFailed)
+	}
+
+	return o.verifyIDToken(metadata, tokenResponse.IDToken, login.nonce)
+}
+
+// verifyIDToken checks the signature and the claims of the id token issued for the login with the given nonce
+func (o *OIDCService) verifyIDToken(metadata *oidcProviderMetadata, idToken string, nonce string) (*OIDCIdentity, error) {
+
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodRS256.Alg()}}
+	token, err := parser.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return o.publicKey(metadata, kid)
+	})
+	if err != nil || !token.Valid {
+		o.logger.Error("unable to verify id token", "error", err)
+		return nil, errors.New(utils.ErrOIDCLoginFailed)
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if claims["iss"] != metadata.Issuer {
+		o.logger.Error("unexpected id token issuer", "iss", claims["iss"])
+		return nil, errors.New(utils.ErrOIDCLoginFailed)
+	}
+	if !audienceContains(claims["aud"], o.configs.OIDCClientID) {
+		o.logger.Error("id token was not issued for this client", "aud", claims["aud"])
+		return nil, errors.New(utils.ErrOIDCLoginFailed)
+	}
+	if azp, ok := claims["azp"].(string); ok && azp != o.configs.OIDCClientID {
+		o.logger.Error("id token was issued for another party", "azp", azp)
+		return nil, errors.New(utils.ErrOIDCLoginFailed)
+	}
+	if claims["nonce"] != nonce {
+		o.logger.Error("id token nonce does not match the login")
+		return nil, errors.New(utils.ErrOIDCLoginFailed)
+	}
+	if _, ok := claims["exp"]; !ok {
+		o.logger.Error("id token has no expiration")
+		return nil, errors.New(utils.ErrOIDCLoginFailed)
+	}
+
+	identity := &OIDCIdentity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	if identity.Subject == "" || identity.Email == "" {
+		o.logger.Error("id token is missing the subject or email")
+		return nil, errors.New(utils.ErrOIDCLoginFailed)
+	}
+	return identity, nil
+}
+
+// discover fetches and caches the discovery document of the identity provider
+func (o *OIDCService) discover() (*oidcProviderMetadata, error) {
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.metadata != nil {
+		return o.metadata, nil
+	}
+	if !o.Enabled() {
+		return nil, errors.New(utils.ErrOIDCNotConfigured)
+	}
+
+	metadata := &oidcProviderMetadata{}
+	if err := o.getJSON(strings.TrimSuffix(o.configs.OIDCIssuer, "/")+"/.well-known/openid-configuration", metadata); err != nil {
+		o.logger.Error("unable to fetch the oidc discovery document", "error", err)
+		return nil, errors.New(utils.ErrOIDCLoginFailed)
+	}
+	if metadata.Issuer != o.configs.OIDCIssuer {
+		o.logger.Error("oidc discovery document issuer does not match the configuration", "issuer", metadata.Issuer)
+		return nil, errors.New(utils.ErrOIDCLoginFailed)
+	}
+	o.metadata = metadata
+	return metadata, nil
+}
+
+// publicKey returns the provider signing key with the given id, the key set is
+// fetched again when the key is unknown to pick up key rotations
+func (o *OIDCService) publicKey(metadata *oidcProviderMetadata, kid string) (*rsa.PublicKey, error) {
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if key, exists := o.keys[kid]; exists {
+		return key, nil
+	}
+
+	keySet := struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err := o.getJSON(metadata.JwksURI, &keySet); err != nil {
+		o.logger.Error("unable to fetch the oidc key set", "error", err)
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range keySet.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	o.keys = keys
+
+	if key, exists := o.keys[kid]; exists {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// removeExpiredLogins drops the logins that were never completed, the caller must hold the lock
+func (o *OIDCService) removeExpiredLogins() {
+	now := time.Now()
+	for state, login := range o.pending {
+		if now.After(login.expiresAt) {
+			delete(o.pending, state)
+		}
+	}
+}
+
+func (o *OIDCService) getJSON(url string, i interface{}) error {
+	resp, err := o.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(i)
+}
+
+// audienceContains checks the aud claim which can either be a string or a list of strings
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// randomURLSafeString returns a url safe string made of n random bytes
+func randomURLSafeString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
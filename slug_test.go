@@ -0,0 +1,71 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/handlers"
+	"MohsenArabi/ArticleManagementSystem/service"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestSlugsAreTransliteratedAndUnique(t *testing.T) {
+	logger := utils.NewLogger()
+	repository := data.NewRepo(logger)
+
+	tests := []struct {
+		title string
+		slug  string
+	}{
+		{"Crème Brûlée: Don't Panic!", "creme-brulee-dont-panic"},
+		{"Straße über Łódź", "strasse-uber-lodz"},
+		{"Привет, мир", "privet-mir"},
+		{"  --- ", "article"},
+		{"Crème brûlée — don’t panic", "creme-brulee-dont-panic-2"},
+	}
+	for _, tt := range tests {
+		article, _ := repository.CreateArticle(&data.Article{Title: tt.title, Author: "writer@example.com"})
+		if article.Slug != tt.slug {
+			t.Errorf("slug of %q: expected %q, got %q", tt.title, tt.slug, article.Slug)
+		}
+	}
+}
+
+func TestOldSlugsRedirect(t *testing.T) {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+	repository := data.NewRepo(logger)
+	ah := handlers.NewArticleHandler(logger, configs, data.NewValidation(), repository, service.NewArticleService(logger, configs), service.NewAnalyticsService(logger, configs, repository))
+
+	article, _ := repository.CreateArticle(&data.Article{Title: "Slug before rename", Author: "writer@example.com"})
+	oldSlug := article.Slug
+	article.Title = "Slug after rename"
+	renamed, _ := repository.UpdateArticle(article)
+	if renamed.Slug != "slug-after-rename" {
+		t.Fatalf("unexpected slug %q", renamed.Slug)
+	}
+	if other, _ := repository.CreateArticle(&data.Article{Title: "Slug before rename", Author: "other@example.com"}); other.Slug == oldSlug {
+		t.Errorf("previous slug given to another article")
+	}
+
+	get := func(slug string) *httptest.ResponseRecorder {
+		req := asUser(httptest.NewRequest(http.MethodGet, "/Article/Slug/"+slug+"?content=rendered", nil), "reader@example.com")
+		rec := httptest.NewRecorder()
+		ah.GetArticleBySlug(rec, mux.SetURLVars(req, map[string]string{"slug": slug}))
+		return rec
+	}
+	if rec := get(oldSlug); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/Article/Slug/slug-after-rename?content=rendered" {
+		t.Errorf("expected a redirect to the new slug, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := get("slug-after-rename"); rec.Code != http.StatusCreated {
+		t.Errorf("article not fetched by its slug: %d", rec.Code)
+	}
+
+	repository.DeleteArticle(article.ID)
+	if rec := get(oldSlug); rec.Code != http.StatusNotFound {
+		t.Errorf("slug of a deleted article still found: %d", rec.Code)
+	}
+}
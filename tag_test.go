@@ -0,0 +1,93 @@
+package main_test
+
+import (
+	"MohsenArabi/ArticleManagementSystem/data"
+	"MohsenArabi/ArticleManagementSystem/utils"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := map[string]string{
+		"Go":                "go",
+		" go ":              "go",
+		"Machine  Learning": "machine-learning",
+		"C++":               "c++",
+		"Node.js.":          "node.js",
+		"--Über_Cool--":     "über-cool",
+		"!!!":               "",
+	}
+	for tag, expected := range tests {
+		if normalized := data.NormalizeTag(tag); normalized != expected {
+			t.Errorf("%q: expected %q, got %q", tag, expected, normalized)
+		}
+	}
+}
+
+func TestTagCountsRenameMergeAndDelete(t *testing.T) {
+	logger := utils.NewLogger()
+	repository := data.NewRepo(logger)
+	count := func(name string) int {
+		for _, tag := range repository.GetTags() {
+			if tag.Name == name {
+				return tag.Articles
+			}
+		}
+		return 0
+	}
+
+	first, _ := repository.CreateArticle(&data.Article{Title: "tagged first", Tags: []string{"Tagtest Go", "tagtest-go ", "TagtestLang"}})
+	second, _ := repository.CreateArticle(&data.Article{Title: "tagged second", Tags: []string{"tagtest golang"}})
+	if !reflect.DeepEqual(first.Tags, []string{"tagtest-go", "tagtestlang"}) {
+		t.Errorf("unexpected tags %v", first.Tags)
+	}
+	if count("tagtest-go") != 1 || count("tagtest-golang") != 1 {
+		t.Errorf("unexpected counts %v", repository.GetTags())
+	}
+
+	second.Tags = []string{"tagtest-golang", "tagtestlang"}
+	repository.UpdateArticle(second)
+	if count("tagtestlang") != 2 {
+		t.Errorf("expected 2 articles for tagtestlang, got %d", count("tagtestlang"))
+	}
+
+	if suggestions := repository.SearchTags("TagTest-g", 10); len(suggestions) != 2 {
+		t.Errorf("unexpected suggestions %v", suggestions)
+	}
+
+	if _, err := repository.RenameTag("tagtest-golang", "tagtest-go"); err == nil {
+		t.Errorf("renamed to a tag in use")
+	}
+	merged, err := repository.MergeTags([]string{"tagtest-golang"}, "tagtest-go")
+	if err != nil || merged.Articles != 2 || count("tagtest-golang") != 0 {
+		t.Fatalf("unexpected merge %+v, %v", merged, err)
+	}
+	third, _ := repository.CreateArticle(&data.Article{Title: "tagged third", Tags: []string{"TagTest Golang"}})
+	if !reflect.DeepEqual(third.Tags, []string{"tagtest-go"}) {
+		t.Errorf("merged tag not resolved to its target: %v", third.Tags)
+	}
+
+	renamed, err := repository.RenameTag("tagtestlang", "tagtest-language")
+	if err != nil || renamed.Articles != 2 {
+		t.Fatalf("unexpected rename %+v, %v", renamed, err)
+	}
+	article, _ := repository.GetArticleByID(first.ID)
+	if !reflect.DeepEqual(article.Tags, []string{"tagtest-go", "tagtest-language"}) {
+		t.Errorf("tag not renamed on the article: %v", article.Tags)
+	}
+	// feeds and sitemaps see the renamed tag as an update of the article
+	if !article.UpdatedAt.After(first.UpdatedAt) {
+		t.Errorf("article not updated by the rename: %v", article.UpdatedAt)
+	}
+
+	if err := repository.DeleteTag("tagtest-go"); err != nil {
+		t.Fatal(err)
+	}
+	repository.DeleteArticle(second.ID)
+	if count("tagtest-go") != 0 || count("tagtest-language") != 1 {
+		t.Errorf("unexpected counts after delete %v", repository.GetTags())
+	}
+	if err := repository.DeleteTag("tagtest-go"); err == nil {
+		t.Errorf("deleted a missing tag")
+	}
+}
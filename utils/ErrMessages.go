@@ -7,6 +7,89 @@ var ErrUserNotFound = fmt.Sprintf("No user account exists with given email. Plea
 var UserCreationFailed = fmt.Sprintf("Unable to create user.Please try again later")
 
 var ErrArticleNotFound = fmt.Sprintf("Article not found")
-var ErrCantUpdateOthersArticle = fmt.Sprintf("Only author can update the article!")
-var ErrCantDeleteOthersArticle = fmt.Sprintf("Only author can delete the article!")
+var ErrCantUpdateOthersArticle = fmt.Sprintf("Only the owner and editors can update the article!")
+var ErrCantDeleteOthersArticle = fmt.Sprintf("Only the owner can delete the article!")
 var ErrInvalidPageNumber = fmt.Sprintf("The requested page number is invalid.")
+
+var ErrAPIKeyNotFound = fmt.Sprintf("API key not found")
+var ErrInvalidAPIKey = fmt.Sprintf("Authentication failed. Invalid API key")
+var ErrScopeMissing = fmt.Sprintf("The API key or app is not allowed to perform this operation")
+var APIKeyCreationFailed = fmt.Sprintf("Unable to create API key.Please try again later")
+
+var ErrOIDCNotConfigured = fmt.Sprintf("Single sign-on is not configured")
+var ErrOIDCInvalidState = fmt.Sprintf("The login request is invalid or has expired. Please try again")
+var ErrOIDCLoginFailed = fmt.Sprintf("Unable to login with the identity provider. Please try again later")
+var ErrOIDCAccountConflict = fmt.Sprintf("An account with this email is already linked to another identity")
+
+var ErrOAuthClientNotFound = fmt.Sprintf("OAuth client not found")
+var ErrOAuthInvalidRedirectURI = fmt.Sprintf("The redirect_uri is not registered for the client")
+var ErrOAuthInvalidScope = fmt.Sprintf("The requested scope is invalid or exceeds the scope of the client")
+var ErrOAuthPKCERequired = fmt.Sprintf("A code_challenge with the S256 code_challenge_method is required")
+var ErrOAuthUnsupportedResponseType = fmt.Sprintf("Only the code response_type is supported")
+var ErrOAuthInvalidGrant = fmt.Sprintf("The authorization code is invalid, expired or was issued to another client")
+var ErrOAuthConsentNotFound = fmt.Sprintf("No consent was given to the client")
+var ErrDelegatedCredentials = fmt.Sprintf("This operation requires signing in with your own account")
+
+var ErrUsernameTaken = fmt.Sprintf("The username is already taken")
+var ErrAuthorNotFound = fmt.Sprintf("Author not found")
+
+var ErrAdminRequired = fmt.Sprintf("Only admins can perform this operation")
+var ErrUserDisabled = fmt.Sprintf("The user account is disabled")
+var ErrPasswordResetRequired = fmt.Sprintf("A password reset is required. Please set a new password")
+var ErrReassignUserNotFound = fmt.Sprintf("The user to reassign the articles to does not exist")
+var ErrCantManageOwnAccount = fmt.Sprintf("Admins can not disable, delete or change the role of their own account")
+var ErrSingleSignOnUser = fmt.Sprintf("The user signs in with single sign-on and has no password")
+var ErrIncorrectPassword = fmt.Sprintf("Incorrect password")
+
+var ErrRegistrationClosed = fmt.Sprintf("Registration is closed")
+var ErrInvitationRequired = fmt.Sprintf("Registration is invite-only. Please sign up with an invitation code")
+var ErrInvalidInvitation = fmt.Sprintf("The invitation code is invalid, expired or was already used")
+var ErrInvitationNotFound = fmt.Sprintf("Invitation not found")
+
+var AccountExportFailed = fmt.Sprintf("Unable to export the account.Please try again later")
+
+var ErrInvalidAuditFilter = fmt.Sprintf("Invalid audit filter. since and until must be RFC 3339 timestamps")
+
+var ErrCommentNotFound = fmt.Sprintf("Comment not found")
+var ErrCantUpdateOthersComment = fmt.Sprintf("You can not update a comment written by another user")
+var ErrCantDeleteComment = fmt.Sprintf("Only the author of the comment or of the article can delete a comment")
+
+var ErrInvalidSort = fmt.Sprintf("Invalid sort. Articles can be sorted by newest or likes")
+
+var ErrInvalidStatsPeriod = fmt.Sprintf("Invalid period. from and to must be days like 2006-01-02, from before to and at most 366 days apart")
+var ErrCantViewOthersStats = fmt.Sprintf("You can only view the statistics of the articles you contribute to")
+
+var ErrInvalidContentView = fmt.Sprintf("Invalid content parameter. Use raw or rendered")
+var ArticleRenderingFailed = fmt.Sprintf("Unable to render the article content")
+
+var ErrTagNotFound = fmt.Sprintf("Tag not found")
+var ErrTagExists = fmt.Sprintf("The tag already exists. Merge the tags instead")
+var ErrInvalidTag = fmt.Sprintf("Invalid tag. Tags need at least one letter or digit")
+var ErrInvalidLimit = fmt.Sprintf("Invalid limit. The limit must be a positive number")
+
+var ErrCategoryNotFound = fmt.Sprintf("Category not found")
+var ErrParentCategoryNotFound = fmt.Sprintf("Parent category not found")
+var ErrCategoryNameTaken = fmt.Sprintf("A category with this name already exists under the parent category")
+var ErrCategoryCycle = fmt.Sprintf("A category can not be moved under itself or one of its subcategories")
+
+var ErrSeriesNotFound = fmt.Sprintf("Series not found")
+var ErrCantUpdateOthersSeries = fmt.Sprintf("You can not change a series of another user")
+var ErrCantAddOthersArticle = fmt.Sprintf("Only your own articles can be added to your series")
+var ErrArticleInSeries = fmt.Sprintf("The article is already part of a series")
+var ErrArticleNotInSeries = fmt.Sprintf("The article is not part of the series")
+var ErrInvalidSeriesOrder = fmt.Sprintf("Invalid order. The order must list every article of the series once")
+
+var ErrInvalidPermission = fmt.Sprintf("Invalid permission. Contributors are editor or viewer")
+var ErrContributorIsOwner = fmt.Sprintf("The user is the owner of the article")
+var ErrContributorNotFound = fmt.Sprintf("The user is not a contributor of the article")
+var ErrCantManageContributors = fmt.Sprintf("Only the owner of the article can manage its contributors")
+
+var ErrArticleLocked = fmt.Sprintf("The article is being edited by another user")
+var ErrLockNotFound = fmt.Sprintf("Nobody is editing the article")
+
+var ErrAttachmentNotFound = fmt.Sprintf("Attachment not found")
+var ErrUploadTooLarge = fmt.Sprintf("The file is too large")
+var ErrUnsupportedUploadType = fmt.Sprintf("The type of the file is not allowed")
+var ErrInvalidUpload = fmt.Sprintf("Invalid upload. Send the file as the file field of a multipart form")
+var UploadFailed = fmt.Sprintf("Unable to store the file.Please try again later")
+var ErrInvalidImageSize = fmt.Sprintf("Invalid size. The size must be thumbnail, small, medium or large")
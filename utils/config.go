@@ -1,10 +1,19 @@
 package utils
 
 import (
+	"strings"
+
 	"github.com/hashicorp/go-hclog"
 	"github.com/spf13/viper"
 )
 
+// Registration modes
+const (
+	RegistrationOpen       = "open"
+	RegistrationInviteOnly = "invite-only"
+	RegistrationClosed     = "closed"
+)
+
 // Configurations wraps all the config variables required by the auth service
 type Configurations struct {
 	ServerAddress              string
@@ -13,7 +22,43 @@ type Configurations struct {
 	RefreshTokenPrivateKeyPath string
 	RefreshTokenPublicKeyPath  string
 	JwtExpiration              int // in minutes
+	RefreshExpiration          int // in minutes
+	JwtIssuer                  string
+	JwtAudience                string
+	JwtClockSkew               int // in seconds
 	PageSize                   int
+	OIDCIssuer                 string
+	OIDCClientID               string
+	OIDCClientSecret           string
+	OIDCRedirectURL            string
+	OIDCScopes                 string
+	AdminEmails                []string
+	RegistrationMode           string // open, invite-only or closed
+	InvitationExpiration       int    // in hours
+	ViewDedupWindow            int    // in minutes
+	ViewFlushInterval          int    // in seconds
+	ExcerptLength              int    // in characters
+	WordsPerMinute             int
+	EditLockTTL                int    // in seconds
+	BlobStore                  string // local or s3
+	BlobDir                    string
+	S3Endpoint                 string
+	S3Region                   string
+	S3Bucket                   string
+	S3AccessKey                string
+	S3SecretKey                string
+	MaxUploadSize              int64 // in bytes
+	AllowedUploadTypes         []string
+	OrphanCleanupInterval      int // in seconds
+	ImageWorkers               int
+	ImageQueueSize             int
+	PublicURL                  string
+	SiteTitle                  string
+	SiteDescription            string
+	FeedSize                   int
+	SitemapSize                int
+	FrontendEnabled            bool
+	FrontendPageSize           int
 }
 
 // NewConfigurations returns a new Configuration object
@@ -27,16 +72,124 @@ func NewConfigurations(logger hclog.Logger) *Configurations {
 	viper.SetDefault("REFRESH_TOKEN_PRIVATE_KEY_PATH", "./refresh-private.pem")
 	viper.SetDefault("REFRESH_TOKEN_PUBLIC_KEY_PATH", "./refresh-public.pem")
 	viper.SetDefault("JWT_EXPIRATION", 120)
+	viper.SetDefault("REFRESH_EXPIRATION", 10080)
+	viper.SetDefault("JWT_ISSUER", "bookite.auth.service")
+	viper.SetDefault("JWT_AUDIENCE", "article-management-system")
+	viper.SetDefault("JWT_CLOCK_SKEW", 30)
 	viper.SetDefault("PAGE_SIZE", 2)
+	viper.SetDefault("OIDC_ISSUER", "")
+	viper.SetDefault("OIDC_CLIENT_ID", "")
+	viper.SetDefault("OIDC_CLIENT_SECRET", "")
+	viper.SetDefault("OIDC_REDIRECT_URL", "http://localhost:9090/oidc/callback")
+	viper.SetDefault("OIDC_SCOPES", "openid email profile")
+	viper.SetDefault("ADMIN_EMAILS", "")
+	viper.SetDefault("REGISTRATION_MODE", RegistrationOpen)
+	viper.SetDefault("INVITATION_EXPIRATION", 168)
+	viper.SetDefault("VIEW_DEDUP_WINDOW", 30)
+	viper.SetDefault("VIEW_FLUSH_INTERVAL", 10)
+	viper.SetDefault("EXCERPT_LENGTH", 200)
+	viper.SetDefault("WORDS_PER_MINUTE", 200)
+	viper.SetDefault("EDIT_LOCK_TTL", 300)
+	viper.SetDefault("BLOB_STORE", "local")
+	viper.SetDefault("BLOB_DIR", "./uploads")
+	viper.SetDefault("S3_ENDPOINT", "")
+	viper.SetDefault("S3_REGION", "us-east-1")
+	viper.SetDefault("S3_BUCKET", "")
+	viper.SetDefault("S3_ACCESS_KEY", "")
+	viper.SetDefault("S3_SECRET_KEY", "")
+	viper.SetDefault("MAX_UPLOAD_SIZE", 10<<20)
+	viper.SetDefault("ALLOWED_UPLOAD_TYPES", "image/jpeg,image/png,image/gif,image/webp,application/pdf")
+	viper.SetDefault("ORPHAN_CLEANUP_INTERVAL", 60)
+	viper.SetDefault("IMAGE_WORKERS", 2)
+	viper.SetDefault("IMAGE_QUEUE_SIZE", 256)
+	viper.SetDefault("PUBLIC_URL", "http://localhost:9090")
+	viper.SetDefault("SITE_TITLE", "Articles")
+	viper.SetDefault("SITE_DESCRIPTION", "The latest articles")
+	viper.SetDefault("FEED_SIZE", 20)
+	viper.SetDefault("SITEMAP_SIZE", 50000)
+	viper.SetDefault("FRONTEND_ENABLED", false)
+	viper.SetDefault("FRONTEND_PAGE_SIZE", 10)
 
 	configs := &Configurations{
 		ServerAddress:              viper.GetString("SERVER_ADDRESS"),
 		JwtExpiration:              viper.GetInt("JWT_EXPIRATION"),
+		RefreshExpiration:          viper.GetInt("REFRESH_EXPIRATION"),
+		JwtIssuer:                  viper.GetString("JWT_ISSUER"),
+		JwtAudience:                viper.GetString("JWT_AUDIENCE"),
+		JwtClockSkew:               viper.GetInt("JWT_CLOCK_SKEW"),
 		AccessTokenPrivateKeyPath:  viper.GetString("ACCESS_TOKEN_PRIVATE_KEY_PATH"),
 		AccessTokenPublicKeyPath:   viper.GetString("ACCESS_TOKEN_PUBLIC_KEY_PATH"),
 		RefreshTokenPrivateKeyPath: viper.GetString("REFRESH_TOKEN_PRIVATE_KEY_PATH"),
 		RefreshTokenPublicKeyPath:  viper.GetString("REFRESH_TOKEN_PUBLIC_KEY_PATH"),
 		PageSize:                   viper.GetInt("PAGE_SIZE"),
+		OIDCIssuer:                 viper.GetString("OIDC_ISSUER"),
+		OIDCClientID:               viper.GetString("OIDC_CLIENT_ID"),
+		OIDCClientSecret:           viper.GetString("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:            viper.GetString("OIDC_REDIRECT_URL"),
+		OIDCScopes:                 viper.GetString("OIDC_SCOPES"),
+		RegistrationMode:           viper.GetString("REGISTRATION_MODE"),
+		InvitationExpiration:       viper.GetInt("INVITATION_EXPIRATION"),
+		ViewDedupWindow:            viper.GetInt("VIEW_DEDUP_WINDOW"),
+		ViewFlushInterval:          viper.GetInt("VIEW_FLUSH_INTERVAL"),
+		ExcerptLength:              viper.GetInt("EXCERPT_LENGTH"),
+		WordsPerMinute:             viper.GetInt("WORDS_PER_MINUTE"),
+		EditLockTTL:                viper.GetInt("EDIT_LOCK_TTL"),
+		BlobStore:                  viper.GetString("BLOB_STORE"),
+		BlobDir:                    viper.GetString("BLOB_DIR"),
+		S3Endpoint:                 viper.GetString("S3_ENDPOINT"),
+		S3Region:                   viper.GetString("S3_REGION"),
+		S3Bucket:                   viper.GetString("S3_BUCKET"),
+		S3AccessKey:                viper.GetString("S3_ACCESS_KEY"),
+		S3SecretKey:                viper.GetString("S3_SECRET_KEY"),
+		MaxUploadSize:              viper.GetInt64("MAX_UPLOAD_SIZE"),
+		OrphanCleanupInterval:      viper.GetInt("ORPHAN_CLEANUP_INTERVAL"),
+		ImageWorkers:               viper.GetInt("IMAGE_WORKERS"),
+		ImageQueueSize:             viper.GetInt("IMAGE_QUEUE_SIZE"),
+		PublicURL:                  strings.TrimSuffix(viper.GetString("PUBLIC_URL"), "/"),
+		SiteTitle:                  viper.GetString("SITE_TITLE"),
+		SiteDescription:            viper.GetString("SITE_DESCRIPTION"),
+		FeedSize:                   viper.GetInt("FEED_SIZE"),
+		SitemapSize:                viper.GetInt("SITEMAP_SIZE"),
+		FrontendEnabled:            viper.GetBool("FRONTEND_ENABLED"),
+		FrontendPageSize:           viper.GetInt("FRONTEND_PAGE_SIZE"),
+	}
+
+	if configs.RegistrationMode != RegistrationOpen && configs.RegistrationMode != RegistrationInviteOnly && configs.RegistrationMode != RegistrationClosed {
+		logger.Error("unknown registration mode, registration is closed", "mode", configs.RegistrationMode)
+		configs.RegistrationMode = RegistrationClosed
+	}
+
+	// the tickers of the background workers panic without a positive interval
+	configs.ViewFlushInterval = atLeast(logger, "VIEW_FLUSH_INTERVAL", configs.ViewFlushInterval, 1, 10)
+	configs.OrphanCleanupInterval = atLeast(logger, "ORPHAN_CLEANUP_INTERVAL", configs.OrphanCleanupInterval, 1, 60)
+	// the reading time is divided by the reading speed
+	configs.WordsPerMinute = atLeast(logger, "WORDS_PER_MINUTE", configs.WordsPerMinute, 1, 200)
+	// the excerpt is cut from the text at this many characters
+	configs.ExcerptLength = atLeast(logger, "EXCERPT_LENGTH", configs.ExcerptLength, 1, 200)
+	// every upload is too large without a positive size
+	configs.MaxUploadSize = atLeastInt64(logger, "MAX_UPLOAD_SIZE", configs.MaxUploadSize, 1, 10<<20)
+	// a negative queue size panics, without a queue the images uploaded while the workers are busy get no variants
+	configs.ImageQueueSize = atLeast(logger, "IMAGE_QUEUE_SIZE", configs.ImageQueueSize, 1, 256)
+	// a negative page size panics and an empty one lists every published article
+	configs.FeedSize = atLeast(logger, "FEED_SIZE", configs.FeedSize, 1, 20)
+	// larger sites are split into several sitemaps, each lists at most the 50000 URLs search engines read
+	configs.SitemapSize = atLeast(logger, "SITEMAP_SIZE", configs.SitemapSize, 1, 50000)
+	configs.SitemapSize = atMost(logger, "SITEMAP_SIZE", configs.SitemapSize, 50000, 50000)
+	// like the feed, the pages of the blog would list every article or panic without a positive size
+	configs.FrontendPageSize = atLeast(logger, "FRONTEND_PAGE_SIZE", configs.FrontendPageSize, 1, 10)
+
+	// comma separated list of the emails that get the admin role when they sign up
+	for _, email := range strings.Split(viper.GetString("ADMIN_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			configs.AdminEmails = append(configs.AdminEmails, email)
+		}
+	}
+
+	// comma separated list of the sniffed content types that can be uploaded
+	for _, contentType := range strings.Split(viper.GetString("ALLOWED_UPLOAD_TYPES"), ",") {
+		if contentType = strings.TrimSpace(contentType); contentType != "" {
+			configs.AllowedUploadTypes = append(configs.AllowedUploadTypes, contentType)
+		}
 	}
 
 	port := viper.GetString("PORT")
@@ -50,3 +203,40 @@ func NewConfigurations(logger hclog.Logger) *Configurations {
 
 	return configs
 }
+
+// atLeast returns the value of the setting, or its default when the value is below minimum
+func atLeast(logger hclog.Logger, name string, value int, minimum int, fallback int) int {
+	if value < minimum {
+		logger.Error("invalid setting, using the default", "setting", name, "value", value, "default", fallback)
+		return fallback
+	}
+	return value
+}
+
+// atLeastInt64 is atLeast for the int64 settings
+func atLeastInt64(logger hclog.Logger, name string, value int64, minimum int64, fallback int64) int64 {
+	if value < minimum {
+		logger.Error("invalid setting, using the default", "setting", name, "value", value, "default", fallback)
+		return fallback
+	}
+	return value
+}
+
+// atMost returns the value of the setting, or its default when the value is above maximum
+func atMost(logger hclog.Logger, name string, value int, maximum int, fallback int) int {
+	if value > maximum {
+		logger.Error("invalid setting, using the default", "setting", name, "value", value, "default", fallback)
+		return fallback
+	}
+	return value
+}
+
+// IsAdminEmail reports whether the user with the given email gets the admin role when signing up
+func (c *Configurations) IsAdminEmail(email string) bool {
+	for _, adminEmail := range c.AdminEmails {
+		if strings.EqualFold(adminEmail, email) {
+			return true
+		}
+	}
+	return false
+}